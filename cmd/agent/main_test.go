@@ -12,7 +12,11 @@ import (
 
 	"github.com/levinOo/go-metrics-project/internal/agent"
 	"github.com/levinOo/go-metrics-project/internal/agent/store"
+	"github.com/levinOo/go-metrics-project/internal/config"
+	"github.com/levinOo/go-metrics-project/internal/handler"
+	"github.com/levinOo/go-metrics-project/internal/logger"
 	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/repository"
 )
 
 func TestCompressData(t *testing.T) {
@@ -101,3 +105,29 @@ func TestSendAllMetricsBatch(t *testing.T) {
 		}
 	}
 }
+
+func TestSendAllMetricsBatchFormats(t *testing.T) {
+	for _, format := range []string{agent.FormatJSON, agent.FormatProtobuf, agent.FormatMsgpack} {
+		t.Run(format, func(t *testing.T) {
+			storage := repository.NewMemStorage()
+			ts := httptest.NewServer(handler.NewRouter(storage, logger.NewLogger(), config.Config{Key: "secret"}, nil))
+			defer ts.Close()
+
+			metrics := store.Metrics{
+				Alloc:     store.Gauge(42.42),
+				PollCount: store.Counter(7),
+			}
+
+			if err := agent.SendAllMetricsBatchFormat(&http.Client{}, ts.URL, metrics, "secret", 2, format); err != nil {
+				t.Fatalf("SendAllMetricsBatchFormat failed: %v", err)
+			}
+
+			if val, _ := storage.GetCounter("PollCount"); val != 7 {
+				t.Errorf("expected PollCount 7, got %d", val)
+			}
+			if val, _ := storage.GetGauge("Alloc"); val != 42.42 {
+				t.Errorf("expected Alloc 42.42, got %v", val)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/levinOo/go-metrics-project/internal/config/db"
+)
+
+const migrateUsage = `usage: server migrate [-d DSN] <command>
+
+commands:
+  up             применить все новые миграции
+  down [N]       откатить N последних миграций (по умолчанию 1)
+  version        показать текущую версию схемы
+  force VERSION  установить версию схемы без выполнения миграций
+
+DSN берется из флага -d или переменной окружения DATABASE_DSN.`
+
+// migrateCommand — разобранная подкоманда "migrate".
+type migrateCommand struct {
+	dsn  string
+	name string
+
+	// n содержит количество откатываемых миграций для down и версию для force.
+	n int
+}
+
+// parseMigrateArgs разбирает аргументы подкоманды "migrate". Переменная окружения
+// DATABASE_DSN (envDSN) имеет приоритет над флагом -d, как и в конфигурации сервера.
+func parseMigrateArgs(args []string, envDSN string) (migrateCommand, error) {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), migrateUsage) }
+	dsnFlag := fs.String("d", "", "Database address")
+
+	if err := fs.Parse(args); err != nil {
+		return migrateCommand{}, err
+	}
+
+	cmd := migrateCommand{dsn: *dsnFlag}
+	if envDSN != "" {
+		cmd.dsn = envDSN
+	}
+	if cmd.dsn == "" {
+		return migrateCommand{}, errors.New("database DSN is not set: use -d or DATABASE_DSN")
+	}
+
+	cmdArgs := fs.Args()
+	if len(cmdArgs) == 0 {
+		fs.Usage()
+		return migrateCommand{}, errors.New("migrate command is required")
+	}
+	cmd.name = cmdArgs[0]
+
+	switch cmd.name {
+	case "up", "version":
+
+	case "down":
+		cmd.n = 1
+		if len(cmdArgs) > 1 {
+			n, err := strconv.Atoi(cmdArgs[1])
+			if err != nil {
+				return migrateCommand{}, fmt.Errorf("invalid number of steps %q: %w", cmdArgs[1], err)
+			}
+			cmd.n = n
+		}
+
+	case "force":
+		if len(cmdArgs) < 2 {
+			return migrateCommand{}, errors.New("force requires a version")
+		}
+		version, err := strconv.Atoi(cmdArgs[1])
+		if err != nil {
+			return migrateCommand{}, fmt.Errorf("invalid version %q: %w", cmdArgs[1], err)
+		}
+		cmd.n = version
+
+	default:
+		fs.Usage()
+		return migrateCommand{}, fmt.Errorf("unknown migrate command %q", cmd.name)
+	}
+
+	return cmd, nil
+}
+
+// runMigrate обрабатывает подкоманду "migrate" для операторов.
+func runMigrate(args []string) error {
+	cmd, err := parseMigrateArgs(args, os.Getenv("DATABASE_DSN"))
+	if err != nil {
+		return err
+	}
+
+	switch cmd.name {
+	case "up":
+		if err := db.MigrateUp(cmd.dsn); err != nil {
+			return err
+		}
+		fmt.Println("Migrations applied")
+
+	case "down":
+		if err := db.MigrateDown(cmd.dsn, cmd.n); err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migration(s)\n", cmd.n)
+
+	case "version":
+		version, dirty, err := db.MigrateVersion(cmd.dsn)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Version: %d, dirty: %t\n", version, dirty)
+
+	case "force":
+		if err := db.MigrateForce(cmd.dsn, cmd.n); err != nil {
+			return err
+		}
+		fmt.Printf("Version forced to %d\n", cmd.n)
+	}
+
+	return nil
+}
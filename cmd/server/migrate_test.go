@@ -0,0 +1,94 @@
+package main
+
+import "testing"
+
+func TestParseMigrateArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		envDSN  string
+		want    migrateCommand
+		wantErr bool
+	}{
+		{
+			name: "up with flag DSN",
+			args: []string{"-d", "postgres://flag", "up"},
+			want: migrateCommand{dsn: "postgres://flag", name: "up"},
+		},
+		{
+			name:   "env DSN overrides flag",
+			args:   []string{"-d", "postgres://flag", "version"},
+			envDSN: "postgres://env",
+			want:   migrateCommand{dsn: "postgres://env", name: "version"},
+		},
+		{
+			name:   "env DSN without flag",
+			args:   []string{"up"},
+			envDSN: "postgres://env",
+			want:   migrateCommand{dsn: "postgres://env", name: "up"},
+		},
+		{
+			name: "down defaults to one step",
+			args: []string{"-d", "postgres://flag", "down"},
+			want: migrateCommand{dsn: "postgres://flag", name: "down", n: 1},
+		},
+		{
+			name: "down with steps",
+			args: []string{"-d", "postgres://flag", "down", "3"},
+			want: migrateCommand{dsn: "postgres://flag", name: "down", n: 3},
+		},
+		{
+			name:    "down with invalid steps",
+			args:    []string{"-d", "postgres://flag", "down", "many"},
+			wantErr: true,
+		},
+		{
+			name: "force with version",
+			args: []string{"-d", "postgres://flag", "force", "2"},
+			want: migrateCommand{dsn: "postgres://flag", name: "force", n: 2},
+		},
+		{
+			name:    "force without version",
+			args:    []string{"-d", "postgres://flag", "force"},
+			wantErr: true,
+		},
+		{
+			name:    "force with invalid version",
+			args:    []string{"-d", "postgres://flag", "force", "v2"},
+			wantErr: true,
+		},
+		{
+			name:    "missing DSN",
+			args:    []string{"up"},
+			wantErr: true,
+		},
+		{
+			name:    "missing command",
+			args:    []string{"-d", "postgres://flag"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown command",
+			args:    []string{"-d", "postgres://flag", "redo"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMigrateArgs(tt.args, tt.envDSN)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseMigrateArgs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,17 +7,23 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"os"
+	"os/signal"
 	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/hashicorp/go-retryablehttp"
+	"github.com/levinOo/go-metrics-project/internal/agent/collector"
 	"github.com/levinOo/go-metrics-project/internal/agent/store"
 	"github.com/levinOo/go-metrics-project/internal/models"
 )
@@ -28,14 +34,164 @@ type Config struct {
 	PollInterval int    `env:"POLL_INTERVAL"`
 	ReqInterval  int    `env:"REPORT_INTERVAL"`
 	RateLimit    int    `env:"RATE_LIMIT"`
+
+	// Format задает формат тела пакетного запроса: json, protobuf или msgpack.
+	Format string `env:"FORMAT"`
+
+	// Collectors перечисляет включенные коллекторы через запятую. Для коллектора
+	// можно задать собственный интервал опроса: "runtime,poll,system:30s". Без интервала
+	// используется PollInterval.
+	Collectors string `env:"COLLECTORS"`
+
+	// Шаблоны path.Match через запятую для отбора точек монтирования коллектора
+	// filesystem и интерфейсов коллектора net.
+	MountsInclude     string `env:"MOUNTS_INCLUDE"`
+	MountsExclude     string `env:"MOUNTS_EXCLUDE"`
+	InterfacesInclude string `env:"INTERFACES_INCLUDE"`
+	InterfacesExclude string `env:"INTERFACES_EXCLUDE"`
+
+	// CgroupRoot — каталог cgroup v2 для коллектора cgroup.
+	CgroupRoot string `env:"CGROUP_ROOT"`
+
+	// ExecCommands — команды коллектора exec, разделенные ";".
+	ExecCommands string `env:"EXEC_COMMANDS"`
+	// ExecTimeout — предельное время выполнения команды в секундах.
+	ExecTimeout int `env:"EXEC_TIMEOUT"`
+
+	// LogTailConfig — JSON-файл с журналами и правилами коллектора logtail,
+	// LogTailState — файл, в котором сохраняются позиции чтения журналов.
+	LogTailConfig string `env:"LOGTAIL_CONFIG"`
+	LogTailState  string `env:"LOGTAIL_STATE"`
+
+	// ProbeTargets — цели коллектора probe через запятую, например
+	// "api=https://example.com/health,tcp://db:5432".
+	ProbeTargets string `env:"PROBE_TARGETS"`
+	// ProbeTimeout — предельное время одной проверки в секундах.
+	ProbeTimeout int `env:"PROBE_TIMEOUT"`
+
+	// PromTargets — цели коллектора prometheus через запятую вида "[имя=]URL",
+	// PromMode — способ указать цель в имени метрики: label или prefix,
+	// PromTimeout — предельное время опроса цели в секундах.
+	PromTargets string `env:"PROM_TARGETS"`
+	PromMode    string `env:"PROM_MODE"`
+	PromTimeout int    `env:"PROM_TIMEOUT"`
+
+	// StatsDAddr — UDP-адрес приемника StatsD коллектора statsd.
+	StatsDAddr string `env:"STATSD_SINK_ADDRESS"`
+}
+
+// DefaultCollectors — коллекторы, включенные по умолчанию.
+const DefaultCollectors = "runtime,poll,system"
+
+var (
+	factoriesMu sync.Mutex
+	factories   = map[string]collector.Factory{
+		"runtime": func() (collector.Collector, error) { return collector.NewRuntime(), nil },
+		"poll":    func() (collector.Collector, error) { return collector.NewPoll(), nil },
+		"system":  func() (collector.Collector, error) { return collector.NewSystem(), nil },
+		"diskio":  func() (collector.Collector, error) { return collector.NewDiskIO(), nil },
+		"load":    func() (collector.Collector, error) { return collector.NewLoad(), nil },
+		"fd":      func() (collector.Collector, error) { return collector.NewFileDescriptors(), nil },
+	}
+)
+
+// RegisterCollector делает коллектор доступным для включения по имени name через
+// Config.Collectors. Вызывается до StartAgent.
+func RegisterCollector(name string, factory collector.Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[name] = factory
+}
+
+// newRegistry создает реестр с коллекторами, включенными в конфигурации.
+func newRegistry(cfg Config) (*collector.Registry, error) {
+	specs, err := collector.ParseSpecs(cfg.Collectors, time.Second*time.Duration(cfg.PollInterval))
+	if err != nil {
+		return nil, err
+	}
+
+	mounts, err := collector.ParseFilter(cfg.MountsInclude, cfg.MountsExclude)
+	if err != nil {
+		return nil, fmt.Errorf("mounts filter: %w", err)
+	}
+	interfaces, err := collector.ParseFilter(cfg.InterfacesInclude, cfg.InterfacesExclude)
+	if err != nil {
+		return nil, fmt.Errorf("interfaces filter: %w", err)
+	}
+
+	factoriesMu.Lock()
+	available := make(map[string]collector.Factory, len(factories))
+	for name, factory := range factories {
+		available[name] = factory
+	}
+	factoriesMu.Unlock()
+
+	// Коллекторы, зависящие от конфигурации агента.
+	available["filesystem"] = func() (collector.Collector, error) { return collector.NewFilesystem(mounts), nil }
+	available["net"] = func() (collector.Collector, error) { return collector.NewNetwork(interfaces), nil }
+	available["cgroup"] = func() (collector.Collector, error) { return collector.NewCgroup(cfg.CgroupRoot), nil }
+	available["exec"] = func() (collector.Collector, error) {
+		return collector.NewExec(collector.ParseCommands(cfg.ExecCommands), time.Second*time.Duration(cfg.ExecTimeout))
+	}
+	available["logtail"] = func() (collector.Collector, error) {
+		if cfg.LogTailConfig == "" {
+			return nil, errors.New("log tail config is not set")
+		}
+		files, err := collector.LoadLogTailConfig(cfg.LogTailConfig)
+		if err != nil {
+			return nil, err
+		}
+		return collector.NewLogTail(files, cfg.LogTailState)
+	}
+	available["probe"] = func() (collector.Collector, error) {
+		targets, err := collector.ParseProbeTargets(cfg.ProbeTargets)
+		if err != nil {
+			return nil, err
+		}
+		return collector.NewProbe(targets, time.Second*time.Duration(cfg.ProbeTimeout))
+	}
+	available["prometheus"] = func() (collector.Collector, error) {
+		targets, err := collector.ParseScrapeTargets(cfg.PromTargets)
+		if err != nil {
+			return nil, err
+		}
+		return collector.NewPrometheus(targets, cfg.PromMode, time.Second*time.Duration(cfg.PromTimeout))
+	}
+	available["statsd"] = func() (collector.Collector, error) { return collector.NewStatsD(cfg.StatsDAddr) }
+
+	registry := collector.NewRegistry()
+	if err := registry.Configure(specs, available); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// Форматы тела пакетного запроса агента.
+const (
+	FormatJSON     = "json"
+	FormatProtobuf = "protobuf"
+	FormatMsgpack  = "msgpack"
+)
+
+// contentTypes сопоставляет формат агента типу содержимого запроса.
+var contentTypes = map[string]string{
+	FormatJSON:     "application/json",
+	FormatProtobuf: "application/x-protobuf",
+	FormatMsgpack:  "application/msgpack",
 }
 
 func SendAllMetricsBatch(client *http.Client, endpoint string, m store.Metrics, key string, rateLimit int) error {
+	return SendAllMetricsBatchFormat(client, endpoint, m, key, rateLimit, FormatJSON)
+}
+
+// SendAllMetricsBatchFormat отправляет все метрики одним запросом /updates в формате
+// format: FormatJSON, FormatProtobuf или FormatMsgpack.
+func SendAllMetricsBatchFormat(client *http.Client, endpoint string, m store.Metrics, key string, rateLimit int, format string) error {
 	metrics := m.ValuesAllTyped()
 	var metricsList []models.Metrics
 
 	inputCh := make(chan models.Metrics)
-	errCh := make(chan error)
+	errCh := make(chan error, 1)
 	go func() {
 		defer close(inputCh)
 		for name, metric := range metrics {
@@ -93,16 +249,30 @@ func SendAllMetricsBatch(client *http.Client, endpoint string, m store.Metrics,
 		}
 	}
 
-	return sendMetricsBatch(metricsList, endpoint, key)
+	return sendMetricsBatch(metricsList, endpoint, key, format)
 }
 
-func sendMetricsBatch(metrics []models.Metrics, endpoint string, key string) error {
+// encodeMetrics кодирует пакет метрик в формате format.
+func encodeMetrics(metrics []models.Metrics, format string) ([]byte, error) {
+	switch format {
+	case FormatProtobuf:
+		return models.ListMetrics{List: metrics}.MarshalProto()
+	case FormatMsgpack:
+		return models.ListMetrics{List: metrics}.MarshalMsgpack()
+	case FormatJSON:
+		return json.Marshal(metrics)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func sendMetricsBatch(metrics []models.Metrics, endpoint string, key string, format string) error {
 	url, err := url.JoinPath(endpoint, "updates")
 	if err != nil {
 		return fmt.Errorf("failed to join URL path: %w", err)
 	}
 
-	data, err := json.Marshal(metrics)
+	data, err := encodeMetrics(metrics, format)
 	if err != nil {
 		return fmt.Errorf("failed to marshal metrics: %w", err)
 	}
@@ -130,8 +300,8 @@ func sendMetricsBatch(metrics []models.Metrics, endpoint string, key string) err
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", contentTypes[format])
+	req.Header.Set("Accept", contentTypes[format])
 	req.Header.Set("Content-Encoding", "gzip")
 	req.Header.Set("Accept-Encoding", "gzip")
 
@@ -146,12 +316,37 @@ func sendMetricsBatch(metrics []models.Metrics, endpoint string, key string) err
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("server returned status %d", resp.StatusCode)
+		return &statusError{code: resp.StatusCode}
 	}
 
 	return nil
 }
 
+// statusError — ответ сервера с кодом, отличным от 200 OK.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("server returned status %d", e.code)
+}
+
+// isRejected сообщает, что сервер отклонил пакет ответом 4xx. Ошибки сети и ответы
+// 5xx временные: метрики такого пакета возвращаются в реестр и отправляются повторно.
+func isRejected(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.code >= 400 && statusErr.code < 500
+}
+
+// metricIDs перечисляет имена метрик пакета через запятую.
+func metricIDs(metrics []models.Metrics) string {
+	ids := make([]string, len(metrics))
+	for i, m := range metrics {
+		ids[i] = m.ID
+	}
+	return strings.Join(ids, ", ")
+}
+
 func customBackoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
 	delays := []time.Duration{1 * time.Second, 3 * time.Second, 5 * time.Second}
 
@@ -190,13 +385,30 @@ func CompressData(data []byte) ([]byte, error) {
 
 func StartAgent() <-chan error {
 	cfg := Config{}
-	errCh := make(chan error)
+	errCh := make(chan error, 1)
 
 	flag.StringVar(&cfg.Addr, "a", "localhost:8080", "Адрес сервера")
 	flag.StringVar(&cfg.Key, "k", "", "Ключ шифрования")
 	flag.IntVar(&cfg.PollInterval, "p", 2, "Значение интервала обновления метрик в секундах")
 	flag.IntVar(&cfg.ReqInterval, "r", 10, "Значение интервала отпрвки в секундах")
 	flag.IntVar(&cfg.RateLimit, "l", 1, "Значение Rate Limit")
+	flag.StringVar(&cfg.Format, "format", FormatJSON, "Формат отправки метрик: json, protobuf или msgpack")
+	flag.StringVar(&cfg.Collectors, "collectors", DefaultCollectors, "Включенные коллекторы через запятую: runtime, poll, system, filesystem, diskio, net, load, fd, cgroup, exec, logtail, probe, prometheus, statsd; интервал задается как system:30s")
+	flag.StringVar(&cfg.MountsInclude, "mounts-include", "", "Шаблоны точек монтирования для коллектора filesystem")
+	flag.StringVar(&cfg.MountsExclude, "mounts-exclude", "", "Исключаемые точки монтирования для коллектора filesystem")
+	flag.StringVar(&cfg.InterfacesInclude, "interfaces-include", "", "Шаблоны сетевых интерфейсов для коллектора net")
+	flag.StringVar(&cfg.InterfacesExclude, "interfaces-exclude", "lo", "Исключаемые сетевые интерфейсы для коллектора net")
+	flag.StringVar(&cfg.CgroupRoot, "cgroup-root", collector.DefaultCgroupRoot, "Каталог cgroup v2 для коллектора cgroup")
+	flag.StringVar(&cfg.ExecCommands, "exec", "", "Команды коллектора exec, разделенные ';'")
+	flag.IntVar(&cfg.ExecTimeout, "exec-timeout", 5, "Предельное время выполнения команды коллектора exec в секундах")
+	flag.StringVar(&cfg.LogTailConfig, "logtail-config", "", "JSON-файл с журналами и правилами коллектора logtail")
+	flag.StringVar(&cfg.LogTailState, "logtail-state", "", "Файл для сохранения позиций чтения журналов")
+	flag.StringVar(&cfg.ProbeTargets, "probe-targets", "", "Цели коллектора probe через запятую: [имя=]http(s)://, tcp:// или tls://")
+	flag.IntVar(&cfg.ProbeTimeout, "probe-timeout", 5, "Предельное время проверки коллектора probe в секундах")
+	flag.StringVar(&cfg.PromTargets, "prom-targets", "", "Цели коллектора prometheus через запятую: [имя=]URL")
+	flag.StringVar(&cfg.PromMode, "prom-mode", collector.ScrapeLabel, "Способ указать цель в имени метрики: label или prefix")
+	flag.IntVar(&cfg.PromTimeout, "prom-timeout", 5, "Предельное время опроса цели коллектора prometheus в секундах")
+	flag.StringVar(&cfg.StatsDAddr, "statsd", collector.DefaultStatsDAddr, "UDP-адрес приемника StatsD коллектора statsd")
 	flag.Parse()
 
 	err := env.Parse(&cfg)
@@ -205,33 +417,84 @@ func StartAgent() <-chan error {
 		return errCh
 	}
 
-	m := store.NewMetricsStorage()
+	if _, ok := contentTypes[cfg.Format]; !ok {
+		errCh <- fmt.Errorf("неизвестный формат отправки метрик: %q", cfg.Format)
+		return errCh
+	}
+
+	registry, err := newRegistry(cfg)
+	if err != nil {
+		errCh <- fmt.Errorf("ошибка настройки коллекторов: %w", err)
+		return errCh
+	}
+	log.Printf("Enabled collectors: %s", strings.Join(registry.Names(), ", "))
+	registry.Start()
+
 	endpoint := "http://" + cfg.Addr
 
 	semaphore := make(chan struct{}, cfg.RateLimit)
 
+	// Состояние коллекторов сохраняется, только если отправленный снимок последний:
+	// иначе оно включает данные более позднего снимка, который еще не доставлен.
+	var snapshotMu sync.Mutex
+	var lastSnapshot uint64
+
+	send := func() {
+		snapshotMu.Lock()
+		lastSnapshot++
+		seq := lastSnapshot
+		metrics := registry.Snapshot()
+		snapshotMu.Unlock()
+
+		if len(metrics) > 0 {
+			if err := sendMetricsBatch(metrics, endpoint, cfg.Key, cfg.Format); isRejected(err) {
+				// Сервер отклонил пакет: повторная отправка тех же приращений снова
+				// завершится отказом, поэтому они отбрасываются.
+				log.Printf("Server rejected metrics, dropped %d: %s: %v", len(metrics), metricIDs(metrics), err)
+			} else if err != nil {
+				registry.Restore(metrics)
+				log.Printf("Final sending metrics error: %v", err)
+				return
+			}
+		}
+
+		snapshotMu.Lock()
+		defer snapshotMu.Unlock()
+		if seq == lastSnapshot {
+			if err := registry.Commit(); err != nil {
+				log.Printf("Failed to commit collector state: %v", err)
+			}
+		}
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
 	go func() {
-		pollTicker := time.NewTicker(time.Second * time.Duration((cfg.PollInterval)))
 		reqTicker := time.NewTicker(time.Second * time.Duration((cfg.ReqInterval)))
+		defer reqTicker.Stop()
 
 		for {
 			select {
-			case <-pollTicker.C:
-				go func() {
-					m.CollectMetrics()
-				}()
-
 			case <-reqTicker.C:
 				go func() {
 					semaphore <- struct{}{}
 					defer func() { <-semaphore }()
+					send()
+				}()
+			case <-quit:
+				log.Printf("Shutting down agent...")
 
-					err := SendAllMetricsBatch(&http.Client{}, endpoint, *m, cfg.Key, cfg.RateLimit)
+				// Дожидаемся начатых отправок, останавливаем коллекторы и отправляем
+				// накопленное с последней отправки.
+				for i := 0; i < cap(semaphore); i++ {
+					semaphore <- struct{}{}
+				}
+				registry.Stop()
+				send()
 
-					if err != nil {
-						log.Printf("Final sending metrics error: %v", err)
-					}
-				}()
+				errCh <- nil
+				return
 			}
 		}
 	}()
@@ -0,0 +1,228 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/levinOo/go-metrics-project/internal/ingest"
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+// DefaultCgroupRoot — точка монтирования единой иерархии cgroup v2.
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+// cpuStatCounters сопоставляет ключи cpu.stat именам counter.
+var cpuStatCounters = map[string]string{
+	"usage_usec":     "CgroupCPUUsageUsec",
+	"user_usec":      "CgroupCPUUserUsec",
+	"system_usec":    "CgroupCPUSystemUsec",
+	"nr_periods":     "CgroupCPUPeriods",
+	"nr_throttled":   "CgroupCPUThrottledPeriods",
+	"throttled_usec": "CgroupCPUThrottledUsec",
+}
+
+// ioStatCounters сопоставляет ключи io.stat именам counter.
+var ioStatCounters = map[string]string{
+	"rbytes": "CgroupIOReadBytes",
+	"wbytes": "CgroupIOWriteBytes",
+	"rios":   "CgroupIOReads",
+	"wios":   "CgroupIOWrites",
+}
+
+// Cgroup сообщает потребление ресурсов контейнера из cgroup v2:
+//   - gauge CgroupMemoryCurrent, CgroupMemoryMax, CgroupPidsCurrent и CgroupPidsMax;
+//   - counter из cpu.stat (CgroupCPUUsageUsec, CgroupCPUThrottledPeriods и др.);
+//   - counter из io.stat по устройствам с меткой device.
+//
+// Лимиты со значением "max" не сообщаются. Файлы отключенных контроллеров
+// пропускаются. Первый опрос только запоминает значения счетчиков.
+type Cgroup struct {
+	root string
+
+	mu     sync.Mutex
+	deltas *deltas
+}
+
+// NewCgroup создает коллектор cgroup для каталога root.
+func NewCgroup(root string) *Cgroup {
+	if root == "" {
+		root = DefaultCgroupRoot
+	}
+	return &Cgroup{root: root, deltas: newDeltas()}
+}
+
+// Name возвращает "cgroup".
+func (c *Cgroup) Name() string {
+	return "cgroup"
+}
+
+// Collect читает файлы контроллеров memory, cpu, io и pids.
+func (c *Cgroup) Collect(context.Context) ([]models.Metrics, error) {
+	if _, err := os.Stat(filepath.Join(c.root, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("%s is not a cgroup v2 directory: %w", c.root, err)
+	}
+
+	var metrics []models.Metrics
+	var errs []error
+
+	for _, g := range []struct {
+		file string
+		name string
+	}{
+		{"memory.current", "CgroupMemoryCurrent"},
+		{"memory.max", "CgroupMemoryMax"},
+		{"pids.current", "CgroupPidsCurrent"},
+		{"pids.max", "CgroupPidsMax"},
+	} {
+		value, ok, err := c.readValue(g.file)
+		if err != nil {
+			errs = append(errs, err)
+		} else if ok {
+			metrics = append(metrics, Gauge(g.name, float64(value)))
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.deltas.begin()
+	defer c.deltas.end()
+
+	cpuStat, err := c.readStat("cpu.stat")
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, kv := range cpuStat {
+		if name, ok := cpuStatCounters[kv.key]; ok {
+			metrics = c.appendDelta(metrics, name, kv.value)
+		}
+	}
+
+	ioStat, err := c.readIOStat()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, dev := range ioStat {
+		tags := []ingest.Tag{{Key: "device", Value: dev.device}}
+		for _, kv := range dev.stats {
+			if name, ok := ioStatCounters[kv.key]; ok {
+				metrics = c.appendDelta(metrics, ingest.MetricName(name, tags), kv.value)
+			}
+		}
+	}
+
+	return metrics, errors.Join(errs...)
+}
+
+func (c *Cgroup) appendDelta(metrics []models.Metrics, id string, value uint64) []models.Metrics {
+	if delta, ok := c.deltas.add(id, value); ok {
+		metrics = append(metrics, Counter(id, delta))
+	}
+	return metrics
+}
+
+// read читает файл контроллера. Отсутствующий файл (контроллер не включен)
+// возвращает nil без ошибки.
+func (c *Cgroup) read(file string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(c.root, file))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cgroup %s: %w", file, err)
+	}
+	return data, nil
+}
+
+// readValue читает файл с одним числом. Значение "max" и отсутствующий файл
+// возвращают ok == false.
+func (c *Cgroup) readValue(file string) (value uint64, ok bool, err error) {
+	data, err := c.read(file)
+	if err != nil || data == nil {
+		return 0, false, err
+	}
+
+	s := strings.TrimSpace(string(data))
+	if s == "max" {
+		return 0, false, nil
+	}
+	value, err = strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("cgroup %s: %w", file, err)
+	}
+	return value, true, nil
+}
+
+type statValue struct {
+	key   string
+	value uint64
+}
+
+// readStat разбирает файл из строк "ключ значение".
+func (c *Cgroup) readStat(file string) ([]statValue, error) {
+	data, err := c.read(file)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var stats []statValue
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return stats, fmt.Errorf("cgroup %s: %s: %w", file, fields[0], err)
+		}
+		stats = append(stats, statValue{key: fields[0], value: value})
+	}
+	return stats, scanner.Err()
+}
+
+type ioDevice struct {
+	device string
+	stats  []statValue
+}
+
+// readIOStat разбирает io.stat из строк "MAJ:MIN ключ=значение ...".
+func (c *Cgroup) readIOStat() ([]ioDevice, error) {
+	data, err := c.read("io.stat")
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var devices []ioDevice
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		dev := ioDevice{device: fields[0]}
+		for _, field := range fields[1:] {
+			key, raw, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			value, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				return devices, fmt.Errorf("cgroup io.stat: %s %s: %w", dev.device, key, err)
+			}
+			dev.stats = append(dev.stats, statValue{key: key, value: value})
+		}
+		devices = append(devices, dev)
+	}
+	return devices, scanner.Err()
+}
@@ -0,0 +1,96 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeCgroup(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCgroup(t *testing.T) {
+	root := t.TempDir()
+	writeCgroup(t, root, map[string]string{
+		"cgroup.controllers": "cpu io memory pids\n",
+		"memory.current":     "104857600\n",
+		"memory.max":         "max\n",
+		"pids.current":       "12\n",
+		"pids.max":           "256\n",
+		"cpu.stat":           "usage_usec 1000\nuser_usec 600\nsystem_usec 400\nnr_periods 10\nnr_throttled 2\nthrottled_usec 500\n",
+		"io.stat":            "8:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0\n",
+	})
+
+	c := NewCgroup(root)
+	metrics, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := byID(metrics)
+	if len(got) != 3 {
+		t.Errorf("first poll must report only gauges, got %+v", metrics)
+	}
+	if m := got["CgroupMemoryCurrent"]; m.Value == nil || *m.Value != 104857600 {
+		t.Errorf("unexpected CgroupMemoryCurrent: %+v", m)
+	}
+	if _, ok := got["CgroupMemoryMax"]; ok {
+		t.Error("unlimited memory.max must not be reported")
+	}
+	if m := got["CgroupPidsMax"]; m.Value == nil || *m.Value != 256 {
+		t.Errorf("unexpected CgroupPidsMax: %+v", m)
+	}
+
+	writeCgroup(t, root, map[string]string{
+		"memory.max": "536870912\n",
+		"cpu.stat":   "usage_usec 1800\nuser_usec 1000\nsystem_usec 800\nnr_periods 20\nnr_throttled 5\nthrottled_usec 900\n",
+		"io.stat":    "8:0 rbytes=6144 wbytes=8192 rios=3 wios=2 dbytes=0 dios=0\n",
+	})
+	metrics, err = c.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got = byID(metrics)
+	for id, want := range map[string]int64{
+		"CgroupCPUUsageUsec":            800,
+		"CgroupCPUThrottledPeriods":     3,
+		"CgroupCPUThrottledUsec":        400,
+		"CgroupIOReadBytes.device:8_0":  2048,
+		"CgroupIOWriteBytes.device:8_0": 0,
+		"CgroupIOReads.device:8_0":      2,
+	} {
+		if m := got[id]; m.Delta == nil || *m.Delta != want {
+			t.Errorf("%s: expected delta %d, got %+v", id, want, m)
+		}
+	}
+	if m := got["CgroupMemoryMax"]; m.Value == nil || *m.Value != 536870912 {
+		t.Errorf("unexpected CgroupMemoryMax: %+v", m)
+	}
+}
+
+func TestCgroupMissingControllers(t *testing.T) {
+	if _, err := NewCgroup(t.TempDir()).Collect(context.Background()); err == nil {
+		t.Error("expected error for directory without cgroup.controllers")
+	}
+
+	root := t.TempDir()
+	writeCgroup(t, root, map[string]string{
+		"cgroup.controllers": "memory\n",
+		"memory.current":     "1\n",
+	})
+	metrics, err := NewCgroup(root).Collect(context.Background())
+	if err != nil {
+		t.Fatalf("disabled controllers must be skipped: %v", err)
+	}
+	if len(metrics) != 1 {
+		t.Errorf("expected only CgroupMemoryCurrent, got %+v", metrics)
+	}
+}
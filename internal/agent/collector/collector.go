@@ -0,0 +1,54 @@
+// Package collector определяет интерфейс источников метрик агента и реестр,
+// который опрашивает их по расписанию и накапливает значения до отправки.
+//
+// Каждый коллектор опрашивается в своей горутине со своим интервалом. Ошибка,
+// зависание или паника одного коллектора не мешают остальным: ошибка записывается
+// в журнал, а метрики других коллекторов продолжают обновляться.
+package collector
+
+import (
+	"context"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+// Collector — источник метрик агента.
+type Collector interface {
+	// Name возвращает уникальное имя коллектора, под которым он включается в конфигурации.
+	Name() string
+
+	// Collect собирает метрики. Для gauge Value содержит текущее значение, для
+	// counter Delta содержит приращение с предыдущего вызова Collect. При ошибке
+	// коллектор может вернуть метрики, которые удалось собрать.
+	//
+	// Контекст отменяется по истечении интервала опроса коллектора.
+	Collect(ctx context.Context) ([]models.Metrics, error)
+}
+
+// Flusher — коллектор, который сам накапливает данные между отправками, например
+// приемник StatsD. Реестр вызывает Flush при каждом снимке, и накопленные за
+// интервал отправки метрики попадают только в тот же пакет: gauge, которые нужно
+// отправлять и дальше, Flush возвращает при каждом вызове.
+type Flusher interface {
+	Flush() []models.Metrics
+}
+
+// Committer — коллектор Flusher, состояние которого сохраняется только после
+// доставки метрик, например позиции чтения журналов. Commit сохраняет состояние
+// на момент последнего Flush и вызывается реестром после успешной отправки снимка.
+type Committer interface {
+	Commit() error
+}
+
+// Factory создает коллектор. Используется для включения коллекторов по имени из конфигурации.
+type Factory func() (Collector, error)
+
+// Gauge создает метрику gauge с именем name.
+func Gauge(name string, value float64) models.Metrics {
+	return models.Metrics{ID: name, MType: models.Gauge, Value: &value}
+}
+
+// Counter создает метрику counter с приращением delta.
+func Counter(name string, delta int64) models.Metrics {
+	return models.Metrics{ID: name, MType: models.Counter, Delta: &delta}
+}
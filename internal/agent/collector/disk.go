@@ -0,0 +1,140 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/levinOo/go-metrics-project/internal/ingest"
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/shirou/gopsutil/disk"
+)
+
+// Filesystem сообщает заполненность файловых систем по точкам монтирования:
+// FilesystemTotal, FilesystemUsed, FilesystemFree (байты), FilesystemUsedPercent
+// и FilesystemInodesUsedPercent. Имя точки монтирования добавляется к имени
+// метрики меткой mount, например "FilesystemUsed.mount:_var".
+type Filesystem struct {
+	filter Filter
+
+	partitions func(ctx context.Context, all bool) ([]disk.PartitionStat, error)
+	usage      func(ctx context.Context, path string) (*disk.UsageStat, error)
+}
+
+// NewFilesystem создает коллектор filesystem для точек монтирования, прошедших filter.
+func NewFilesystem(filter Filter) *Filesystem {
+	return &Filesystem{
+		filter:     filter,
+		partitions: disk.PartitionsWithContext,
+		usage:      disk.UsageWithContext,
+	}
+}
+
+// Name возвращает "filesystem".
+func (f *Filesystem) Name() string {
+	return "filesystem"
+}
+
+// Collect опрашивает физические файловые системы. Недоступная точка
+// монтирования не мешает опросу остальных.
+func (f *Filesystem) Collect(ctx context.Context) ([]models.Metrics, error) {
+	partitions, err := f.partitions(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("partitions: %w", err)
+	}
+
+	var metrics []models.Metrics
+	var errs []error
+	seen := make(map[string]bool)
+
+	for _, p := range partitions {
+		if seen[p.Mountpoint] || !f.filter.Match(p.Mountpoint) {
+			continue
+		}
+		seen[p.Mountpoint] = true
+
+		u, err := f.usage(ctx, p.Mountpoint)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mount %s: %w", p.Mountpoint, err))
+			continue
+		}
+
+		tags := []ingest.Tag{{Key: "mount", Value: p.Mountpoint}}
+		metrics = append(metrics,
+			Gauge(ingest.MetricName("FilesystemTotal", tags), float64(u.Total)),
+			Gauge(ingest.MetricName("FilesystemUsed", tags), float64(u.Used)),
+			Gauge(ingest.MetricName("FilesystemFree", tags), float64(u.Free)),
+			Gauge(ingest.MetricName("FilesystemUsedPercent", tags), u.UsedPercent),
+		)
+		if u.InodesTotal > 0 {
+			metrics = append(metrics, Gauge(ingest.MetricName("FilesystemInodesUsedPercent", tags), u.InodesUsedPercent))
+		}
+	}
+	return metrics, errors.Join(errs...)
+}
+
+// DiskIO сообщает приращения счетчиков ввода-вывода блочных устройств:
+// DiskReadBytes, DiskWriteBytes, DiskReads и DiskWrites с меткой device.
+// Первый опрос только запоминает значения счетчиков.
+type DiskIO struct {
+	mu     sync.Mutex
+	deltas *deltas
+
+	ioCounters func(ctx context.Context, names ...string) (map[string]disk.IOCountersStat, error)
+}
+
+// NewDiskIO создает коллектор diskio.
+func NewDiskIO() *DiskIO {
+	return &DiskIO{
+		deltas:     newDeltas(),
+		ioCounters: disk.IOCountersWithContext,
+	}
+}
+
+// Name возвращает "diskio".
+func (d *DiskIO) Name() string {
+	return "diskio"
+}
+
+// Collect читает счетчики устройств и возвращает их приращения с прошлого опроса.
+func (d *DiskIO) Collect(ctx context.Context) ([]models.Metrics, error) {
+	counters, err := d.ioCounters(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("disk io: %w", err)
+	}
+
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.deltas.begin()
+	defer d.deltas.end()
+
+	var metrics []models.Metrics
+	for _, name := range names {
+		c := counters[name]
+		tags := []ingest.Tag{{Key: "device", Value: name}}
+		for _, v := range []struct {
+			base  string
+			value uint64
+		}{
+			{"DiskReadBytes", c.ReadBytes},
+			{"DiskWriteBytes", c.WriteBytes},
+			{"DiskReads", c.ReadCount},
+			{"DiskWrites", c.WriteCount},
+		} {
+			id := ingest.MetricName(v.base, tags)
+			if delta, ok := d.deltas.add(id, v.value); ok {
+				metrics = append(metrics, Counter(id, delta))
+			}
+		}
+	}
+	return metrics, nil
+}
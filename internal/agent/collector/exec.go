@@ -0,0 +1,214 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/validation"
+)
+
+// maxExecOutput ограничивает объем читаемого вывода команды.
+const maxExecOutput = 1 << 20
+
+// Exec запускает внешние команды и разбирает их стандартный вывод как метрики.
+//
+// Вывод — либо JSON (объект или массив models.Metrics), либо строки вида
+// "name type value", где type — gauge или counter. Пустые строки и строки,
+// начинающиеся с "#", пропускаются. Значение counter — приращение за запуск.
+type Exec struct {
+	commands []string
+	timeout  time.Duration
+}
+
+// NewExec создает коллектор exec. Команды выполняются через /bin/sh -c, каждая
+// не дольше timeout.
+func NewExec(commands []string, timeout time.Duration) (*Exec, error) {
+	if len(commands) == 0 {
+		return nil, errors.New("no commands configured")
+	}
+	if timeout <= 0 {
+		return nil, errors.New("timeout must be positive")
+	}
+	return &Exec{commands: commands, timeout: timeout}, nil
+}
+
+// ParseCommands разбирает список команд, разделенных ";".
+func ParseCommands(s string) []string {
+	var commands []string
+	for _, c := range strings.Split(s, ";") {
+		if c = strings.TrimSpace(c); c != "" {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+// Name возвращает "exec".
+func (e *Exec) Name() string {
+	return "exec"
+}
+
+// Collect запускает все команды параллельно. Сбой одной команды не мешает
+// вернуть метрики остальных.
+func (e *Exec) Collect(ctx context.Context) ([]models.Metrics, error) {
+	results := make([][]models.Metrics, len(e.commands))
+	errs := make([]error, len(e.commands))
+
+	var wg sync.WaitGroup
+	for i, command := range e.commands {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = e.run(ctx, command)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("%q: %w", command, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	var metrics []models.Metrics
+	for _, r := range results {
+		metrics = append(metrics, r...)
+	}
+	return metrics, errors.Join(errs...)
+}
+
+func (e *Exec) run(ctx context.Context, command string) ([]models.Metrics, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	stdout := &limitedBuffer{limit: maxExecOutput}
+	stderr := &limitedBuffer{limit: 4096}
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Не ждем дочерние процессы, унаследовавшие вывод, после отмены.
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("timed out after %s", e.timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	if stdout.truncated {
+		return nil, fmt.Errorf("output exceeds %d bytes", maxExecOutput)
+	}
+
+	return ParseExecOutput(stdout.Bytes())
+}
+
+// ParseExecOutput разбирает вывод команды. Некорректные метрики пропускаются,
+// ошибки по ним возвращаются вместе с корректными метриками.
+func ParseExecOutput(data []byte) ([]models.Metrics, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	var parsed []models.Metrics
+	var errs []error
+
+	switch trimmed[0] {
+	case '[':
+		if err := json.Unmarshal(trimmed, &parsed); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	case '{':
+		var m models.Metrics
+		if err := json.Unmarshal(trimmed, &m); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		parsed = append(parsed, m)
+	default:
+		parsed, errs = parseExecLines(trimmed)
+	}
+
+	metrics := make([]models.Metrics, 0, len(parsed))
+	for _, m := range parsed {
+		m.Hash = ""
+		if err := validation.Default().Metric(m); err != nil {
+			errs = append(errs, fmt.Errorf("metric %q: %w", m.ID, err))
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, errors.Join(errs...)
+}
+
+// parseExecLines разбирает строки "name type value".
+func parseExecLines(data []byte) ([]models.Metrics, []error) {
+	var metrics []models.Metrics
+	var errs []error
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			errs = append(errs, fmt.Errorf("line %d: expected \"name type value\"", n))
+			continue
+		}
+
+		name, mtype, raw := fields[0], fields[1], fields[2]
+		switch mtype {
+		case models.Gauge:
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("line %d: invalid gauge value %q", n, raw))
+				continue
+			}
+			metrics = append(metrics, Gauge(name, v))
+		case models.Counter:
+			d, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("line %d: invalid counter value %q", n, raw))
+				continue
+			}
+			metrics = append(metrics, Counter(name, d))
+		default:
+			errs = append(errs, fmt.Errorf("line %d: unknown metric type %q", n, mtype))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return metrics, errs
+}
+
+// limitedBuffer сохраняет не более limit байт, остальное отбрасывает.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); len(p) > room {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
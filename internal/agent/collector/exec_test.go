@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseExecOutput(t *testing.T) {
+	metrics, err := ParseExecOutput([]byte("# queue stats\nqueue.depth gauge 42.5\n\njobs.done counter 3\nbroken line\nbad name! gauge 1\njobs.failed counter 1.5\n"))
+	if err == nil {
+		t.Error("expected errors for malformed lines")
+	}
+	got := byID(metrics)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 metrics, got %+v", metrics)
+	}
+	if m := got["queue.depth"]; m.Value == nil || *m.Value != 42.5 {
+		t.Errorf("unexpected queue.depth: %+v", m)
+	}
+	if m := got["jobs.done"]; m.Delta == nil || *m.Delta != 3 {
+		t.Errorf("unexpected jobs.done: %+v", m)
+	}
+
+	metrics, err = ParseExecOutput([]byte(`[{"id":"orders","type":"counter","delta":7},{"id":"lag","type":"gauge"}]`))
+	if err == nil {
+		t.Error("expected error for gauge without value")
+	}
+	if len(metrics) != 1 || metrics[0].ID != "orders" || *metrics[0].Delta != 7 {
+		t.Errorf("unexpected JSON metrics: %+v", metrics)
+	}
+
+	metrics, err = ParseExecOutput([]byte(` {"id":"lag","type":"gauge","value":0.25}`))
+	if err != nil || len(metrics) != 1 || *metrics[0].Value != 0.25 {
+		t.Errorf("unexpected single JSON metric: %+v, %v", metrics, err)
+	}
+}
+
+func TestExecCollect(t *testing.T) {
+	e, err := NewExec(ParseCommands("echo 'queue.depth gauge 5'; exit 3; sleep 5"), 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	metrics, err := e.Collect(context.Background())
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("timeout was not enforced, took %s", time.Since(start))
+	}
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected exit and timeout errors, got %v", err)
+	}
+	if len(metrics) != 1 || metrics[0].ID != "queue.depth" {
+		t.Errorf("expected result of the successful command, got %+v", metrics)
+	}
+
+	if _, err := NewExec(nil, time.Second); err == nil {
+		t.Error("expected error without commands")
+	}
+}
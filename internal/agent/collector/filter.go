@@ -0,0 +1,92 @@
+package collector
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Filter отбирает имена (точки монтирования, сетевые интерфейсы) по шаблонам
+// path.Match. Пустой список Include разрешает все имена; Exclude проверяется после Include.
+type Filter struct {
+	Include []string
+	Exclude []string
+}
+
+// ParseFilter создает Filter из списков шаблонов через запятую.
+func ParseFilter(include, exclude string) (Filter, error) {
+	f := Filter{Include: splitPatterns(include), Exclude: splitPatterns(exclude)}
+	for _, p := range append(append([]string(nil), f.Include...), f.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return Filter{}, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+	return f, nil
+}
+
+// Match сообщает, проходит ли name фильтр.
+func (f Filter) Match(name string) bool {
+	if len(f.Include) > 0 && !matchAny(f.Include, name) {
+		return false
+	}
+	return !matchAny(f.Exclude, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func splitPatterns(s string) []string {
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// deltas превращает монотонные счетчики ядра в приращения counter. Первое
+// наблюдение ряда только запоминается; уменьшение значения (сброс счетчика или
+// пересоздание устройства) считается приращением от нуля.
+type deltas struct {
+	prev map[string]uint64
+	seen map[string]bool
+}
+
+func newDeltas() *deltas {
+	return &deltas{prev: make(map[string]uint64)}
+}
+
+// begin начинает опрос: ряды, не обновленные до end, забываются.
+func (d *deltas) begin() {
+	d.seen = make(map[string]bool)
+}
+
+// add возвращает приращение ряда key и false для первого наблюдения.
+func (d *deltas) add(key string, value uint64) (int64, bool) {
+	d.seen[key] = true
+	prev, ok := d.prev[key]
+	d.prev[key] = value
+	if !ok {
+		return 0, false
+	}
+	if value < prev {
+		return int64(value), true
+	}
+	return int64(value - prev), true
+}
+
+// end забывает ряды, исчезнувшие с предыдущего опроса.
+func (d *deltas) end() {
+	for key := range d.prev {
+		if !d.seen[key] {
+			delete(d.prev, key)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/net"
+)
+
+func byID(metrics []models.Metrics) map[string]models.Metrics {
+	result := make(map[string]models.Metrics, len(metrics))
+	for _, m := range metrics {
+		result[m.ID] = m
+	}
+	return result
+}
+
+func TestFilter(t *testing.T) {
+	f, err := ParseFilter("/, /data/*", "/data/tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"/":           true,
+		"/data/db":    true,
+		"/data/tmp":   false,
+		"/boot":       false,
+		"/data/a/b/c": false,
+	} {
+		if got := f.Match(name); got != want {
+			t.Errorf("%s: expected %v, got %v", name, want, got)
+		}
+	}
+
+	if !(Filter{}).Match("anything") {
+		t.Error("empty filter must match everything")
+	}
+	if _, err := ParseFilter("[", ""); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestFilesystem(t *testing.T) {
+	f := NewFilesystem(Filter{Exclude: []string{"/boot"}})
+	f.partitions = func(context.Context, bool) ([]disk.PartitionStat, error) {
+		return []disk.PartitionStat{{Mountpoint: "/"}, {Mountpoint: "/boot"}, {Mountpoint: "/var/lib"}}, nil
+	}
+	f.usage = func(_ context.Context, path string) (*disk.UsageStat, error) {
+		if path == "/var/lib" {
+			return nil, errors.New("permission denied")
+		}
+		return &disk.UsageStat{Total: 100, Used: 40, Free: 60, UsedPercent: 40}, nil
+	}
+
+	metrics, err := f.Collect(context.Background())
+	if err == nil {
+		t.Error("expected error for unavailable mount")
+	}
+
+	got := byID(metrics)
+	if len(got) != 4 {
+		t.Errorf("expected 4 metrics for /, got %+v", metrics)
+	}
+	if m, ok := got["FilesystemUsedPercent.mount:_"]; !ok || *m.Value != 40 {
+		t.Errorf("unexpected FilesystemUsedPercent: %+v", m)
+	}
+}
+
+func TestNetworkDeltas(t *testing.T) {
+	n := NewNetwork(Filter{Exclude: []string{"lo"}})
+	samples := [][]net.IOCountersStat{
+		{{Name: "eth0", BytesRecv: 100, Errin: 1}, {Name: "lo", BytesRecv: 5}},
+		{{Name: "eth0", BytesRecv: 250, Errin: 1}, {Name: "lo", BytesRecv: 9}},
+		// Счетчик сброшен: интерфейс пересоздан.
+		{{Name: "eth0", BytesRecv: 30, Errin: 0}},
+	}
+	call := 0
+	n.ioCounters = func(context.Context, bool) ([]net.IOCountersStat, error) {
+		call++
+		return samples[call-1], nil
+	}
+
+	metrics, err := n.Collect(context.Background())
+	if err != nil || len(metrics) != 0 {
+		t.Fatalf("first poll must only remember counters, got %+v, %v", metrics, err)
+	}
+
+	for _, want := range []int64{150, 30} {
+		metrics, err = n.Collect(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := byID(metrics)
+		if _, ok := got["NetBytesRecv.interface:lo"]; ok {
+			t.Error("excluded interface reported")
+		}
+		if m := got["NetBytesRecv.interface:eth0"]; m.Delta == nil || *m.Delta != want {
+			t.Errorf("expected NetBytesRecv delta %d, got %+v", want, m)
+		}
+		if m := got["NetErrIn.interface:eth0"]; m.Delta == nil || *m.Delta != 0 {
+			t.Errorf("expected NetErrIn delta 0, got %+v", m)
+		}
+	}
+}
+
+func TestFileDescriptors(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "sys", "fs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "sys", "fs", "file-nr"), []byte("1024\t0\t65536\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFileDescriptors()
+	f.procRoot = root
+
+	metrics, err := f.Collect(context.Background())
+	got := byID(metrics)
+	if m := got["SystemOpenFiles"]; m.Value == nil || *m.Value != 1024 {
+		t.Errorf("unexpected SystemOpenFiles: %+v", m)
+	}
+	if m := got["SystemMaxOpenFiles"]; m.Value == nil || *m.Value != 65536 {
+		t.Errorf("unexpected SystemMaxOpenFiles: %+v", m)
+	}
+	if _, ok := got["ProcessOpenFiles"]; !ok {
+		t.Logf("process fds unavailable: %v", err)
+	}
+}
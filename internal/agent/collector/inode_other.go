@@ -0,0 +1,10 @@
+//go:build !unix
+
+package collector
+
+import "os"
+
+// fileInode не поддерживается: позиции файлов журналов не сохраняются.
+func fileInode(os.FileInfo) (uint64, bool) {
+	return 0, false
+}
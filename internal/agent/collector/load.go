@@ -0,0 +1,113 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/shirou/gopsutil/load"
+	"github.com/shirou/gopsutil/process"
+)
+
+// Load сообщает среднюю загрузку системы LoadAverage1, LoadAverage5 и LoadAverage15.
+type Load struct {
+	avg func(ctx context.Context) (*load.AvgStat, error)
+}
+
+// NewLoad создает коллектор load.
+func NewLoad() *Load {
+	return &Load{avg: load.AvgWithContext}
+}
+
+// Name возвращает "load".
+func (l *Load) Name() string {
+	return "load"
+}
+
+// Collect читает среднюю загрузку.
+func (l *Load) Collect(ctx context.Context) ([]models.Metrics, error) {
+	avg, err := l.avg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load average: %w", err)
+	}
+	return []models.Metrics{
+		Gauge("LoadAverage1", avg.Load1),
+		Gauge("LoadAverage5", avg.Load5),
+		Gauge("LoadAverage15", avg.Load15),
+	}, nil
+}
+
+// FileDescriptors сообщает число открытых файловых дескрипторов: SystemOpenFiles
+// и SystemMaxOpenFiles из /proc/sys/fs/file-nr и ProcessOpenFiles самого агента.
+type FileDescriptors struct {
+	procRoot string
+	pid      int32
+}
+
+// NewFileDescriptors создает коллектор fd.
+func NewFileDescriptors() *FileDescriptors {
+	return &FileDescriptors{procRoot: "/proc", pid: int32(os.Getpid())}
+}
+
+// Name возвращает "fd".
+func (f *FileDescriptors) Name() string {
+	return "fd"
+}
+
+// Collect читает системные и собственные дескрипторы, ошибка одного источника
+// не мешает вернуть метрики другого.
+func (f *FileDescriptors) Collect(ctx context.Context) ([]models.Metrics, error) {
+	var metrics []models.Metrics
+	var errs []error
+
+	openFiles, maxFiles, err := readFileNr(filepath.Join(f.procRoot, "sys", "fs", "file-nr"))
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		metrics = append(metrics,
+			Gauge("SystemOpenFiles", openFiles),
+			Gauge("SystemMaxOpenFiles", maxFiles),
+		)
+	}
+
+	p, err := process.NewProcessWithContext(ctx, f.pid)
+	if err == nil {
+		var n int32
+		if n, err = p.NumFDsWithContext(ctx); err == nil {
+			metrics = append(metrics, Gauge("ProcessOpenFiles", float64(n)))
+		}
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("process fds: %w", err))
+	}
+
+	return metrics, errors.Join(errs...)
+}
+
+// readFileNr разбирает /proc/sys/fs/file-nr: "выделено свободно максимум".
+func readFileNr(path string) (openFiles, maxFiles float64, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("file-nr: %w", err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) != 3 {
+		return 0, 0, fmt.Errorf("file-nr: unexpected format %q", strings.TrimSpace(string(data)))
+	}
+
+	var values [3]float64
+	for i, s := range fields {
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("file-nr: %w", err)
+		}
+		values[i] = float64(v)
+	}
+	return values[0] - values[1], values[2], nil
+}
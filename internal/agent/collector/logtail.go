@@ -0,0 +1,429 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+// maxLogLine ограничивает длину строки журнала; более длинные строки пропускаются.
+const maxLogLine = 64 << 10
+
+// LogRule — правило разбора строк журнала. Каждая строка, совпавшая с Pattern,
+// увеличивает counter Counter на единицу (если он задан). Именованные группы
+// Pattern с числовыми значениями сообщаются как gauge с именем группы.
+type LogRule struct {
+	Counter string `json:"counter"`
+	Pattern string `json:"pattern"`
+
+	re *regexp.Regexp
+}
+
+// LogFile — отслеживаемый файл журнала и его правила.
+type LogFile struct {
+	Path  string    `json:"path"`
+	Rules []LogRule `json:"rules"`
+}
+
+// LoadLogTailConfig читает описание отслеживаемых файлов из JSON-файла вида
+// {"files": [{"path": "...", "rules": [{"counter": "...", "pattern": "..."}]}]}.
+func LoadLogTailConfig(path string) ([]LogFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg struct {
+		Files []LogFile `json:"files"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return cfg.Files, nil
+}
+
+// logState — сохраняемая позиция чтения файла.
+type logState struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// tailedFile — открытый отслеживаемый файл.
+type tailedFile struct {
+	LogFile
+
+	f    *os.File
+	info os.FileInfo
+
+	// offset — позиция чтения, lineStart — начало незавершенной строки partial.
+	offset    int64
+	lineStart int64
+	partial   []byte
+	skipping  bool
+}
+
+// LogTail следит за файлами журналов и превращает совпадения правил в метрики.
+//
+// Ротация определяется по смене файла под тем же путем: старый файл дочитывается
+// до конца, новый читается с начала. При усечении файла чтение начинается заново.
+// При первом запуске файл читается с конца, чтобы не учитывать старые записи.
+//
+// Совпадения накапливаются между отправками и попадают в снимок реестра через
+// Flush. Позиции, до которых прочитаны отданные Flush строки, сохраняются в
+// stateFile только вызовом Commit после успешной отправки снимка: после
+// перезапуска агента недоставленные строки читаются заново, а доставленные
+// не учитываются повторно.
+type LogTail struct {
+	stateFile string
+
+	mu       sync.Mutex
+	files    []*tailedFile
+	saved    map[string]logState
+	counters map[string]int64
+	gauges   map[string]float64
+
+	// flushed — позиции на момент последнего Flush, ожидающие Commit.
+	flushed map[string]logState
+}
+
+// NewLogTail создает коллектор logtail. Если stateFile пуст, позиции не сохраняются.
+func NewLogTail(files []LogFile, stateFile string) (*LogTail, error) {
+	if len(files) == 0 {
+		return nil, errors.New("no log files configured")
+	}
+
+	t := &LogTail{
+		stateFile: stateFile,
+		saved:     make(map[string]logState),
+		counters:  make(map[string]int64),
+		gauges:    make(map[string]float64),
+	}
+	for _, lf := range files {
+		if lf.Path == "" {
+			return nil, errors.New("log file path is empty")
+		}
+		if len(lf.Rules) == 0 {
+			return nil, fmt.Errorf("%s: no rules", lf.Path)
+		}
+
+		rules := make([]LogRule, len(lf.Rules))
+		for i, rule := range lf.Rules {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s: rule %d: %w", lf.Path, i, err)
+			}
+			if rule.Counter == "" && len(re.SubexpNames()) < 2 {
+				return nil, fmt.Errorf("%s: rule %d: neither counter nor named groups", lf.Path, i)
+			}
+			rule.re = re
+			rules[i] = rule
+		}
+		t.files = append(t.files, &tailedFile{LogFile: LogFile{Path: lf.Path, Rules: rules}})
+	}
+
+	if stateFile != "" {
+		data, err := os.ReadFile(stateFile)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return nil, fmt.Errorf("read state: %w", err)
+		default:
+			if err := json.Unmarshal(data, &t.saved); err != nil {
+				return nil, fmt.Errorf("parse state %s: %w", stateFile, err)
+			}
+		}
+	}
+	return t, nil
+}
+
+// Name возвращает "logtail".
+func (t *LogTail) Name() string {
+	return "logtail"
+}
+
+// Collect дочитывает новые строки всех файлов. Метрики отдаются Flush.
+func (t *LogTail) Collect(context.Context) ([]models.Metrics, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var errs []error
+	for _, tf := range t.files {
+		if err := t.poll(tf, t.counters, t.gauges); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", tf.Path, err))
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Flush отдает совпадения, накопленные с прошлого Flush, и запоминает позиции
+// чтения для Commit.
+func (t *LogTail) Flush() []models.Metrics {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	metrics := make([]models.Metrics, 0, len(t.counters)+len(t.gauges))
+	for name, delta := range t.counters {
+		metrics = append(metrics, Counter(name, delta))
+	}
+	for name, value := range t.gauges {
+		metrics = append(metrics, Gauge(name, value))
+	}
+	clear(t.counters)
+	clear(t.gauges)
+
+	for _, tf := range t.files {
+		if tf.f == nil {
+			continue
+		}
+		if ino, ok := fileInode(tf.info); ok {
+			// Незавершенная строка будет прочитана заново после перезапуска.
+			t.saved[tf.Path] = logState{Inode: ino, Offset: tf.lineStart}
+		}
+	}
+	t.flushed = make(map[string]logState, len(t.saved))
+	for path, state := range t.saved {
+		t.flushed[path] = state
+	}
+	return metrics
+}
+
+// Commit сохраняет позиции чтения на момент последнего Flush.
+func (t *LogTail) Commit() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.flushed == nil {
+		return nil
+	}
+	if err := t.saveState(t.flushed); err != nil {
+		return fmt.Errorf("save state: %w", err)
+	}
+	t.flushed = nil
+	return nil
+}
+
+// Close закрывает открытые файлы.
+func (t *LogTail) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, tf := range t.files {
+		if tf.f != nil {
+			tf.f.Close()
+			tf.f = nil
+		}
+	}
+	return nil
+}
+
+func (t *LogTail) poll(tf *tailedFile, counters map[string]int64, gauges map[string]float64) error {
+	if tf.f == nil {
+		if err := t.open(tf); err != nil {
+			return err
+		}
+		if tf.f == nil {
+			return nil
+		}
+	}
+
+	// Усечение: файл стал короче прочитанного.
+	if info, err := tf.f.Stat(); err == nil && info.Size() < tf.offset {
+		if _, err := tf.f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		tf.offset, tf.lineStart = 0, 0
+		tf.partial, tf.skipping = nil, false
+	}
+
+	if err := tf.read(counters, gauges); err != nil {
+		return err
+	}
+
+	// Ротация: под путем теперь другой файл или файла нет.
+	info, err := os.Stat(tf.Path)
+	if err == nil && os.SameFile(info, tf.info) {
+		return nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// Последняя строка старого файла может быть без перевода строки.
+	if len(tf.partial) > 0 && !tf.skipping {
+		tf.match(bytes.TrimRight(tf.partial, "\r\n"), counters, gauges)
+	}
+	tf.f.Close()
+	tf.f = nil
+	// Новый файл под этим путем читается с начала, даже если появится позже.
+	t.saved[tf.Path] = logState{}
+	if err != nil {
+		return nil
+	}
+
+	if err := tf.openAt(0); err != nil {
+		return err
+	}
+	return tf.read(counters, gauges)
+}
+
+// open открывает файл, продолжая с сохраненной позиции, если файл тот же.
+// Отсутствующий файл не считается ошибкой: он может появиться позже.
+func (t *LogTail) open(tf *tailedFile) error {
+	info, err := os.Stat(tf.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	offset := info.Size()
+	if state, ok := t.saved[tf.Path]; ok {
+		offset = 0
+		if ino, ok := fileInode(info); ok && ino == state.Inode && state.Offset <= info.Size() {
+			offset = state.Offset
+		}
+	}
+	return tf.openAt(offset)
+}
+
+func (tf *tailedFile) openAt(offset int64) error {
+	f, err := os.Open(tf.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+
+	tf.f, tf.info = f, info
+	tf.offset, tf.lineStart = offset, offset
+	tf.partial, tf.skipping = nil, false
+	return nil
+}
+
+// read читает файл до конца и применяет правила к завершенным строкам.
+// Незавершенная последняя строка откладывается до следующего опроса.
+func (tf *tailedFile) read(counters map[string]int64, gauges map[string]float64) error {
+	r := bufio.NewReader(tf.f)
+	for {
+		chunk, err := r.ReadSlice('\n')
+		tf.offset += int64(len(chunk))
+
+		switch {
+		case tf.skipping:
+		case len(tf.partial)+len(chunk) > maxLogLine:
+			// Слишком длинная строка пропускается до ее конца.
+			tf.skipping = true
+			tf.partial = nil
+		default:
+			tf.partial = append(tf.partial, chunk...)
+		}
+
+		if len(chunk) > 0 && chunk[len(chunk)-1] == '\n' {
+			if !tf.skipping {
+				tf.match(bytes.TrimRight(tf.partial, "\r\n"), counters, gauges)
+			}
+			tf.partial = tf.partial[:0]
+			tf.skipping = false
+			tf.lineStart = tf.offset
+		}
+
+		switch {
+		case err == nil, errors.Is(err, bufio.ErrBufferFull):
+		case errors.Is(err, io.EOF):
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+func (tf *tailedFile) match(line []byte, counters map[string]int64, gauges map[string]float64) {
+	for _, rule := range tf.Rules {
+		groups := rule.re.FindSubmatch(line)
+		if groups == nil {
+			continue
+		}
+		if rule.Counter != "" {
+			counters[rule.Counter]++
+		}
+		for i, name := range rule.re.SubexpNames() {
+			if name == "" || groups[i] == nil {
+				continue
+			}
+			if v, err := strconv.ParseFloat(string(groups[i]), 64); err == nil {
+				gauges[name] = v
+			}
+		}
+	}
+}
+
+// saveState атомарно записывает позиции в stateFile: данные синхронизируются на диск
+// до переименования, а после него синхронизируется каталог, чтобы после сбоя питания
+// не остаться с пустым или прежним файлом позиций.
+func (t *LogTail) saveState(states map[string]logState) error {
+	if t.stateFile == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(t.stateFile)
+	tmp, err := os.CreateTemp(dir, filepath.Base(t.stateFile)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), t.stateFile); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir синхронизирует каталог, чтобы переименование файла сохранилось на диске.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
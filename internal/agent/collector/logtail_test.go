@@ -0,0 +1,126 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func appendLog(t *testing.T, path, text string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// collectLog опрашивает файлы, отдает накопленные метрики и сохраняет позиции,
+// как после успешной отправки.
+func collectLog(t *testing.T, c *LogTail) map[string]float64 {
+	t.Helper()
+	if _, err := c.Collect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	metrics := c.Flush()
+	if err := c.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[string]float64)
+	for _, m := range metrics {
+		if m.Delta != nil {
+			result[m.ID] = float64(*m.Delta)
+		} else {
+			result[m.ID] = *m.Value
+		}
+	}
+	return result
+}
+
+func TestLogTail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	state := filepath.Join(dir, "state.json")
+	appendLog(t, path, "ERROR old entry\n")
+
+	files := []LogFile{{Path: path, Rules: []LogRule{
+		{Counter: "AppErrors", Pattern: `ERROR`},
+		{Pattern: `latency=(?P<AppLatencyMs>\d+)ms`},
+	}}}
+	newTail := func() *LogTail {
+		c, err := NewLogTail(files, state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	c := newTail()
+	// Первый запуск начинает с конца файла.
+	if got := collectLog(t, c); len(got) != 0 {
+		t.Errorf("existing lines must be skipped on first start, got %v", got)
+	}
+
+	appendLog(t, path, "ERROR a\nok latency=15ms\nERROR b latency=40ms\nERROR partial")
+	got := collectLog(t, c)
+	if got["AppErrors"] != 2 || got["AppLatencyMs"] != 40 {
+		t.Errorf("unexpected metrics: %v", got)
+	}
+
+	// Перезапуск: незавершенная строка дочитывается, учтенные строки не повторяются.
+	c.Close()
+	c = newTail()
+	appendLog(t, path, " line\n")
+	if got := collectLog(t, c); got["AppErrors"] != 1 {
+		t.Errorf("expected 1 error after restart, got %v", got)
+	}
+
+	// Без Commit позиции не сохраняются: после перезапуска строки читаются заново.
+	appendLog(t, path, "ERROR unsent\n")
+	if _, err := c.Collect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	c.Flush()
+	c.Close()
+	c = newTail()
+	if got := collectLog(t, c); got["AppErrors"] != 1 {
+		t.Errorf("undelivered line must be read again after restart, got %v", got)
+	}
+
+	// Усечение.
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendLog(t, path, "ERROR c\n")
+	if got := collectLog(t, c); got["AppErrors"] != 1 {
+		t.Errorf("expected 1 error after truncation, got %v", got)
+	}
+
+	// Ротация: старый файл дочитывается, новый читается с начала.
+	appendLog(t, path, "ERROR d\n")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendLog(t, path, "ERROR e\nERROR f\n")
+	if got := collectLog(t, c); got["AppErrors"] != 3 {
+		t.Errorf("expected 3 errors across rotation, got %v", got)
+	}
+	c.Close()
+}
+
+func TestNewLogTailInvalid(t *testing.T) {
+	for _, files := range [][]LogFile{
+		nil,
+		{{Path: "a.log"}},
+		{{Path: "a.log", Rules: []LogRule{{Counter: "X", Pattern: "("}}}},
+		{{Path: "a.log", Rules: []LogRule{{Pattern: "no groups"}}}},
+	} {
+		if _, err := NewLogTail(files, ""); err == nil {
+			t.Errorf("expected error for %+v", files)
+		}
+	}
+}
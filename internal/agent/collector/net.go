@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/levinOo/go-metrics-project/internal/ingest"
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/shirou/gopsutil/net"
+)
+
+// Network сообщает приращения счетчиков сетевых интерфейсов: NetBytesRecv,
+// NetBytesSent, NetErrIn, NetErrOut, NetDropIn и NetDropOut с меткой interface.
+// Первый опрос только запоминает значения счетчиков.
+type Network struct {
+	filter Filter
+
+	mu     sync.Mutex
+	deltas *deltas
+
+	ioCounters func(ctx context.Context, pernic bool) ([]net.IOCountersStat, error)
+}
+
+// NewNetwork создает коллектор net для интерфейсов, прошедших filter.
+func NewNetwork(filter Filter) *Network {
+	return &Network{
+		filter:     filter,
+		deltas:     newDeltas(),
+		ioCounters: net.IOCountersWithContext,
+	}
+}
+
+// Name возвращает "net".
+func (n *Network) Name() string {
+	return "net"
+}
+
+// Collect читает счетчики интерфейсов и возвращает их приращения с прошлого опроса.
+func (n *Network) Collect(ctx context.Context) ([]models.Metrics, error) {
+	counters, err := n.ioCounters(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("network: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.deltas.begin()
+	defer n.deltas.end()
+
+	var metrics []models.Metrics
+	for _, c := range counters {
+		if !n.filter.Match(c.Name) {
+			continue
+		}
+
+		tags := []ingest.Tag{{Key: "interface", Value: c.Name}}
+		for _, v := range []struct {
+			base  string
+			value uint64
+		}{
+			{"NetBytesRecv", c.BytesRecv},
+			{"NetBytesSent", c.BytesSent},
+			{"NetErrIn", c.Errin},
+			{"NetErrOut", c.Errout},
+			{"NetDropIn", c.Dropin},
+			{"NetDropOut", c.Dropout},
+		} {
+			id := ingest.MetricName(v.base, tags)
+			if delta, ok := n.deltas.add(id, v.value); ok {
+				metrics = append(metrics, Counter(id, delta))
+			}
+		}
+	}
+	return metrics, nil
+}
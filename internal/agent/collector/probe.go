@@ -0,0 +1,250 @@
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/ingest"
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+// ProbeTarget — проверяемая точка: адрес и имя, под которым сообщаются метрики.
+type ProbeTarget struct {
+	Name string
+	URL  *url.URL
+}
+
+// ParseProbeTargets разбирает список целей через запятую. Цель — URL со схемой
+// http, https, tcp или tls, перед которым можно указать имя: "api=https://host/health".
+// Без имени используется хост и путь URL.
+func ParseProbeTargets(s string) ([]ProbeTarget, error) {
+	var targets []ProbeTarget
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var name string
+		if i := strings.Index(part, "="); i > 0 && !strings.Contains(part[:i], "://") {
+			name, part = part[:i], part[i+1:]
+		}
+
+		u, err := url.Parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("probe target %q: %w", part, err)
+		}
+		switch u.Scheme {
+		case "http", "https":
+		case "tcp", "tls":
+			if u.Port() == "" {
+				return nil, fmt.Errorf("probe target %q: port is required", part)
+			}
+		default:
+			return nil, fmt.Errorf("probe target %q: unsupported scheme %q", part, u.Scheme)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("probe target %q: host is required", part)
+		}
+
+		if name == "" {
+			name = u.Host + strings.TrimSuffix(u.Path, "/")
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("probe target %q listed twice", name)
+		}
+		seen[name] = true
+		targets = append(targets, ProbeTarget{Name: name, URL: u})
+	}
+	return targets, nil
+}
+
+// Probe проверяет доступность HTTP и TCP точек. Для каждой цели с меткой target
+// сообщаются gauge ProbeUp (1 или 0) и ProbeLatencyMs, для HTTP — ProbeStatusCode,
+// для https и tls — ProbeCertDaysLeft, число дней до истечения сертификата.
+// ProbeCertDaysLeft сообщается и для сертификата, не прошедшего проверку:
+// у истекшего сертификата значение отрицательное.
+//
+// HTTP-цель считается доступной при коде ответа меньше 400. Причина неудачной
+// проверки записывается в журнал.
+type Probe struct {
+	targets []ProbeTarget
+	timeout time.Duration
+
+	// tlsConfig — базовые параметры TLS, например корневые сертификаты.
+	tlsConfig *tls.Config
+}
+
+// NewProbe создает коллектор probe. Каждая проверка длится не дольше timeout.
+func NewProbe(targets []ProbeTarget, timeout time.Duration) (*Probe, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("no probe targets configured")
+	}
+	if timeout <= 0 {
+		return nil, errors.New("timeout must be positive")
+	}
+	return &Probe{targets: targets, timeout: timeout}, nil
+}
+
+// Name возвращает "probe".
+func (p *Probe) Name() string {
+	return "probe"
+}
+
+// Collect проверяет все цели параллельно. Недоступность цели не ошибка
+// коллектора, а значение ProbeUp 0.
+func (p *Probe) Collect(ctx context.Context) ([]models.Metrics, error) {
+	results := make([][]models.Metrics, len(p.targets))
+
+	var wg sync.WaitGroup
+	for i, target := range p.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = p.probe(ctx, target)
+		}()
+	}
+	wg.Wait()
+
+	var metrics []models.Metrics
+	for _, r := range results {
+		metrics = append(metrics, r...)
+	}
+	return metrics, nil
+}
+
+func (p *Probe) probe(ctx context.Context, target ProbeTarget) []models.Metrics {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	tags := []ingest.Tag{{Key: "target", Value: target.Name}}
+	start := time.Now()
+
+	var status int
+	var cert atomic.Pointer[x509.Certificate]
+	var err error
+
+	switch target.URL.Scheme {
+	case "http", "https":
+		status, err = p.probeHTTP(ctx, target.URL, &cert)
+		if err == nil && status >= http.StatusBadRequest {
+			err = fmt.Errorf("status %d", status)
+		}
+	default:
+		err = p.probeTCP(ctx, target.URL, &cert)
+	}
+
+	up := err == nil
+	if err != nil {
+		log.Printf("probe %s: %v", target.Name, err)
+	}
+
+	var upValue float64
+	if up {
+		upValue = 1
+	}
+	metrics := []models.Metrics{
+		Gauge(ingest.MetricName("ProbeUp", tags), upValue),
+		Gauge(ingest.MetricName("ProbeLatencyMs", tags), float64(time.Since(start).Microseconds())/1000),
+	}
+	if status > 0 {
+		metrics = append(metrics, Gauge(ingest.MetricName("ProbeStatusCode", tags), float64(status)))
+	}
+	if c := cert.Load(); c != nil {
+		metrics = append(metrics, Gauge(ingest.MetricName("ProbeCertDaysLeft", tags), time.Until(c.NotAfter).Hours()/24))
+	}
+	return metrics
+}
+
+// probeHTTP выполняет GET и возвращает код ответа. Сертификат сервера
+// записывается в cert, даже если он не прошел проверку.
+func (p *Probe) probeHTTP(ctx context.Context, u *url.URL, cert *atomic.Pointer[x509.Certificate]) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "go-metrics-agent-probe")
+
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		DisableKeepAlives: true,
+		TLSClientConfig:   p.tlsClientConfig("", cert),
+	}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return resp.StatusCode, nil
+}
+
+// probeTCP устанавливает соединение, для схемы tls — с рукопожатием TLS.
+// Сертификат сервера записывается в cert, даже если он не прошел проверку.
+func (p *Probe) probeTCP(ctx context.Context, u *url.URL, cert *atomic.Pointer[x509.Certificate]) error {
+	var d net.Dialer
+	if u.Scheme == "tcp" {
+		conn, err := d.DialContext(ctx, "tcp", u.Host)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	td := tls.Dialer{NetDialer: &d, Config: p.tlsClientConfig(u.Hostname(), cert)}
+	conn, err := td.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// tlsClientConfig возвращает параметры TLS, которые сохраняют сертификат сервера
+// в cert до его проверки. Проверка выполняется в VerifyConnection так же, как
+// стандартная, поэтому недействительный сертификат по-прежнему прерывает
+// рукопожатие, но его срок действия известен.
+func (p *Probe) tlsClientConfig(serverName string, cert *atomic.Pointer[x509.Certificate]) *tls.Config {
+	cfg := &tls.Config{}
+	if p.tlsConfig != nil {
+		cfg = p.tlsConfig.Clone()
+	}
+	if serverName != "" {
+		cfg.ServerName = serverName
+	}
+
+	roots := cfg.RootCAs
+	cfg.InsecureSkipVerify = true
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("server sent no certificate")
+		}
+		cert.Store(cs.PeerCertificates[0])
+
+		opts := x509.VerifyOptions{
+			DNSName:       cs.ServerName,
+			Roots:         roots,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, c := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(c)
+		}
+		_, err := cs.PeerCertificates[0].Verify(opts)
+		return err
+	}
+	return cfg
+}
@@ -0,0 +1,151 @@
+package collector
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseProbeTargets(t *testing.T) {
+	targets, err := ParseProbeTargets("api=https://example.com/health/, tcp://db:5432")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 || targets[0].Name != "api" || targets[1].Name != "db:5432" {
+		t.Errorf("unexpected targets: %+v", targets)
+	}
+
+	for _, s := range []string{"ftp://host", "tcp://db", "https://", "a=http://x,a=http://y"} {
+		if _, err := ParseProbeTargets(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	host := (&url.URL{Host: srv.Listener.Addr().String()}).Host
+	targets, err := ParseProbeTargets("ok=" + srv.URL + "/health,broken=" + srv.URL + "/broken,tls=tls://" + host + ",down=tcp://" + closedAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewProbe(targets, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.tlsConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig
+
+	metrics, err := p.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := byID(metrics)
+
+	for id, want := range map[string]float64{
+		"ProbeUp.target:ok":             1,
+		"ProbeStatusCode.target:ok":     200,
+		"ProbeUp.target:broken":         0,
+		"ProbeStatusCode.target:broken": 503,
+		"ProbeUp.target:tls":            1,
+		"ProbeUp.target:down":           0,
+	} {
+		if m, ok := got[id]; !ok || *m.Value != want {
+			t.Errorf("%s: expected %v, got %+v", id, want, m)
+		}
+	}
+	for _, id := range []string{"ProbeCertDaysLeft.target:ok", "ProbeCertDaysLeft.target:tls"} {
+		if m, ok := got[id]; !ok || *m.Value <= 0 {
+			t.Errorf("%s: expected positive days left, got %+v", id, m)
+		}
+	}
+	if _, ok := got["ProbeStatusCode.target:down"]; ok {
+		t.Error("TCP target must not report status code")
+	}
+	if _, ok := got["ProbeLatencyMs.target:down"]; !ok {
+		t.Error("expected latency for down target")
+	}
+}
+
+func TestProbeExpiredCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "expired"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-72 * time.Hour),
+		NotAfter:     time.Now().Add(-48 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	host := srv.Listener.Addr().String()
+	targets, err := ParseProbeTargets("https=" + srv.URL + ",tls=tls://" + host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewProbe(targets, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	p.tlsConfig = &tls.Config{RootCAs: roots}
+
+	metrics, err := p.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := byID(metrics)
+	for _, name := range []string{"https", "tls"} {
+		if m := got["ProbeUp.target:"+name]; m.Value == nil || *m.Value != 0 {
+			t.Errorf("%s: expired certificate must fail the probe, got %+v", name, m)
+		}
+		if m := got["ProbeCertDaysLeft.target:"+name]; m.Value == nil || *m.Value > -1 {
+			t.Errorf("%s: expected negative days left, got %+v", name, m)
+		}
+	}
+}
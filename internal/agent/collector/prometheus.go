@@ -0,0 +1,264 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/ingest"
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/validation"
+)
+
+// maxScrapeBody ограничивает размер ответа цели.
+const maxScrapeBody = 10 << 20
+
+// Способы указать цель в именах метрик коллектора prometheus.
+const (
+	// ScrapeLabel добавляет к имени метку target: "http_requests_total.target:api".
+	ScrapeLabel = "label"
+	// ScrapePrefix добавляет имя цели префиксом: "api_http_requests_total".
+	ScrapePrefix = "prefix"
+)
+
+// ScrapeTarget — опрашиваемая цель Prometheus.
+type ScrapeTarget struct {
+	Name string
+	URL  string
+}
+
+// ParseScrapeTargets разбирает список целей через запятую вида "[имя=]URL".
+// Без имени используется хост и порт URL. Недопустимые в имени символы
+// заменяются на "_", а имя, начинающееся с цифры, дополняется "_" в начале,
+// чтобы в режиме ScrapePrefix имена метрик начинались с буквы или "_".
+func ParseScrapeTargets(s string) ([]ScrapeTarget, error) {
+	var targets []ScrapeTarget
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var name string
+		if i := strings.Index(part, "="); i > 0 && !strings.Contains(part[:i], "://") {
+			name, part = part[:i], part[i+1:]
+		}
+
+		u, err := url.Parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("scrape target %q: %w", part, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("scrape target %q: expected http(s) URL", part)
+		}
+
+		if name == "" {
+			name = u.Host
+		}
+		name = strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+				return r
+			}
+			return '_'
+		}, name)
+		if name[0] >= '0' && name[0] <= '9' {
+			name = "_" + name
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("scrape target %q listed twice", name)
+		}
+		seen[name] = true
+		targets = append(targets, ScrapeTarget{Name: name, URL: u.String()})
+	}
+	return targets, nil
+}
+
+// promCounter — состояние накопительного счетчика цели.
+type promCounter struct {
+	prev  float64
+	carry float64
+	seen  bool
+}
+
+// Prometheus опрашивает цели в формате экспозиции Prometheus. Gauge и
+// нетипизированные отсчеты сообщаются как gauge, counter — как приращения с
+// прошлого опроса; дробная часть приращения переносится на следующий опрос.
+// Гистограммы и сводки пропускаются. Для каждой цели сообщается gauge ScrapeUp.
+//
+// Первый опрос счетчика только запоминает его значение; уменьшение значения
+// считается сбросом счетчика.
+type Prometheus struct {
+	targets []ScrapeTarget
+	mode    string
+	timeout time.Duration
+	client  *http.Client
+
+	mu       sync.Mutex
+	counters map[string]map[string]*promCounter
+}
+
+// NewPrometheus создает коллектор prometheus. mode — ScrapeLabel или ScrapePrefix.
+func NewPrometheus(targets []ScrapeTarget, mode string, timeout time.Duration) (*Prometheus, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("no scrape targets configured")
+	}
+	if mode != ScrapeLabel && mode != ScrapePrefix {
+		return nil, fmt.Errorf("unknown scrape mode %q", mode)
+	}
+	if timeout <= 0 {
+		return nil, errors.New("timeout must be positive")
+	}
+	return &Prometheus{
+		targets:  targets,
+		mode:     mode,
+		timeout:  timeout,
+		client:   &http.Client{},
+		counters: make(map[string]map[string]*promCounter),
+	}, nil
+}
+
+// Name возвращает "prometheus".
+func (p *Prometheus) Name() string {
+	return "prometheus"
+}
+
+// Collect опрашивает цели параллельно. Недоступность цели возвращается
+// ошибкой вместе с метриками остальных целей.
+func (p *Prometheus) Collect(ctx context.Context) ([]models.Metrics, error) {
+	results := make([][]ingest.PromSample, len(p.targets))
+	errs := make([]error, len(p.targets))
+
+	var wg sync.WaitGroup
+	for i, target := range p.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = p.scrape(ctx, target)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("%s: %w", target.Name, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var metrics []models.Metrics
+	for i, target := range p.targets {
+		up := 1.0
+		if errs[i] != nil {
+			up = 0
+		}
+		metrics = append(metrics, Gauge(ingest.MetricName("ScrapeUp", []ingest.Tag{{Key: "target", Value: target.Name}}), up))
+		if errs[i] == nil {
+			metrics, errs[i] = p.convert(metrics, target, results[i])
+		}
+	}
+	return metrics, errors.Join(errs...)
+}
+
+func (p *Prometheus) scrape(ctx context.Context, target ScrapeTarget) ([]ingest.PromSample, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain;version=0.0.4;q=1,*/*;q=0.1")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("target returned status %d", resp.StatusCode)
+	}
+	return ingest.ParsePrometheus(io.LimitReader(resp.Body, maxScrapeBody))
+}
+
+// convert преобразует отсчеты цели в метрики. Отсчеты, имена которых не проходят
+// проверку сервера (например, слишком длинные), пропускаются; их количество и
+// первая причина возвращаются ошибкой. Вызывается под p.mu.
+func (p *Prometheus) convert(metrics []models.Metrics, target ScrapeTarget, samples []ingest.PromSample) ([]models.Metrics, error) {
+	prev := p.counters[target.Name]
+	next := make(map[string]*promCounter)
+
+	var rejected int
+	var firstErr error
+	for _, s := range samples {
+		if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+			continue
+		}
+
+		id := p.metricName(target, s)
+		if err := validation.Default().Name(id); err != nil {
+			if rejected == 0 {
+				firstErr = fmt.Errorf("metric %q: %w", id, err)
+			}
+			rejected++
+			continue
+		}
+		switch s.Type {
+		case ingest.PromGauge, ingest.PromUntyped:
+			metrics = append(metrics, Gauge(id, s.Value))
+		case ingest.PromCounter:
+			if strings.HasSuffix(s.Name, "_created") {
+				continue
+			}
+			state, ok := prev[id]
+			if !ok {
+				state = &promCounter{}
+			}
+			next[id] = state
+			if delta, ok := state.add(s.Value); ok {
+				metrics = append(metrics, Counter(id, delta))
+			}
+		}
+	}
+
+	p.counters[target.Name] = next
+	if rejected > 0 {
+		return metrics, fmt.Errorf("%s: %d samples rejected, first: %w", target.Name, rejected, firstErr)
+	}
+	return metrics, nil
+}
+
+func (p *Prometheus) metricName(target ScrapeTarget, s ingest.PromSample) string {
+	if p.mode == ScrapePrefix {
+		return ingest.MetricName(target.Name+"_"+s.Name, s.Labels)
+	}
+	tags := append([]ingest.Tag{{Key: "target", Value: target.Name}}, s.Labels...)
+	return ingest.MetricName(s.Name, tags)
+}
+
+// add возвращает целое приращение счетчика и false для первого наблюдения.
+func (c *promCounter) add(value float64) (int64, bool) {
+	if !c.seen {
+		c.prev, c.seen = value, true
+		return 0, false
+	}
+
+	delta := value - c.prev
+	if value < c.prev {
+		delta, c.carry = value, 0
+	}
+	c.prev = value
+
+	total := delta + c.carry
+	whole := math.Trunc(total)
+	c.carry = total - whole
+	return int64(whole), true
+}
@@ -0,0 +1,103 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/validation"
+)
+
+func TestPrometheus(t *testing.T) {
+	requests := 0.0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "# TYPE http_requests_total counter\nhttp_requests_total{code=\"200\"} %g\n", requests)
+		fmt.Fprint(w, "# TYPE queue_depth gauge\nqueue_depth 7\n")
+		fmt.Fprint(w, "# TYPE latency histogram\nlatency_bucket{le=\"+Inf\"} 3\nlatency_count 3\n")
+	}))
+	defer srv.Close()
+
+	targets, err := ParseScrapeTargets("app=" + srv.URL + "/metrics,down=http://127.0.0.1:1/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []string{ScrapeLabel, ScrapePrefix} {
+		t.Run(mode, func(t *testing.T) {
+			p, err := NewPrometheus(targets, mode, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			counter, gauge := "http_requests_total.target:app.code:200", "queue_depth.target:app"
+			if mode == ScrapePrefix {
+				counter, gauge = "app_http_requests_total.code:200", "app_queue_depth"
+			}
+
+			requests = 10
+			metrics, err := p.Collect(context.Background())
+			if err == nil {
+				t.Error("expected error for unreachable target")
+			}
+			got := byID(metrics)
+			if len(got) != 3 {
+				t.Errorf("expected ScrapeUp for both targets and gauge, got %+v", metrics)
+			}
+			if m := got[gauge]; m.Value == nil || *m.Value != 7 {
+				t.Errorf("unexpected %s: %+v", gauge, m)
+			}
+			if m := got["ScrapeUp.target:down"]; m.Value == nil || *m.Value != 0 {
+				t.Errorf("unexpected ScrapeUp for down target: %+v", m)
+			}
+
+			for _, step := range []struct {
+				value float64
+				want  int64
+			}{{12.5, 2}, {13, 1}, {4, 4}} {
+				requests = step.value
+				metrics, _ = p.Collect(context.Background())
+				if m := byID(metrics)[counter]; m.Delta == nil || *m.Delta != step.want {
+					t.Errorf("value %v: expected delta %d, got %+v", step.value, step.want, m)
+				}
+			}
+		})
+	}
+}
+
+func TestPrometheusNames(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "up 1\n%s 2\n", long)
+	}))
+	defer srv.Close()
+
+	targets, err := ParseScrapeTargets(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := targets[0].Name; !strings.HasPrefix(name, "_127_0_0_1_") {
+		t.Errorf("default name must not start with a digit, got %q", name)
+	}
+
+	p, err := NewPrometheus(targets, ScrapePrefix, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics, err := p.Collect(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "1 samples rejected") {
+		t.Errorf("expected rejected sample error, got %v", err)
+	}
+	got := byID(metrics)
+	if _, ok := got[targets[0].Name+"_up"]; !ok || len(got) != 2 {
+		t.Errorf("expected ScrapeUp and prefixed up, got %+v", metrics)
+	}
+	for _, m := range metrics {
+		if err := validation.Default().Metric(m); err != nil {
+			t.Errorf("%s: %v", m.ID, err)
+		}
+	}
+}
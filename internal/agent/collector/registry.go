@@ -0,0 +1,348 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/validation"
+)
+
+// Spec описывает включенный коллектор: имя и интервал опроса.
+type Spec struct {
+	Name     string
+	Interval time.Duration
+}
+
+// ParseSpecs разбирает список коллекторов вида "runtime,system:30s". Для
+// коллекторов без интервала используется defaultInterval.
+func ParseSpecs(s string, defaultInterval time.Duration) ([]Spec, error) {
+	var specs []Spec
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		spec := Spec{Name: part, Interval: defaultInterval}
+		if name, interval, ok := strings.Cut(part, ":"); ok {
+			d, err := time.ParseDuration(interval)
+			if err != nil {
+				return nil, fmt.Errorf("collector %q: invalid interval: %w", name, err)
+			}
+			spec = Spec{Name: name, Interval: d}
+		}
+		if spec.Interval <= 0 {
+			return nil, fmt.Errorf("collector %q: interval must be positive", spec.Name)
+		}
+		if seen[spec.Name] {
+			return nil, fmt.Errorf("collector %q listed twice", spec.Name)
+		}
+		seen[spec.Name] = true
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// ErrDuplicate возвращается Register, если коллектор с таким именем уже зарегистрирован.
+var ErrDuplicate = errors.New("collector already registered")
+
+type entry struct {
+	collector Collector
+	interval  time.Duration
+}
+
+// Registry опрашивает зарегистрированные коллекторы и накапливает их метрики
+// до отправки. Gauge хранят значения из последнего опроса коллектора: gauge,
+// которого коллектор больше не сообщает (удаленный диск, остановленный процесс),
+// перестает отправляться. Приращения counter суммируются и сбрасываются при Snapshot.
+type Registry struct {
+	mu      sync.Mutex
+	entries []entry
+	// gauges хранит gauge последнего опроса по имени коллектора.
+	gauges   map[string]map[string]float64
+	counters map[string]int64
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewRegistry создает пустой реестр.
+func NewRegistry() *Registry {
+	return &Registry{
+		gauges:   make(map[string]map[string]float64),
+		counters: make(map[string]int64),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Register добавляет коллектор с интервалом опроса interval. Регистрировать
+// коллекторы нужно до Start.
+func (r *Registry) Register(c Collector, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("collector %q: interval must be positive", c.Name())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, e := range r.entries {
+		if e.collector.Name() == c.Name() {
+			return fmt.Errorf("%w: %s", ErrDuplicate, c.Name())
+		}
+	}
+	r.entries = append(r.entries, entry{collector: c, interval: interval})
+	return nil
+}
+
+// Configure создает и регистрирует коллекторы из specs с помощью factories.
+func (r *Registry) Configure(specs []Spec, factories map[string]Factory) error {
+	for _, spec := range specs {
+		factory, ok := factories[spec.Name]
+		if !ok {
+			return fmt.Errorf("unknown collector %q, available: %s", spec.Name, strings.Join(factoryNames(factories), ", "))
+		}
+
+		c, err := factory()
+		if err != nil {
+			return fmt.Errorf("collector %q: %w", spec.Name, err)
+		}
+		if err := r.Register(c, spec.Interval); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Names возвращает имена зарегистрированных коллекторов в порядке регистрации.
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.entries))
+	for _, e := range r.entries {
+		names = append(names, e.collector.Name())
+	}
+	return names
+}
+
+// Start запускает опрос каждого коллектора в отдельной горутине. Первый опрос
+// выполняется сразу.
+func (r *Registry) Start() {
+	r.mu.Lock()
+	entries := append([]entry(nil), r.entries...)
+	r.mu.Unlock()
+
+	for _, e := range entries {
+		r.wg.Add(1)
+		go r.run(e)
+	}
+}
+
+// Stop останавливает опрос, дожидается завершения текущих вызовов Collect и
+// закрывает коллекторы, реализующие io.Closer.
+func (r *Registry) Stop() {
+	close(r.stopCh)
+	r.wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, e := range r.entries {
+		if c, ok := e.collector.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				log.Printf("collector %s: close: %v", e.collector.Name(), err)
+			}
+		}
+	}
+}
+
+func (r *Registry) run(e entry) {
+	defer r.wg.Done()
+
+	r.collect(e)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.collect(e)
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// CollectOnce синхронно опрашивает все коллекторы по одному разу.
+func (r *Registry) CollectOnce() {
+	r.mu.Lock()
+	entries := append([]entry(nil), r.entries...)
+	r.mu.Unlock()
+
+	for _, e := range entries {
+		r.collect(e)
+	}
+}
+
+func (r *Registry) collect(e entry) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.interval)
+	defer cancel()
+
+	metrics, err := safeCollect(ctx, e.collector)
+	if err != nil {
+		log.Printf("collector %s: %v", e.collector.Name(), err)
+	}
+	r.record(e.collector.Name(), metrics)
+}
+
+// safeCollect вызывает Collect и превращает панику коллектора в ошибку.
+func safeCollect(ctx context.Context, c Collector) (metrics []models.Metrics, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			metrics, err = nil, fmt.Errorf("collector panicked: %v", p)
+		}
+	}()
+	return c.Collect(ctx)
+}
+
+// record заменяет gauge коллектора source метриками его последнего опроса и
+// добавляет приращения counter.
+func (r *Registry) record(source string, metrics []models.Metrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	gauges := make(map[string]float64)
+	r.add(source, metrics, gauges)
+	r.gauges[source] = gauges
+}
+
+// add записывает gauge из metrics в gauges, а приращения counter — в реестр.
+// Вызывается с захваченным r.mu.
+func (r *Registry) add(source string, metrics []models.Metrics, gauges map[string]float64) {
+	for _, m := range metrics {
+		switch {
+		case m.MType == models.Gauge && m.Value != nil:
+			gauges[m.ID] = *m.Value
+		case m.MType == models.Counter && m.Delta != nil:
+			sum, err := validation.AddCounter(r.counters[m.ID], *m.Delta)
+			if err != nil {
+				log.Printf("collector %s: counter %s: %v", source, m.ID, err)
+				continue
+			}
+			r.counters[m.ID] = sum
+		default:
+			log.Printf("collector %s: skipping invalid metric %q of type %q", source, m.ID, m.MType)
+		}
+	}
+}
+
+// Snapshot возвращает накопленные метрики, отсортированные по имени, вместе с
+// данными коллекторов Flusher за интервал отправки. Приращения counter сбрасываются; если отправка
+// не удалась, их нужно вернуть через Restore.
+func (r *Registry) Snapshot() []models.Metrics {
+	r.mu.Lock()
+	entries := append([]entry(nil), r.entries...)
+	r.mu.Unlock()
+
+	// Gauge коллекторов Flusher относятся к интервалу отправки и попадают только
+	// в этот снимок, не сохраняясь в реестре.
+	flushed := make(map[string]map[string]float64)
+	for _, e := range entries {
+		if f, ok := e.collector.(Flusher); ok {
+			metrics := f.Flush()
+
+			r.mu.Lock()
+			gauges := make(map[string]float64)
+			r.add(e.collector.Name(), metrics, gauges)
+			flushed[e.collector.Name()] = gauges
+			r.mu.Unlock()
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Одноименные gauge разных коллекторов: побеждает зарегистрированный позже.
+	gauges := make(map[string]float64)
+	for _, e := range entries {
+		for name, value := range r.gauges[e.collector.Name()] {
+			gauges[name] = value
+		}
+		for name, value := range flushed[e.collector.Name()] {
+			gauges[name] = value
+		}
+	}
+
+	metrics := make([]models.Metrics, 0, len(gauges)+len(r.counters))
+	for name, value := range gauges {
+		metrics = append(metrics, Gauge(name, value))
+	}
+	for name, delta := range r.counters {
+		metrics = append(metrics, Counter(name, delta))
+	}
+	r.counters = make(map[string]int64)
+
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].ID != metrics[j].ID {
+			return metrics[i].ID < metrics[j].ID
+		}
+		return metrics[i].MType < metrics[j].MType
+	})
+	return metrics
+}
+
+// Commit сохраняет состояние коллекторов Committer на момент последнего Snapshot.
+// Вызывается после успешной отправки этого снимка, до следующего Snapshot.
+func (r *Registry) Commit() error {
+	r.mu.Lock()
+	entries := append([]entry(nil), r.entries...)
+	r.mu.Unlock()
+
+	var errs []error
+	for _, e := range entries {
+		if c, ok := e.collector.(Committer); ok {
+			if err := c.Commit(); err != nil {
+				errs = append(errs, fmt.Errorf("collector %s: %w", e.collector.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Restore возвращает приращения counter из неотправленного снимка.
+func (r *Registry) Restore(metrics []models.Metrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, m := range metrics {
+		if m.MType != models.Counter || m.Delta == nil {
+			continue
+		}
+		sum, err := validation.AddCounter(r.counters[m.ID], *m.Delta)
+		if err != nil {
+			log.Printf("restore counter %s: %v", m.ID, err)
+			continue
+		}
+		r.counters[m.ID] = sum
+	}
+}
+
+func factoryNames(factories map[string]Factory) []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,165 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+type funcCollector struct {
+	name    string
+	collect func(ctx context.Context) ([]models.Metrics, error)
+}
+
+func (c funcCollector) Name() string { return c.name }
+
+func (c funcCollector) Collect(ctx context.Context) ([]models.Metrics, error) {
+	return c.collect(ctx)
+}
+
+func TestParseSpecs(t *testing.T) {
+	specs, err := ParseSpecs(" runtime, poll:5s ,", 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Spec{{"runtime", 2 * time.Second}, {"poll", 5 * time.Second}}
+	if len(specs) != len(want) {
+		t.Fatalf("expected %v, got %v", want, specs)
+	}
+	for i := range want {
+		if specs[i] != want[i] {
+			t.Errorf("spec %d: expected %v, got %v", i, want[i], specs[i])
+		}
+	}
+
+	for _, s := range []string{"poll:abc", "poll:0s", "poll,poll"} {
+		if _, err := ParseSpecs(s, time.Second); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestRegistryIsolation(t *testing.T) {
+	r := NewRegistry()
+
+	collectors := []Collector{
+		NewPoll(),
+		funcCollector{"panics", func(context.Context) ([]models.Metrics, error) {
+			var m map[string]int
+			m["x"]++
+			return nil, nil
+		}},
+		funcCollector{"partial", func(context.Context) ([]models.Metrics, error) {
+			return []models.Metrics{Gauge("Partial", 1)}, errors.New("device unavailable")
+		}},
+	}
+	for _, c := range collectors {
+		if err := r.Register(c, time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Register(NewPoll(), time.Second); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("expected ErrDuplicate, got %v", err)
+	}
+
+	r.CollectOnce()
+	r.CollectOnce()
+
+	got := map[string]models.Metrics{}
+	for _, m := range r.Snapshot() {
+		got[m.ID] = m
+	}
+	if m, ok := got["PollCount"]; !ok || *m.Delta != 2 {
+		t.Errorf("expected PollCount 2, got %+v", m)
+	}
+	if _, ok := got["RandomValue"]; !ok {
+		t.Error("expected RandomValue")
+	}
+	if _, ok := got["Partial"]; !ok {
+		t.Error("expected partial result of failed collector")
+	}
+
+	// Приращения counter сбрасываются при снимке, gauge сохраняются.
+	snapshot := r.Snapshot()
+	for _, m := range snapshot {
+		if m.MType == models.Counter {
+			t.Errorf("counter %s not drained", m.ID)
+		}
+	}
+
+	r.Restore([]models.Metrics{Counter("PollCount", 3)})
+	r.CollectOnce()
+	for _, m := range r.Snapshot() {
+		if m.ID == "PollCount" && *m.Delta != 4 {
+			t.Errorf("expected restored PollCount 4, got %d", *m.Delta)
+		}
+	}
+}
+
+func TestRegistryEvictsUnreportedGauges(t *testing.T) {
+	r := NewRegistry()
+	disks := []string{"sda", "sdb"}
+	if err := r.Register(funcCollector{"disk", func(context.Context) ([]models.Metrics, error) {
+		var metrics []models.Metrics
+		for _, d := range disks {
+			metrics = append(metrics, Gauge("Free."+d, 1))
+		}
+		return metrics, nil
+	}}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	r.CollectOnce()
+	if got := byID(r.Snapshot()); len(got) != 2 {
+		t.Fatalf("expected 2 gauges, got %v", got)
+	}
+
+	disks = disks[:1]
+	r.CollectOnce()
+	got := byID(r.Snapshot())
+	if _, ok := got["Free.sdb"]; ok || len(got) != 1 {
+		t.Errorf("gauge of removed disk must be evicted, got %v", got)
+	}
+}
+
+func TestRegistryStart(t *testing.T) {
+	r := NewRegistry()
+	blocked := funcCollector{"blocked", func(ctx context.Context) ([]models.Metrics, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	if err := r.Register(blocked, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(NewRuntime(), 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Start()
+	defer r.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := byID(r.Snapshot())
+		if _, ok := got["HeapAlloc"]; ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("runtime collector did not report while another collector was blocked")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestConfigureUnknown(t *testing.T) {
+	r := NewRegistry()
+	err := r.Configure([]Spec{{"missing", time.Second}}, map[string]Factory{
+		"runtime": func() (Collector, error) { return NewRuntime(), nil },
+	})
+	if err == nil {
+		t.Fatal("expected error for unknown collector")
+	}
+}
@@ -0,0 +1,300 @@
+package collector
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"runtime/debug"
+	"runtime/metrics"
+	"strings"
+	"sync"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+// legacyGauges выражает имена метрик runtime.MemStats через суммы метрик
+// runtime/metrics. Метрики, которых нет в текущей версии Go, считаются нулевыми.
+var legacyGauges = []struct {
+	name string
+	keys []string
+}{
+	{"Alloc", []string{"/memory/classes/heap/objects:bytes"}},
+	{"BuckHashSys", []string{"/memory/classes/profiling/buckets:bytes"}},
+	{"Frees", []string{"/gc/heap/frees:objects", "/gc/heap/tiny/allocs:objects"}},
+	{"GCSys", []string{"/memory/classes/metadata/other:bytes"}},
+	{"HeapAlloc", []string{"/memory/classes/heap/objects:bytes"}},
+	{"HeapIdle", []string{"/memory/classes/heap/free:bytes", "/memory/classes/heap/released:bytes"}},
+	{"HeapInuse", []string{"/memory/classes/heap/objects:bytes", "/memory/classes/heap/unused:bytes"}},
+	{"HeapObjects", []string{"/gc/heap/objects:objects"}},
+	{"HeapReleased", []string{"/memory/classes/heap/released:bytes"}},
+	{"HeapSys", []string{
+		"/memory/classes/heap/objects:bytes", "/memory/classes/heap/unused:bytes",
+		"/memory/classes/heap/free:bytes", "/memory/classes/heap/released:bytes",
+	}},
+	{"Lookups", nil},
+	{"MCacheInuse", []string{"/memory/classes/metadata/mcache/inuse:bytes"}},
+	{"MCacheSys", []string{"/memory/classes/metadata/mcache/inuse:bytes", "/memory/classes/metadata/mcache/free:bytes"}},
+	{"MSpanInuse", []string{"/memory/classes/metadata/mspan/inuse:bytes"}},
+	{"MSpanSys", []string{"/memory/classes/metadata/mspan/inuse:bytes", "/memory/classes/metadata/mspan/free:bytes"}},
+	{"Mallocs", []string{"/gc/heap/allocs:objects", "/gc/heap/tiny/allocs:objects"}},
+	{"NextGC", []string{"/gc/heap/goal:bytes"}},
+	{"NumForcedGC", []string{"/gc/cycles/forced:gc-cycles"}},
+	{"NumGC", []string{"/gc/cycles/total:gc-cycles"}},
+	{"OtherSys", []string{"/memory/classes/other:bytes"}},
+	{"StackInuse", []string{"/memory/classes/heap/stacks:bytes"}},
+	{"StackSys", []string{"/memory/classes/heap/stacks:bytes", "/memory/classes/os-stacks:bytes"}},
+	{"Sys", []string{"/memory/classes/total:bytes"}},
+	{"TotalAlloc", []string{"/gc/heap/allocs:bytes"}},
+}
+
+// histogramPercentiles — процентили, сообщаемые для гистограмм runtime/metrics.
+var histogramPercentiles = []struct {
+	name string
+	p    float64
+}{
+	{"p50", 0.5},
+	{"p90", 0.9},
+	{"p99", 0.99},
+}
+
+// Runtime собирает метрики среды выполнения Go через runtime/metrics, не
+// останавливая программу, в отличие от runtime.ReadMemStats.
+//
+// Сообщаются прежние имена runtime.MemStats (Alloc, HeapInuse, NumGC, LastGC и
+// др.) и все метрики runtime/metrics под именами вида "go.gc.heap.goal:bytes":
+//   - накопительные целые метрики — counter с приращением с прошлого опроса;
+//   - остальные скалярные метрики — gauge;
+//   - гистограммы — counter name.count с числом событий и gauge name.p50,
+//     name.p90 и name.p99 по событиям за интервал отправки. Опросы накапливают
+//     счетчики корзин, а процентили вычисляются в Flush; если событий за интервал
+//     не было, процентили не отправляются.
+type Runtime struct {
+	mu          sync.Mutex
+	samples     []metrics.Sample
+	index       map[string]int
+	cumulative  map[string]bool
+	prevCounter map[string]uint64
+	prevHist    map[string][]uint64
+	pendingHist map[string]*histogramDelta
+}
+
+// histogramDelta — события гистограммы с прошлой отправки.
+type histogramDelta struct {
+	buckets []float64
+	counts  []uint64
+}
+
+// NewRuntime создает коллектор runtime.
+func NewRuntime() *Runtime {
+	descs := metrics.All()
+	r := &Runtime{
+		samples:     make([]metrics.Sample, len(descs)),
+		index:       make(map[string]int, len(descs)),
+		cumulative:  make(map[string]bool, len(descs)),
+		prevCounter: make(map[string]uint64),
+		prevHist:    make(map[string][]uint64),
+		pendingHist: make(map[string]*histogramDelta),
+	}
+	for i, d := range descs {
+		r.samples[i].Name = d.Name
+		r.index[d.Name] = i
+		r.cumulative[d.Name] = d.Cumulative
+	}
+	return r
+}
+
+// Name возвращает "runtime".
+func (r *Runtime) Name() string {
+	return "runtime"
+}
+
+// Collect читает все метрики runtime/metrics. События гистограмм накапливаются
+// до Flush.
+func (r *Runtime) Collect(context.Context) ([]models.Metrics, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	metrics.Read(r.samples)
+
+	result := r.legacy()
+	for _, s := range r.samples {
+		name := runtimeMetricName(s.Name, "")
+		switch s.Value.Kind() {
+		case metrics.KindUint64:
+			v := s.Value.Uint64()
+			if !r.cumulative[s.Name] {
+				result = append(result, Gauge(name, float64(v)))
+				continue
+			}
+			// Счетчики среды выполнения начинаются с нуля при старте процесса.
+			prev := r.prevCounter[s.Name]
+			r.prevCounter[s.Name] = v
+			if v >= prev {
+				result = append(result, Counter(name, int64(v-prev)))
+			}
+		case metrics.KindFloat64:
+			if v := s.Value.Float64(); !math.IsNaN(v) && !math.IsInf(v, 0) {
+				result = append(result, Gauge(name, v))
+			}
+		case metrics.KindFloat64Histogram:
+			r.accumulate(s.Name, s.Value.Float64Histogram())
+		}
+	}
+	return result, nil
+}
+
+// Flush возвращает число событий и процентили гистограмм с прошлой отправки.
+func (r *Runtime) Flush() []models.Metrics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []models.Metrics
+	for key, h := range r.pendingHist {
+		var total uint64
+		for _, c := range h.counts {
+			total += c
+		}
+
+		result = append(result, Counter(runtimeMetricName(key, "count"), int64(total)))
+		if total > 0 {
+			for _, q := range histogramPercentiles {
+				result = append(result, Gauge(runtimeMetricName(key, q.name), histogramPercentile(h.buckets, h.counts, total, q.p)))
+			}
+		}
+		clear(h.counts)
+	}
+	return result
+}
+
+// legacy вычисляет метрики с именами runtime.MemStats.
+func (r *Runtime) legacy() []models.Metrics {
+	result := make([]models.Metrics, 0, len(legacyGauges)+3)
+	for _, g := range legacyGauges {
+		var sum float64
+		for _, key := range g.keys {
+			sum += r.scalar(key)
+		}
+		result = append(result, Gauge(g.name, sum))
+	}
+
+	// Доля процессорного времени сборщика мусора с начала работы программы.
+	var gcFraction float64
+	if total := r.scalar("/cpu/classes/total:cpu-seconds"); total > 0 {
+		gcFraction = r.scalar("/cpu/classes/gc/total:cpu-seconds") / total
+	}
+
+	// Время последней сборки и суммарные паузы есть только в debug.GCStats,
+	// чтение которых не останавливает программу.
+	var gc debug.GCStats
+	debug.ReadGCStats(&gc)
+	var lastGC float64
+	if !gc.LastGC.IsZero() {
+		lastGC = float64(gc.LastGC.UnixNano())
+	}
+
+	return append(result,
+		Gauge("GCCPUFraction", gcFraction),
+		Gauge("LastGC", lastGC),
+		Gauge("PauseTotalNs", float64(gc.PauseTotal.Nanoseconds())),
+	)
+}
+
+// scalar возвращает значение скалярной метрики или 0, если ее нет.
+func (r *Runtime) scalar(key string) float64 {
+	i, ok := r.index[key]
+	if !ok {
+		return 0
+	}
+	switch v := r.samples[i].Value; v.Kind() {
+	case metrics.KindUint64:
+		return float64(v.Uint64())
+	case metrics.KindFloat64:
+		return v.Float64()
+	default:
+		return 0
+	}
+}
+
+// accumulate добавляет к накопленным счетчикам корзин события гистограммы
+// с прошлого опроса.
+func (r *Runtime) accumulate(key string, h *metrics.Float64Histogram) {
+	prev := r.prevHist[key]
+	if len(prev) != len(h.Counts) {
+		prev = make([]uint64, len(h.Counts))
+	}
+
+	pending, ok := r.pendingHist[key]
+	if !ok || len(pending.counts) != len(h.Counts) {
+		pending = &histogramDelta{counts: make([]uint64, len(h.Counts))}
+		r.pendingHist[key] = pending
+	}
+	pending.buckets = h.Buckets
+
+	for i, c := range h.Counts {
+		if c >= prev[i] {
+			pending.counts[i] += c - prev[i]
+		}
+	}
+	r.prevHist[key] = append(prev[:0], h.Counts...)
+}
+
+// histogramPercentile оценивает процентиль p по счетчикам корзин: возвращается
+// верхняя граница корзины, в которую попадает процентиль, а для открытой
+// корзины — нижняя.
+func histogramPercentile(buckets []float64, counts []uint64, total uint64, p float64) float64 {
+	rank := uint64(math.Ceil(p * float64(total)))
+	var seen uint64
+	for i, c := range counts {
+		seen += c
+		if seen < rank || c == 0 {
+			continue
+		}
+		if upper := buckets[i+1]; !math.IsInf(upper, 0) {
+			return upper
+		}
+		if lower := buckets[i]; !math.IsInf(lower, 0) {
+			return lower
+		}
+		return 0
+	}
+	return 0
+}
+
+// runtimeMetricName преобразует имя runtime/metrics "/gc/heap/goal:bytes" в
+// "go.gc.heap.goal:bytes"; stat добавляется перед единицей измерения.
+func runtimeMetricName(key, stat string) string {
+	path, unit, _ := strings.Cut(strings.TrimPrefix(key, "/"), ":")
+	name := "go." + strings.ReplaceAll(path, "/", ".")
+	if stat != "" {
+		name += "." + stat
+	}
+	name += ":" + unit
+
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_.:-", r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// Poll сообщает число опросов PollCount и случайное значение RandomValue.
+type Poll struct{}
+
+// NewPoll создает коллектор poll.
+func NewPoll() Collector {
+	return Poll{}
+}
+
+// Name возвращает "poll".
+func (Poll) Name() string {
+	return "poll"
+}
+
+// Collect увеличивает PollCount на единицу и обновляет RandomValue.
+func (Poll) Collect(context.Context) ([]models.Metrics, error) {
+	return []models.Metrics{
+		Counter("PollCount", 1),
+		Gauge("RandomValue", rand.Float64()),
+	}, nil
+}
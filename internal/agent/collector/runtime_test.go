@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"context"
+	"runtime"
+	"runtime/metrics"
+	"testing"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/validation"
+)
+
+var sink [][]byte
+
+func TestRuntime(t *testing.T) {
+	r := NewRuntime()
+	if _, err := r.Collect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Нагрузка между опросами: выделения памяти, сборка мусора и переключения горутин.
+	for i := 0; i < 1000; i++ {
+		sink = append(sink, make([]byte, 1024))
+	}
+	sink = nil
+	runtime.GC()
+	done := make(chan struct{})
+	go func() { time.Sleep(time.Millisecond); close(done) }()
+	<-done
+
+	got, err := r.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, r.Flush()...)
+	metricsByID := byID(got)
+
+	for _, g := range legacyGauges {
+		if _, ok := metricsByID[g.name]; !ok {
+			t.Errorf("legacy metric %s is missing", g.name)
+		}
+	}
+	for _, name := range []string{"GCCPUFraction", "LastGC", "PauseTotalNs"} {
+		if _, ok := metricsByID[name]; !ok {
+			t.Errorf("legacy metric %s is missing", name)
+		}
+	}
+	if m := metricsByID["HeapAlloc"]; *m.Value <= 0 {
+		t.Errorf("expected positive HeapAlloc, got %v", *m.Value)
+	}
+	if m := metricsByID["LastGC"]; *m.Value <= 0 {
+		t.Errorf("expected LastGC after runtime.GC, got %v", *m.Value)
+	}
+
+	if m, ok := metricsByID["go.gc.heap.goal:bytes"]; !ok || m.Value == nil {
+		t.Errorf("expected gauge go.gc.heap.goal:bytes, got %+v", m)
+	}
+	if m, ok := metricsByID["go.gc.cycles.forced:gc-cycles"]; !ok || m.Delta == nil || *m.Delta < 1 {
+		t.Errorf("expected forced GC delta, got %+v", m)
+	}
+	if m, ok := metricsByID["go.gc.pauses.count:seconds"]; !ok || m.Delta == nil || *m.Delta < 1 {
+		t.Errorf("expected GC pause histogram count, got %+v", m)
+	}
+	if _, ok := metricsByID["go.gc.pauses.p99:seconds"]; !ok {
+		t.Error("expected GC pause histogram percentile")
+	}
+
+	// Все метрики runtime/metrics экспортируются под допустимыми именами.
+	for _, d := range metrics.All() {
+		name := runtimeMetricName(d.Name, "")
+		if d.Kind == metrics.KindFloat64Histogram {
+			name = runtimeMetricName(d.Name, "count")
+		}
+		if _, ok := metricsByID[name]; !ok {
+			t.Errorf("%s is not exported as %s", d.Name, name)
+		}
+	}
+	for _, m := range got {
+		if err := validation.Default().Metric(m); err != nil {
+			t.Errorf("%s: %v", m.ID, err)
+		}
+	}
+}
+
+func TestRuntimeHistogramInterval(t *testing.T) {
+	r := NewRuntime()
+	r.Collect(context.Background())
+	r.Flush()
+
+	// Два опроса за интервал: число событий и процентили покрывают оба.
+	runtime.GC()
+	r.Collect(context.Background())
+	runtime.GC()
+	r.Collect(context.Background())
+	got := byID(r.Flush())
+	if m := got["go.gc.pauses.count:seconds"]; m.Delta == nil || *m.Delta < 2 {
+		t.Errorf("expected pauses of both polls, got %+v", m)
+	}
+	if _, ok := got["go.gc.pauses.p99:seconds"]; !ok {
+		t.Error("expected GC pause percentile")
+	}
+
+	// Интервал без событий: процентили не отправляются.
+	got = byID(r.Flush())
+	if m := got["go.gc.pauses.count:seconds"]; m.Delta == nil || *m.Delta != 0 {
+		t.Errorf("expected zero pauses, got %+v", m)
+	}
+	if _, ok := got["go.gc.pauses.p99:seconds"]; ok {
+		t.Error("percentile of an empty interval must not be sent")
+	}
+}
+
+func TestHistogramPercentile(t *testing.T) {
+	buckets := []float64{0, 1, 2, 4}
+	counts := []uint64{5, 4, 1}
+	for p, want := range map[float64]float64{0.5: 1, 0.9: 2, 0.99: 4} {
+		if got := histogramPercentile(buckets, counts, 10, p); got != want {
+			t.Errorf("p%v: expected %v, got %v", p, want, got)
+		}
+	}
+}
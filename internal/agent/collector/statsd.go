@@ -0,0 +1,246 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/levinOo/go-metrics-project/internal/ingest"
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+// DefaultStatsDAddr — адрес приемника StatsD агента по умолчанию.
+const DefaultStatsDAddr = "127.0.0.1:8125"
+
+// Ограничения памяти приемника на интервал отправки.
+const (
+	maxTimerSamples = 10000
+	maxSetMembers   = 100000
+)
+
+// timerStats — значения таймера за интервал отправки.
+type timerStats struct {
+	base   string
+	tags   []ingest.Tag
+	count  float64
+	sum    float64
+	min    float64
+	max    float64
+	values []float64
+}
+
+// StatsD — приемник StatsD по UDP для приложений на хосте. Строки накапливаются
+// между отправками и попадают в пакет агента при снимке реестра:
+//   - c — counter, дробные приращения переносятся на следующую отправку;
+//   - g — gauge, значение со знаком + или - изменяет текущее;
+//   - ms, h, d — gauge-сводки name.count, name.sum, name.min, name.max, name.mean,
+//     name.p50, name.p90 и name.p99 за интервал;
+//   - s — gauge с числом уникальных значений за интервал.
+//
+// Сам коллектор при опросе сообщает counter StatsDPackets и StatsDBadLines.
+type StatsD struct {
+	conn *net.UDPConn
+	done chan struct{}
+
+	mu       sync.Mutex
+	counters map[string]float64
+	gauges   map[string]float64
+	timers   map[string]*timerStats
+	sets     map[string]map[string]struct{}
+	packets  int64
+	badLines int64
+}
+
+// NewStatsD создает приемник и начинает слушать addr.
+func NewStatsD(addr string) (*StatsD, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &StatsD{
+		conn:     conn,
+		done:     make(chan struct{}),
+		counters: make(map[string]float64),
+		gauges:   make(map[string]float64),
+		timers:   make(map[string]*timerStats),
+		sets:     make(map[string]map[string]struct{}),
+	}
+	go s.serve()
+	return s, nil
+}
+
+// Addr возвращает адрес, на котором слушает приемник.
+func (s *StatsD) Addr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+// Name возвращает "statsd".
+func (s *StatsD) Name() string {
+	return "statsd"
+}
+
+// Collect сообщает число принятых пакетов и отброшенных строк с прошлого опроса.
+func (s *StatsD) Collect(context.Context) ([]models.Metrics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metrics := []models.Metrics{
+		Counter("StatsDPackets", s.packets),
+		Counter("StatsDBadLines", s.badLines),
+	}
+	s.packets, s.badLines = 0, 0
+	return metrics, nil
+}
+
+// Close останавливает прием и дожидается завершения чтения.
+func (s *StatsD) Close() error {
+	err := s.conn.Close()
+	<-s.done
+	return err
+}
+
+func (s *StatsD) serve() {
+	defer close(s.done)
+
+	buf := make([]byte, 65535)
+	for {
+		n, _, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		s.handlePacket(buf[:n])
+	}
+}
+
+func (s *StatsD) handlePacket(packet []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.packets++
+	for _, line := range bytes.Split(packet, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		sample, err := ingest.ParseStatsD(string(line))
+		if err != nil {
+			s.badLines++
+			continue
+		}
+		s.add(sample)
+	}
+}
+
+func (s *StatsD) add(sample ingest.StatsDSample) {
+	switch sample.Type {
+	case ingest.StatsDCounter:
+		s.counters[sample.Name] += sample.Value
+	case ingest.StatsDGauge:
+		if sample.Relative {
+			s.gauges[sample.Name] += sample.Value
+		} else {
+			s.gauges[sample.Name] = sample.Value
+		}
+	case ingest.StatsDSet:
+		members, ok := s.sets[sample.Name]
+		if !ok {
+			members = make(map[string]struct{})
+			s.sets[sample.Name] = members
+		}
+		if len(members) < maxSetMembers {
+			members[sample.Member] = struct{}{}
+		}
+	default:
+		t, ok := s.timers[sample.Name]
+		if !ok {
+			t = &timerStats{base: sample.Base, tags: sample.Tags, min: sample.Value, max: sample.Value}
+			s.timers[sample.Name] = t
+		}
+		t.count += 1 / sample.Rate
+		t.sum += sample.Value / sample.Rate
+		t.min = math.Min(t.min, sample.Value)
+		t.max = math.Max(t.max, sample.Value)
+		if len(t.values) < maxTimerSamples {
+			t.values = append(t.values, sample.Value)
+		}
+	}
+}
+
+// Flush отдает накопленные за интервал метрики. Gauge сохраняют значение для
+// следующих относительных изменений, остальное сбрасывается.
+func (s *StatsD) Flush() []models.Metrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var metrics []models.Metrics
+
+	for name, value := range s.counters {
+		whole := math.Trunc(value)
+		if whole != 0 {
+			metrics = append(metrics, Counter(name, int64(whole)))
+		}
+		if rest := value - whole; rest != 0 {
+			s.counters[name] = rest
+		} else {
+			delete(s.counters, name)
+		}
+	}
+
+	for name, value := range s.gauges {
+		metrics = append(metrics, Gauge(name, value))
+	}
+
+	for name, members := range s.sets {
+		metrics = append(metrics, Gauge(name, float64(len(members))))
+	}
+	s.sets = make(map[string]map[string]struct{})
+
+	for _, t := range s.timers {
+		metrics = append(metrics, t.summary()...)
+	}
+	s.timers = make(map[string]*timerStats)
+
+	return metrics
+}
+
+func (t *timerStats) summary() []models.Metrics {
+	sort.Float64s(t.values)
+
+	gauge := func(stat string, value float64) models.Metrics {
+		return Gauge(ingest.MetricName(t.base+"."+stat, t.tags), value)
+	}
+	return []models.Metrics{
+		gauge("count", t.count),
+		gauge("sum", t.sum),
+		gauge("min", t.min),
+		gauge("max", t.max),
+		gauge("mean", t.sum/t.count),
+		gauge("p50", percentile(t.values, 0.5)),
+		gauge("p90", percentile(t.values, 0.9)),
+		gauge("p99", percentile(t.values, 0.99)),
+	}
+}
+
+// percentile возвращает процентиль p отсортированных значений методом ближайшего ранга.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStatsD(t *testing.T) {
+	s, err := NewStatsD("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRegistry()
+	if err := r.Register(s, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	conn, err := net.Dial("udp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	fmt.Fprint(conn, "jobs:1|c|@0.5\njobs:0.5|c\npool:10|g\npool:-3|g\nbroken")
+	fmt.Fprint(conn, "users:alice|s\nusers:bob|s\nusers:alice|s")
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(conn, "api.latency:%d|ms|#route:GET", i*10)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		packets := s.packets
+		s.mu.Unlock()
+		if packets == 12 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("received %d of 12 packets", packets)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	r.CollectOnce()
+	got := byID(r.Snapshot())
+
+	if m := got["jobs"]; m.Delta == nil || *m.Delta != 2 {
+		t.Errorf("expected jobs delta 2 with carried 0.5, got %+v", m)
+	}
+	for id, want := range map[string]float64{
+		"pool":                        7,
+		"users":                       2,
+		"api.latency.count.route:GET": 10,
+		"api.latency.sum.route:GET":   550,
+		"api.latency.max.route:GET":   100,
+		"api.latency.p50.route:GET":   50,
+		"api.latency.p90.route:GET":   90,
+	} {
+		if m := got[id]; m.Value == nil || *m.Value != want {
+			t.Errorf("%s: expected %v, got %+v", id, want, m)
+		}
+	}
+	if m := got["StatsDBadLines"]; m.Delta == nil || *m.Delta != 1 {
+		t.Errorf("expected 1 bad line, got %+v", m)
+	}
+
+	// Сводки и множества не отправляются повторно, дробный остаток счетчика
+	// переносится, gauge сохраняют значение.
+	fmt.Fprint(conn, "jobs:0.5|c")
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		packets := s.packets
+		s.mu.Unlock()
+		if packets == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("second packet not received")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	flushed := byID(r.Snapshot())
+	if m := flushed["jobs"]; m.Delta == nil || *m.Delta != 1 {
+		t.Errorf("expected carried jobs delta 1, got %+v", m)
+	}
+	for _, id := range []string{"api.latency.count.route:GET", "users"} {
+		if _, ok := flushed[id]; ok {
+			t.Errorf("%s: interval gauge must not be resent", id)
+		}
+	}
+	if m := flushed["pool"]; m.Value == nil || *m.Value != 7 {
+		t.Errorf("statsd gauge must keep its value, got %+v", m)
+	}
+}
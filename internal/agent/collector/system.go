@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/mem"
+)
+
+// System собирает метрики памяти и загрузки процессоров через gopsutil:
+// TotalMemory, FreeMemory и CPUutilization1..N в процентах.
+//
+// Загрузка процессора считается по разнице времен между двумя опросами, поэтому
+// первый опрос сообщает только память.
+type System struct {
+	mu   sync.Mutex
+	prev []cpu.TimesStat
+
+	virtualMemory func(ctx context.Context) (*mem.VirtualMemoryStat, error)
+	cpuTimes      func(ctx context.Context, percpu bool) ([]cpu.TimesStat, error)
+}
+
+// NewSystem создает коллектор system.
+func NewSystem() *System {
+	return &System{
+		virtualMemory: mem.VirtualMemoryWithContext,
+		cpuTimes:      cpu.TimesWithContext,
+	}
+}
+
+// Name возвращает "system".
+func (s *System) Name() string {
+	return "system"
+}
+
+// Collect читает память и времена процессоров. Ошибка одного источника не
+// мешает вернуть метрики другого.
+func (s *System) Collect(ctx context.Context) ([]models.Metrics, error) {
+	var metrics []models.Metrics
+	var errs []error
+
+	vm, err := s.virtualMemory(ctx)
+	switch {
+	case err != nil:
+		errs = append(errs, fmt.Errorf("memory: %w", err))
+	case vm == nil:
+		errs = append(errs, errors.New("memory: no data"))
+	default:
+		metrics = append(metrics,
+			Gauge("TotalMemory", float64(vm.Total)),
+			Gauge("FreeMemory", float64(vm.Free)),
+		)
+	}
+
+	times, err := s.cpuTimes(ctx, true)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("cpu: %w", err))
+		return metrics, errors.Join(errs...)
+	}
+
+	s.mu.Lock()
+	prev := s.prev
+	s.prev = times
+	s.mu.Unlock()
+
+	// При первом опросе или изменении числа процессоров сравнивать не с чем.
+	if len(prev) == len(times) {
+		for i := range times {
+			metrics = append(metrics, Gauge("CPUutilization"+strconv.Itoa(i+1), cpuBusy(prev[i], times[i])))
+		}
+	}
+	return metrics, errors.Join(errs...)
+}
+
+// cpuBusy возвращает долю занятого времени процессора между t1 и t2 в процентах.
+func cpuBusy(t1, t2 cpu.TimesStat) float64 {
+	busy1 := t1.Total() - t1.Idle
+	busy2 := t2.Total() - t2.Idle
+	all := t2.Total() - t1.Total()
+
+	if busy2 <= busy1 || all <= 0 {
+		return 0
+	}
+	return math.Min(100, (busy2-busy1)/all*100)
+}
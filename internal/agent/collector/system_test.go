@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/mem"
+)
+
+func TestSystemCollect(t *testing.T) {
+	s := NewSystem()
+	memErr := errors.New("no /proc/meminfo")
+	s.virtualMemory = func(context.Context) (*mem.VirtualMemoryStat, error) {
+		return nil, memErr
+	}
+
+	times := [][]cpu.TimesStat{
+		{{User: 10, Idle: 90}, {User: 50, Idle: 50}},
+		{{User: 35, Idle: 165}, {User: 50, Idle: 150}},
+	}
+	calls := 0
+	s.cpuTimes = func(context.Context, bool) ([]cpu.TimesStat, error) {
+		calls++
+		return times[calls-1], nil
+	}
+
+	// Первый опрос: память недоступна, загрузку процессора не с чем сравнить.
+	metrics, err := s.Collect(context.Background())
+	if !errors.Is(err, memErr) {
+		t.Errorf("expected memory error, got %v", err)
+	}
+	if len(metrics) != 0 {
+		t.Errorf("expected no metrics, got %+v", metrics)
+	}
+
+	s.virtualMemory = func(context.Context) (*mem.VirtualMemoryStat, error) {
+		return &mem.VirtualMemoryStat{Total: 1000, Free: 400}, nil
+	}
+	metrics, err = s.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]float64{
+		"TotalMemory":     1000,
+		"FreeMemory":      400,
+		"CPUutilization1": 25,
+		"CPUutilization2": 0,
+	}
+	if len(metrics) != len(want) {
+		t.Fatalf("expected %d metrics, got %+v", len(want), metrics)
+	}
+	for _, m := range metrics {
+		if *m.Value != want[m.ID] {
+			t.Errorf("%s: expected %v, got %v", m.ID, want[m.ID], *m.Value)
+		}
+	}
+}
@@ -89,7 +89,7 @@ func (m *Metrics) ValuesAllTyped() map[string]Metric {
 }
 
 func (m *Metrics) ValuesGauge() map[string]Metric {
-	return map[string]Metric{
+	gauges := map[string]Metric{
 		"Alloc":         m.Alloc,
 		"BuckHashSys":   m.BuckHashSys,
 		"Frees":         m.Frees,
@@ -118,7 +118,13 @@ func (m *Metrics) ValuesGauge() map[string]Metric {
 		"Sys":           m.Sys,
 		"TotalAlloc":    m.TotalAlloc,
 		"RandomValue":   m.RandomValue,
+		"TotalMemory":   m.TotalMemory,
+		"FreeMemory":    m.FreeMemory,
+	}
+	for i, val := range m.CPUutilization {
+		gauges["CPUutilization"+strconv.Itoa(i+1)] = val
 	}
+	return gauges
 }
 
 func (m *Metrics) ValuesCounter() map[string]Metric {
@@ -127,6 +133,10 @@ func (m *Metrics) ValuesCounter() map[string]Metric {
 	}
 }
 
+// CollectMetrics заполняет метрики из runtime.MemStats и gopsutil.
+//
+// Deprecated: runtime.ReadMemStats останавливает программу при каждом вызове.
+// Агент собирает метрики коллекторами collector.Runtime и collector.System.
 func (m *Metrics) CollectMetrics() {
 	var stats runtime.MemStats
 	runtime.ReadMemStats(&stats)
@@ -164,13 +174,15 @@ func (m *Metrics) CollectMetrics() {
 	memStat, err := mem.VirtualMemory()
 	if err != nil {
 		log.Printf("Error collecting memory metrics: %v", err)
+	} else {
+		m.TotalMemory = Gauge(memStat.Total)
+		m.FreeMemory = Gauge(memStat.Free)
 	}
 
-	m.TotalMemory = Gauge(memStat.Total)
-	m.FreeMemory = Gauge(memStat.Free)
 	cpu, err := cpu.Percent(0, true)
 	if err != nil {
 		log.Printf("failed to get CPU stats: %v", err)
+		return
 	}
 
 	m.CPUutilization = make([]Gauge, len(cpu))
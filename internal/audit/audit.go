@@ -161,6 +161,15 @@ func (a *URLAuditer) Update(data models.Data) {
 	defer resp.Body.Close()
 }
 
+// Административные действия, записываемые в поле Action события аудита.
+const (
+	// ActionCounterReset — счетчик обнулен.
+	ActionCounterReset = "counter_reset"
+
+	// ActionCounterSet — счетчику установлено абсолютное значение.
+	ActionCounterSet = "counter_set"
+)
+
 // NewAuditEvent создаёт и отправляет событие аудита для списка метрик.
 // Настраивает подписчиков для файла и URL, собирает информацию о метриках
 // и уведомляет всех подписчиков.
@@ -171,27 +180,38 @@ func (a *URLAuditer) Update(data models.Data) {
 //	path: путь к файлу аудита (пустая строка для отключения)
 //	url: URL для отправки событий (пустая строка для отключения)
 //	ip: IP-адрес клиента, выполнившего операцию
-//	json: JSON-сериализатор
 func NewAuditEvent(metrics models.ListMetrics, path, url, ip string) {
-	ts := time.Now().Unix()
-
-	fileAuditer := NewFileAuditer(path)
-	urlAuditer := NewURLAuditer(url)
-
 	data := models.Data{
-		TS:          ts,
+		TS:          time.Now().Unix(),
 		IP:          ip,
 		MetricNames: make([]string, 0, len(metrics.List)),
 	}
 
-	auditer := &Auditer{}
-	auditer.RegisterClient(fileAuditer)
-	auditer.RegisterClient(urlAuditer)
-
 	for _, metric := range metrics.List {
 		data.MetricNames = append(data.MetricNames, metric.ID)
 	}
 
+	notify(data, path, url)
+}
+
+// NewAdminAuditEvent создаёт и отправляет событие аудита для административного
+// действия над счетчиком. Для сброса value равно нулю.
+func NewAdminAuditEvent(action, name string, value int64, path, url, ip string) {
+	notify(models.Data{
+		TS:          time.Now().Unix(),
+		IP:          ip,
+		MetricNames: []string{name},
+		Action:      action,
+		Value:       &value,
+	}, path, url)
+}
+
+// notify уведомляет подписчиков для файла и URL о событии.
+func notify(data models.Data, path, url string) {
+	auditer := &Auditer{}
+	auditer.RegisterClient(NewFileAuditer(path))
+	auditer.RegisterClient(NewURLAuditer(url))
+
 	auditer.SetMessage(data)
 	auditer.NotifyClient()
 }
@@ -30,10 +30,95 @@ type Config struct {
 	// Restore определяет, нужно ли восстанавливать метрики из файла при запуске сервера.
 	Restore bool `env:"RESTORE"`
 
+	// SnapshotKeep задает количество хранимых снимков метрик (текущий и ротированные копии).
+	// При восстановлении используется самый новый корректный снимок.
+	SnapshotKeep int `env:"SNAPSHOT_KEEP"`
+
 	// AddrDB содержит строку подключения к базе данных PostgreSQL (DSN).
 	// Если не указано, используется хранилище в памяти.
 	AddrDB string `env:"DATABASE_DSN"`
 
+	// DBMaxOpenConns задает максимальное количество открытых соединений с базой данных.
+	DBMaxOpenConns int `env:"DB_MAX_OPEN_CONNS"`
+
+	// DBMaxIdleConns задает максимальное количество простаивающих соединений в пуле.
+	DBMaxIdleConns int `env:"DB_MAX_IDLE_CONNS"`
+
+	// DBConnMaxLifetime задает максимальное время жизни соединения в секундах.
+	DBConnMaxLifetime int `env:"DB_CONN_MAX_LIFETIME"`
+
+	// DBBatchChunkSize задает максимальное количество строк в одном пакетном INSERT.
+	DBBatchChunkSize int `env:"DB_BATCH_CHUNK_SIZE"`
+
+	// DBCopyThreshold задает размер пакета, начиная с которого используется COPY.
+	// Значение 0 отключает COPY.
+	DBCopyThreshold int `env:"DB_COPY_THRESHOLD"`
+
+	// DBBufferCapacity задает емкость буфера записи (в метриках) на время недоступности базы данных.
+	DBBufferCapacity int `env:"DB_BUFFER_CAPACITY"`
+
+	// DBCheckInterval задает интервал проверки доступности базы данных в секундах
+	// в деградированном режиме.
+	DBCheckInterval int `env:"DB_CHECK_INTERVAL"`
+
+	// DBCacheSize задает максимальное количество метрик в кеше чтения перед базой данных.
+	// Нулевое значение отключает кеш.
+	DBCacheSize int `env:"DB_CACHE_SIZE"`
+
+	// DBCacheTTL задает время жизни записи кеша чтения в секундах.
+	DBCacheTTL int `env:"DB_CACHE_TTL"`
+
+	// MetricNamePattern задает регулярное выражение, которому должно целиком
+	// соответствовать имя метрики.
+	MetricNamePattern string `env:"METRIC_NAME_PATTERN"`
+
+	// MetricNameMaxLength задает максимальную длину имени метрики в байтах.
+	MetricNameMaxLength int `env:"METRIC_NAME_MAX_LENGTH"`
+
+	// MaxBodySize задает максимальный размер тела запроса после распаковки в байтах.
+	// Нулевое значение снимает ограничение.
+	MaxBodySize int64 `env:"MAX_BODY_SIZE"`
+
+	// MaxBatchItems задает максимальное количество метрик в одном пакетном запросе.
+	// Нулевое значение снимает ограничение.
+	MaxBatchItems int `env:"MAX_BATCH_ITEMS"`
+
+	// StreamChunkSize задает количество метрик, после накопления которых потоковый
+	// NDJSON-запрос записывается в хранилище.
+	StreamChunkSize int `env:"STREAM_CHUNK_SIZE"`
+
+	// InfluxFieldTypes задает правила выбора типа метрики для полей line protocol
+	// в виде "pattern=type,...", например "*_total=counter,requests=counter".
+	// Поля, не попавшие ни под одно правило, записываются как gauge.
+	InfluxFieldTypes string `env:"INFLUX_FIELD_TYPES"`
+
+	// InfluxPrecision задает точность меток времени line protocol по умолчанию:
+	// ns, us, ms, s, m или h.
+	InfluxPrecision string `env:"INFLUX_PRECISION"`
+
+	// OTLPResourceLabels перечисляет через запятую атрибуты ресурса OTLP, которые
+	// добавляются к имени метрики как теги, например "service.name,host.name".
+	OTLPResourceLabels string `env:"OTLP_RESOURCE_LABELS"`
+
+	// OTLPResourcePrefix задает атрибут ресурса OTLP, значение которого становится
+	// префиксом имени метрики. Пустое значение отключает префикс.
+	OTLPResourcePrefix string `env:"OTLP_RESOURCE_PREFIX"`
+
+	// GraphiteAddr задает TCP-адрес приемника Graphite plaintext.
+	// Пустое значение отключает приемник.
+	GraphiteAddr string `env:"GRAPHITE_ADDRESS"`
+
+	// GraphiteTypes задает правила выбора типа метрики Graphite по пути
+	// в виде "pattern=type,...". Остальные метрики записываются как gauge.
+	GraphiteTypes string `env:"GRAPHITE_TYPES"`
+
+	// StatsDAddr задает UDP-адрес приемника StatsD. Пустое значение отключает приемник.
+	StatsDAddr string `env:"STATSD_ADDRESS"`
+
+	// ListenerFlushInterval задает интервал записи метрик, накопленных приемниками
+	// Graphite и StatsD, в секундах.
+	ListenerFlushInterval int `env:"LISTENER_FLUSH_INTERVAL"`
+
 	// Key содержит секретный ключ для подписи запросов HMAC SHA256.
 	// Пустое значение отключает проверку подписей.
 	Key string `env:"KEY"`
@@ -43,6 +128,10 @@ type Config struct {
 
 	// AuditURL содержит URL для отправки аудит-событий на внешний сервис.
 	AuditURL string `env:"AUDIT_URL"`
+
+	// AdminToken содержит токен для административных эндпоинтов /admin.
+	// Пустое значение отключает административные эндпоинты.
+	AdminToken string `env:"ADMIN_TOKEN"`
 }
 
 // GetConfig загружает и возвращает конфигурацию приложения.
@@ -59,11 +148,42 @@ type Config struct {
 //	-k: ключ для HMAC (по умолчанию "")
 //	-p: путь к файлу аудита (по умолчанию "./audit.json")
 //	-u: URL для аудита (по умолчанию "")
+//	-snapshot-keep: количество хранимых снимков (по умолчанию "3")
+//	-db-max-open-conns: максимум открытых соединений с БД (по умолчанию "10")
+//	-db-max-idle-conns: максимум простаивающих соединений с БД (по умолчанию "5")
+//	-db-conn-max-lifetime: время жизни соединения в секундах (по умолчанию "300")
+//	-db-batch-chunk-size: строк в одном пакетном INSERT (по умолчанию "1000")
+//	-db-copy-threshold: размер пакета для загрузки через COPY (по умолчанию "5000")
+//	-db-buffer-capacity: емкость буфера записи при недоступной БД (по умолчанию "10000")
+//	-db-check-interval: интервал проверки БД в секундах (по умолчанию "5")
+//	-db-cache-size: размер кеша чтения в метриках, 0 отключает кеш (по умолчанию "10000")
+//	-db-cache-ttl: время жизни записи кеша чтения в секундах (по умолчанию "10")
+//	-admin-token: токен административных эндпоинтов (по умолчанию "")
+//	-metric-name-pattern: регулярное выражение для имен метрик (по умолчанию "[A-Za-z_][A-Za-z0-9_.:-]*")
+//	-metric-name-max-length: максимальная длина имени метрики в байтах (по умолчанию "255")
+//	-max-body-size: максимальный размер тела запроса в байтах (по умолчанию "67108864")
+//	-max-batch-items: максимум метрик в пакетном запросе (по умолчанию "1000000")
+//	-stream-chunk-size: метрик в одной записи потокового режима (по умолчанию "1000")
+//	-influx-field-types: правила типов полей line protocol (по умолчанию "")
+//	-influx-precision: точность меток времени line protocol (по умолчанию "ns")
+//	-otlp-resource-labels: атрибуты ресурса OTLP для меток (по умолчанию "service.name")
+//	-otlp-resource-prefix: атрибут ресурса OTLP для префикса имени (по умолчанию "")
+//	-graphite-address: TCP-адрес приемника Graphite (по умолчанию "")
+//	-graphite-types: правила типов метрик Graphite (по умолчанию "")
+//	-statsd-address: UDP-адрес приемника StatsD (по умолчанию "")
+//	-listener-flush-interval: интервал записи метрик приемников в секундах (по умолчанию "10")
 //
 // Соответствующие переменные окружения:
 //
 //	ADDRESS, STORE_INTERVAL, FILE_STORAGE_PATH, RESTORE,
-//	DATABASE_DSN, KEY, AUDIT_FILE, AUDIT_URL
+//	DATABASE_DSN, KEY, AUDIT_FILE, AUDIT_URL, SNAPSHOT_KEEP,
+//	DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS, DB_CONN_MAX_LIFETIME,
+//	DB_BATCH_CHUNK_SIZE, DB_COPY_THRESHOLD, DB_BUFFER_CAPACITY, DB_CHECK_INTERVAL,
+//	DB_CACHE_SIZE, DB_CACHE_TTL, ADMIN_TOKEN,
+//	METRIC_NAME_PATTERN, METRIC_NAME_MAX_LENGTH,
+//	MAX_BODY_SIZE, MAX_BATCH_ITEMS, STREAM_CHUNK_SIZE,
+//	INFLUX_FIELD_TYPES, INFLUX_PRECISION, OTLP_RESOURCE_LABELS, OTLP_RESOURCE_PREFIX,
+//	GRAPHITE_ADDRESS, GRAPHITE_TYPES, STATSD_ADDRESS, LISTENER_FLUSH_INTERVAL
 func GetConfig() (Config, error) {
 	addrFlag := flag.String("a", "localhost:8080", "HTTP server address")
 	storeIntFlag := flag.String("i", "300", "store interval in seconds")
@@ -73,18 +193,66 @@ func GetConfig() (Config, error) {
 	key := flag.String("k", "", "Hash key")
 	auditFile := flag.String("p", "./audit.json", "audit file path")
 	auditURL := flag.String("u", "", "audit url")
+	adminToken := flag.String("admin-token", "", "bearer token for admin endpoints, empty disables them")
+	snapshotKeep := flag.String("snapshot-keep", "3", "number of metric snapshots to keep")
+	dbMaxOpenConns := flag.String("db-max-open-conns", "10", "max open database connections")
+	dbMaxIdleConns := flag.String("db-max-idle-conns", "5", "max idle database connections")
+	dbConnMaxLifetime := flag.String("db-conn-max-lifetime", "300", "database connection max lifetime in seconds")
+	dbBatchChunkSize := flag.String("db-batch-chunk-size", "1000", "rows per batch INSERT")
+	dbCopyThreshold := flag.String("db-copy-threshold", "5000", "batch size to switch to COPY (0 disables)")
+	dbBufferCapacity := flag.String("db-buffer-capacity", "10000", "write buffer capacity while database is down")
+	dbCheckInterval := flag.String("db-check-interval", "5", "database availability check interval in seconds")
+	dbCacheSize := flag.String("db-cache-size", "10000", "read cache size in metrics, 0 disables the cache")
+	dbCacheTTL := flag.String("db-cache-ttl", "10", "read cache entry TTL in seconds")
+	metricNamePattern := flag.String("metric-name-pattern", `[A-Za-z_][A-Za-z0-9_.:-]*`, "regular expression for metric names")
+	metricNameMaxLength := flag.String("metric-name-max-length", "255", "maximum metric name length in bytes")
+	maxBodySize := flag.String("max-body-size", "67108864", "maximum decompressed request body size in bytes, 0 disables the limit")
+	maxBatchItems := flag.String("max-batch-items", "1000000", "maximum number of metrics in one batch request, 0 disables the limit")
+	streamChunkSize := flag.String("stream-chunk-size", "1000", "number of metrics written to storage at once for NDJSON requests")
+	influxFieldTypes := flag.String("influx-field-types", "", "line protocol field type rules: pattern=gauge|counter, comma separated")
+	influxPrecision := flag.String("influx-precision", "ns", "default line protocol timestamp precision")
+	otlpResourceLabels := flag.String("otlp-resource-labels", "service.name", "OTLP resource attributes added to metric names as labels, comma separated")
+	otlpResourcePrefix := flag.String("otlp-resource-prefix", "", "OTLP resource attribute used as metric name prefix")
+	graphiteAddr := flag.String("graphite-address", "", "Graphite plaintext TCP listener address, empty disables it")
+	graphiteTypes := flag.String("graphite-types", "", "Graphite metric type rules: pattern=gauge|counter, comma separated")
+	statsdAddr := flag.String("statsd-address", "", "StatsD UDP listener address, empty disables it")
+	listenerFlushInterval := flag.String("listener-flush-interval", "10", "Graphite and StatsD flush interval in seconds")
 
 	flag.Parse()
 
 	cfg := Config{
-		Addr:          getString(os.Getenv("ADDRESS"), *addrFlag),
-		FileStorage:   getString(os.Getenv("FILE_STORAGE_PATH"), *fileFlag),
-		StoreInterval: getInt(os.Getenv("STORE_INTERVAL"), *storeIntFlag),
-		Restore:       getBool(os.Getenv("RESTORE"), *restoreFlag),
-		AddrDB:        getString(os.Getenv("DATABASE_DSN"), *addrDBFlag),
-		Key:           getString(os.Getenv("KEY"), *key),
-		AuditFile:     getString(os.Getenv("AUDIT_FILE"), *auditFile),
-		AuditURL:      getString(os.Getenv("AUDIT_URL"), *auditURL),
+		Addr:                  getString(os.Getenv("ADDRESS"), *addrFlag),
+		FileStorage:           getString(os.Getenv("FILE_STORAGE_PATH"), *fileFlag),
+		StoreInterval:         getInt(os.Getenv("STORE_INTERVAL"), *storeIntFlag),
+		Restore:               getBool(os.Getenv("RESTORE"), *restoreFlag),
+		AddrDB:                getString(os.Getenv("DATABASE_DSN"), *addrDBFlag),
+		DBMaxOpenConns:        getInt(os.Getenv("DB_MAX_OPEN_CONNS"), *dbMaxOpenConns),
+		DBMaxIdleConns:        getInt(os.Getenv("DB_MAX_IDLE_CONNS"), *dbMaxIdleConns),
+		DBConnMaxLifetime:     getInt(os.Getenv("DB_CONN_MAX_LIFETIME"), *dbConnMaxLifetime),
+		DBBatchChunkSize:      getInt(os.Getenv("DB_BATCH_CHUNK_SIZE"), *dbBatchChunkSize),
+		DBCopyThreshold:       getInt(os.Getenv("DB_COPY_THRESHOLD"), *dbCopyThreshold),
+		DBBufferCapacity:      getInt(os.Getenv("DB_BUFFER_CAPACITY"), *dbBufferCapacity),
+		DBCheckInterval:       getInt(os.Getenv("DB_CHECK_INTERVAL"), *dbCheckInterval),
+		DBCacheSize:           getInt(os.Getenv("DB_CACHE_SIZE"), *dbCacheSize),
+		DBCacheTTL:            getInt(os.Getenv("DB_CACHE_TTL"), *dbCacheTTL),
+		MetricNamePattern:     getString(os.Getenv("METRIC_NAME_PATTERN"), *metricNamePattern),
+		MetricNameMaxLength:   getInt(os.Getenv("METRIC_NAME_MAX_LENGTH"), *metricNameMaxLength),
+		MaxBodySize:           int64(getInt(os.Getenv("MAX_BODY_SIZE"), *maxBodySize)),
+		MaxBatchItems:         getInt(os.Getenv("MAX_BATCH_ITEMS"), *maxBatchItems),
+		StreamChunkSize:       getInt(os.Getenv("STREAM_CHUNK_SIZE"), *streamChunkSize),
+		InfluxFieldTypes:      getString(os.Getenv("INFLUX_FIELD_TYPES"), *influxFieldTypes),
+		InfluxPrecision:       getString(os.Getenv("INFLUX_PRECISION"), *influxPrecision),
+		OTLPResourceLabels:    getString(os.Getenv("OTLP_RESOURCE_LABELS"), *otlpResourceLabels),
+		OTLPResourcePrefix:    getString(os.Getenv("OTLP_RESOURCE_PREFIX"), *otlpResourcePrefix),
+		GraphiteAddr:          getString(os.Getenv("GRAPHITE_ADDRESS"), *graphiteAddr),
+		GraphiteTypes:         getString(os.Getenv("GRAPHITE_TYPES"), *graphiteTypes),
+		StatsDAddr:            getString(os.Getenv("STATSD_ADDRESS"), *statsdAddr),
+		ListenerFlushInterval: getInt(os.Getenv("LISTENER_FLUSH_INTERVAL"), *listenerFlushInterval),
+		Key:                   getString(os.Getenv("KEY"), *key),
+		AuditFile:             getString(os.Getenv("AUDIT_FILE"), *auditFile),
+		AuditURL:              getString(os.Getenv("AUDIT_URL"), *auditURL),
+		AdminToken:            getString(os.Getenv("ADMIN_TOKEN"), *adminToken),
+		SnapshotKeep:          getInt(os.Getenv("SNAPSHOT_KEEP"), *snapshotKeep),
 	}
 
 	return cfg, nil
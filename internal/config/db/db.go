@@ -7,6 +7,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"net"
 	"strings"
 	"syscall"
 	"time"
@@ -14,8 +15,9 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/levinOo/go-metrics-project/migrations"
 
 	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -87,6 +89,32 @@ func ConnectDB(cfgAddrDB string, sugar *zap.SugaredLogger) (*sql.DB, error) {
 	return dbConn, nil
 }
 
+// PoolConfig задает параметры пула соединений *sql.DB.
+// Нулевые значения оставляют настройки database/sql по умолчанию.
+type PoolConfig struct {
+	// MaxOpenConns — максимальное количество открытых соединений.
+	MaxOpenConns int
+
+	// MaxIdleConns — максимальное количество простаивающих соединений в пуле.
+	MaxIdleConns int
+
+	// ConnMaxLifetime — максимальное время жизни соединения.
+	ConnMaxLifetime time.Duration
+}
+
+// ConfigurePool применяет параметры пула к соединению с базой данных.
+func ConfigurePool(dbConn *sql.DB, cfg PoolConfig) {
+	if cfg.MaxOpenConns > 0 {
+		dbConn.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		dbConn.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		dbConn.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+}
+
 // isPostgreSQLConnectionError проверяет, является ли ошибка проблемой соединения с PostgreSQL.
 // Определяет следующие типы ошибок подключения:
 //   - Ошибки PostgreSQL класса 08 (Connection Exception)
@@ -115,10 +143,78 @@ func isPostgreSQLConnectionError(err error) bool {
 		strings.Contains(errStr, "connect:")
 }
 
-// RunMigrations выполняет миграции базы данных из директории migrations/.
-// Использует библиотеку golang-migrate для применения SQL-миграций.
+// IsTransientError проверяет, является ли ошибка временной, после которой операцию
+// с базой данных имеет смысл повторить. Временными считаются:
+//   - ошибки недоступности базы данных (см. IsUnavailableError)
+//   - 40001 serialization_failure и 40P01 deadlock_detected (транзакция откачена)
+//
+// Повтор после временной ошибки безопасен только для идемпотентных операций:
+// при разрыве соединения запрос мог быть уже применен. Для увеличения счетчиков
+// используется IsSafeToRetry.
+func IsTransientError(err error) bool {
+	return isRolledBack(err) || IsUnavailableError(err)
+}
+
+// IsUnavailableError проверяет, означает ли ошибка недоступность базы данных:
+//   - ошибки соединения (см. isPostgreSQLConnectionError)
+//   - ошибки, которые pgconn помечает как безопасные для повтора (запрос не был отправлен)
+//   - 57P01, 57P02, 57P03 — остановка или перезапуск сервера при переключении реплик
+func IsUnavailableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "57P01", "57P02", "57P03":
+			return true
+		}
+	}
+
+	return isPostgreSQLConnectionError(err)
+}
+
+// IsSafeToRetry проверяет, что операция, завершившаяся ошибкой, гарантированно
+// не была применена, поэтому ее можно повторить, даже если она не идемпотентна:
+//   - pgconn помечает ошибку безопасной для повтора (запрос не был отправлен)
+//   - 40001 и 40P01 — транзакция откачена сервером
+//   - соединение с сервером не было установлено
+func IsSafeToRetry(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if pgconn.SafeToRetry(err) || isRolledBack(err) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	return strings.Contains(err.Error(), "dial tcp")
+}
+
+// isRolledBack проверяет, откатил ли сервер транзакцию из-за конфликта.
+func isRolledBack(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	return false
+}
+
+// RunMigrations применяет все новые миграции, встроенные в бинарный файл (пакет migrations).
+// Использует библиотеку golang-migrate с источником iofs.
 //
-// Миграции должны находиться в директории "./migrations" относительно рабочей директории.
 // Файлы миграций должны следовать формату: {version}_{name}.up.sql и {version}_{name}.down.sql
 //
 // Параметры:
@@ -127,28 +223,104 @@ func isPostgreSQLConnectionError(err error) bool {
 //
 // Возвращает nil при успешном применении миграций или если миграции уже применены.
 // Возвращает ошибку при проблемах с созданием экземпляра migrate или применением миграций.
-//
-// Пример структуры директории migrations:
-//
-//	migrations/
-//	  001_create_metrics_table.up.sql
-//	  001_create_metrics_table.down.sql
-//	  002_add_indexes.up.sql
-//	  002_add_indexes.down.sql
 func RunMigrations(dbConnString string) error {
-	migrationsPath := "file://migrations"
-	m, err := migrate.New(
-		migrationsPath,
-		dbConnString,
+	return MigrateUp(dbConnString)
+}
+
+// MigrateUp применяет все непримененные миграции.
+func MigrateUp(dbConnString string) error {
+	return withMigrate(dbConnString, func(m *migrate.Migrate) error {
+		err := m.Up()
+		if err != nil && err != migrate.ErrNoChange {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+		return nil
+	})
+}
+
+// MigrateDown откатывает steps последних миграций.
+func MigrateDown(dbConnString string, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps must be positive, got %d", steps)
+	}
+
+	return withMigrate(dbConnString, func(m *migrate.Migrate) error {
+		err := m.Steps(-steps)
+		if err != nil && err != migrate.ErrNoChange {
+			return fmt.Errorf("migration rollback failed: %w", err)
+		}
+		return nil
+	})
+}
+
+// MigrateVersion возвращает текущую версию схемы и признак "грязной" миграции,
+// прерванной на середине. Если миграции не применялись, возвращает версию 0.
+func MigrateVersion(dbConnString string) (uint, bool, error) {
+	var (
+		version uint
+		dirty   bool
 	)
+
+	err := withMigrate(dbConnString, func(m *migrate.Migrate) error {
+		var err error
+		version, dirty, err = m.Version()
+		if err == migrate.ErrNilVersion {
+			return nil
+		}
+		return err
+	})
+
+	return version, dirty, err
+}
+
+// MigrateForce принудительно устанавливает версию схемы и снимает признак "грязной"
+// миграции без выполнения SQL. Используется после ручного исправления схемы.
+func MigrateForce(dbConnString string, version int) error {
+	return withMigrate(dbConnString, func(m *migrate.Migrate) error {
+		if err := m.Force(version); err != nil {
+			return fmt.Errorf("force version failed: %w", err)
+		}
+		return nil
+	})
+}
+
+// withMigrate создает экземпляр migrate поверх встроенных миграций и выполняет fn.
+// Изменяющие схему операции migrate сам выполняет под advisory-блокировкой
+// PostgreSQL, поэтому одновременный запуск с нескольких реплик безопасен.
+func withMigrate(dbConnString string, fn func(m *migrate.Migrate) error) error {
+	dbConn, err := DataBaseConnection(dbConnString)
 	if err != nil {
+		return fmt.Errorf("could not open database: %w", err)
+	}
+
+	m, err := newMigrate(dbConn)
+	if err != nil {
+		dbConn.Close()
 		return fmt.Errorf("could not create migrate instance: %w", err)
 	}
 
-	err = m.Up()
-	if err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("migration failed: %w", err)
+	runErr := fn(m)
+
+	// Close закрывает и соединение драйвера, и dbConn.
+	srcErr, dbErr := m.Close()
+	if runErr == nil {
+		runErr = errors.Join(srcErr, dbErr)
+	}
+
+	return runErr
+}
+
+func newMigrate(dbConn *sql.DB) (*migrate.Migrate, error) {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := postgres.WithInstance(dbConn, &postgres.Config{})
+	if err != nil {
+		src.Close()
+		return nil, err
 	}
 
-	return nil
+	return migrate.NewWithInstance("iofs", src, "postgres", driver)
 }
@@ -7,9 +7,33 @@ func (s *Config) Reset() {
 	s.StoreInterval = 0
 	s.FileStorage = ""
 	s.Restore = false
+	s.SnapshotKeep = 0
 	s.AddrDB = ""
+	s.DBMaxOpenConns = 0
+	s.DBMaxIdleConns = 0
+	s.DBConnMaxLifetime = 0
+	s.DBBatchChunkSize = 0
+	s.DBCopyThreshold = 0
+	s.DBBufferCapacity = 0
+	s.DBCheckInterval = 0
+	s.DBCacheSize = 0
+	s.DBCacheTTL = 0
+	s.MetricNamePattern = ""
+	s.MetricNameMaxLength = 0
+	s.MaxBodySize = 0
+	s.MaxBatchItems = 0
+	s.StreamChunkSize = 0
+	s.InfluxFieldTypes = ""
+	s.InfluxPrecision = ""
+	s.OTLPResourceLabels = ""
+	s.OTLPResourcePrefix = ""
+	s.GraphiteAddr = ""
+	s.GraphiteTypes = ""
+	s.StatsDAddr = ""
+	s.ListenerFlushInterval = 0
 	s.Key = ""
 	s.AuditFile = ""
 	s.AuditURL = ""
+	s.AdminToken = ""
 
 }
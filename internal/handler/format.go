@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+// Форматы тела запросов и ответов /updates и /value.
+const (
+	formatJSON     = "application/json"
+	formatProtobuf = "application/x-protobuf"
+	formatMsgpack  = "application/msgpack"
+)
+
+// normalizeFormat приводит тип содержимого к одному из поддерживаемых форматов.
+// Для неподдерживаемых типов возвращает пустую строку.
+func normalizeFormat(mediaType string) string {
+	switch strings.ToLower(mediaType) {
+	case "application/json":
+		return formatJSON
+	case "application/x-protobuf", "application/protobuf", "application/vnd.google.protobuf":
+		return formatProtobuf
+	case "application/msgpack", "application/x-msgpack", "application/vnd.msgpack":
+		return formatMsgpack
+	default:
+		return ""
+	}
+}
+
+// requestFormat возвращает формат тела запроса по заголовку Content-Type.
+// Отсутствующий или неизвестный тип считается JSON.
+func requestFormat(r *http.Request) string {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if format := normalizeFormat(mediaType); format != "" {
+		return format
+	}
+	return formatJSON
+}
+
+// responseFormat возвращает первый поддерживаемый формат из заголовка Accept
+// или пустую строку, если клиент не запросил ни один из них.
+func responseFormat(r *http.Request) string {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		if format := normalizeFormat(mediaType); format != "" {
+			return format
+		}
+	}
+	return ""
+}
+
+// decodeBatchFormat разбирает тело пакетного запроса в формате format.
+// При превышении maxItems возвращает errTooManyItems.
+func decodeBatchFormat(format string, body []byte, maxItems int) (models.ListMetrics, error) {
+	var metrics models.ListMetrics
+	var err error
+
+	switch format {
+	case formatProtobuf:
+		err = metrics.UnmarshalProto(body, maxItems)
+	case formatMsgpack:
+		err = metrics.UnmarshalMsgpack(body, maxItems)
+	default:
+		return decodeBatch(body, maxItems)
+	}
+
+	if errors.Is(err, models.ErrProtoTooManyItems) || errors.Is(err, models.ErrMsgpackTooManyItems) {
+		return metrics, errTooManyItems
+	}
+	return metrics, err
+}
+
+// decodeMetric разбирает метрику в формате format.
+func decodeMetric(format string, body []byte) (models.Metrics, error) {
+	var metric models.Metrics
+	var err error
+
+	switch format {
+	case formatProtobuf:
+		err = metric.UnmarshalProto(body)
+	case formatMsgpack:
+		_, err = metric.UnmarshalMsg(body)
+	default:
+		err = metric.UnmarshalJSON(body)
+	}
+	return metric, err
+}
+
+// encodeMetric кодирует метрику в формате format.
+func encodeMetric(format string, metric models.Metrics) ([]byte, error) {
+	switch format {
+	case formatProtobuf:
+		return metric.MarshalProto()
+	case formatMsgpack:
+		return metric.MarshalMsg(nil)
+	default:
+		return metric.MarshalJSON()
+	}
+}
+
+// encodeBatchResult кодирует результат пакетного обновления в формате format.
+func encodeBatchResult(format string, result models.BatchResult) ([]byte, error) {
+	switch format {
+	case formatProtobuf:
+		return result.MarshalProto()
+	case formatMsgpack:
+		return result.MarshalMsg(nil)
+	default:
+		return result.MarshalJSON()
+	}
+}
+
+// formatName возвращает короткое имя формата для сообщений об ошибках.
+func formatName(format string) string {
+	switch format {
+	case formatProtobuf:
+		return "protobuf"
+	case formatMsgpack:
+		return "MessagePack"
+	default:
+		return "JSON"
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/levinOo/go-metrics-project/internal/config"
+	"github.com/levinOo/go-metrics-project/internal/logger"
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/repository"
+)
+
+func TestBinaryFormats(t *testing.T) {
+	storage := repository.NewMemStorage()
+	router := NewRouter(storage, logger.NewLogger(), config.Config{MaxBatchItems: 2}, nil)
+
+	post := func(path, contentType, accept string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	delta, value := int64(3), 1.5
+	batch := models.ListMetrics{List: []models.Metrics{
+		{ID: "hits", MType: models.Counter, Delta: &delta},
+		{ID: "load", MType: models.Gauge, Value: &value},
+	}}
+
+	body, _ := batch.MarshalProto()
+	rec := post("/updates", formatProtobuf, formatProtobuf, body)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != formatProtobuf {
+		t.Fatalf("unexpected response %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var result models.BatchResult
+	if err := result.UnmarshalProto(rec.Body.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != "ok" || result.Applied != 2 || len(result.Items) != 2 || result.Items[1].ID != "load" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	body, _ = batch.MarshalMsgpack()
+	rec = post("/updates", "application/x-msgpack", "application/json", body)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != formatJSON {
+		t.Fatalf("unexpected response %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if val, _ := storage.GetCounter("hits"); val != 6 {
+		t.Errorf("expected counter 6, got %d", val)
+	}
+
+	query, _ := (&models.Metrics{ID: "hits", MType: models.Counter}).MarshalMsg(nil)
+	rec = post("/value/", formatMsgpack, "", query)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != formatMsgpack {
+		t.Fatalf("unexpected response %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var metric models.Metrics
+	if _, err := metric.UnmarshalMsg(rec.Body.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if metric.Delta == nil || *metric.Delta != 6 {
+		t.Errorf("unexpected metric: %+v", metric)
+	}
+
+	query, _ = models.Metrics{ID: "load", MType: models.Gauge}.MarshalProto()
+	rec = post("/value/", formatProtobuf, formatJSON, query)
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"id":"load","type":"gauge","value":1.5}` {
+		t.Errorf("unexpected response %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := post("/updates", formatProtobuf, "", []byte{0x0a, 0xff}); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for truncated protobuf, got %d", rec.Code)
+	}
+
+	batch.List = append(batch.List, batch.List[0])
+	body, _ = batch.MarshalProto()
+	if rec := post("/updates", formatProtobuf, "", body); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", rec.Code)
+	}
+	body, _ = batch.MarshalMsgpack()
+	if rec := post("/updates", formatMsgpack, "", body); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", rec.Code)
+	}
+}
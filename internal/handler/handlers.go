@@ -9,7 +9,10 @@ import (
 	"context"
 	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -22,41 +25,64 @@ import (
 	"github.com/go-chi/chi"
 	"github.com/levinOo/go-metrics-project/internal/audit"
 	"github.com/levinOo/go-metrics-project/internal/config"
+	"github.com/levinOo/go-metrics-project/internal/ingest"
 	"github.com/levinOo/go-metrics-project/internal/logger"
 	"github.com/levinOo/go-metrics-project/internal/models"
 	"github.com/levinOo/go-metrics-project/internal/repository"
+	"github.com/levinOo/go-metrics-project/internal/validation"
+	"github.com/mailru/easyjson/jlexer"
 	"go.uber.org/zap"
 )
 
 // NewRouter создает и настраивает HTTP-роутер с использованием chi.
 // Регистрирует все обработчики для работы с метриками и применяет middleware.
+// influxTypes — правила типов полей line protocol, разобранные из cfg.InfluxFieldTypes;
+// nil задает отображение по умолчанию.
 //
 // Зарегистрированные эндпоинты:
 //
 //	GET  /           - получить список всех метрик (HTML или text)
 //	GET  /ping       - проверить доступность базы данных
-//	POST /updates    - пакетное обновление метрик (JSON)
+//	GET  /health     - состояние хранилища и буфера записи (JSON)
+//	POST /updates    - пакетное обновление метрик (JSON или потоковый NDJSON)
+//	POST /api/v1/write - запись метрик в формате InfluxDB line protocol
+//	POST /v1/metrics - прием метрик OTLP/HTTP (protobuf или JSON)
 //	POST /update/    - обновить метрику (JSON)
 //	POST /update/{typeMetric}/{metric}/{value} - обновить метрику (URL params)
 //	POST /value/     - получить значение метрики (JSON)
 //	GET  /value/{typeMetric}/{metric} - получить значение метрики (URL params)
+//	POST /admin/counters/{metric}/reset - обнулить счетчик (требует AdminToken)
+//	POST /admin/counters/{metric}/set/{value} - установить значение счетчика (требует AdminToken)
 //
 // Применяемые middleware (в порядке выполнения):
 //  1. LoggerMiddleware - логирование всех запросов
 //  2. DecompressMiddleware - автоматическая декомпрессия gzip
 //  3. DecryptMiddleware - проверка HMAC-подписей
-func NewRouter(storage repository.Storage, sugar *zap.SugaredLogger, cfg config.Config) *chi.Mux {
+func NewRouter(storage repository.Storage, sugar *zap.SugaredLogger, cfg config.Config, influxTypes *ingest.TypeMapping) *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Use(LoggerMiddleware(sugar))
-	r.Use(DecompressMiddleware())
+	r.Use(DecompressMiddleware(cfg.MaxBodySize))
 	r.Use(DecryptMiddleware(cfg.Key))
 
 	r.Get("/", GetListHandler(storage))
 	r.Get("/ping", PingHandler(storage))
+	r.Get("/health", HealthHandler(storage))
 
-	r.Post("/updates", UpdatesValuesHandler(storage, cfg.Key, cfg.AuditFile, cfg.AuditURL))
-	r.Post("/updates/", UpdatesValuesHandler(storage, cfg.Key, cfg.AuditFile, cfg.AuditURL))
+	limits := BatchLimits{MaxItems: cfg.MaxBatchItems, ChunkSize: cfg.StreamChunkSize}
+	r.Post("/updates", UpdatesValuesHandler(storage, cfg.Key, cfg.AuditFile, cfg.AuditURL, limits))
+	r.Post("/updates/", UpdatesValuesHandler(storage, cfg.Key, cfg.AuditFile, cfg.AuditURL, limits))
+
+	if influxTypes == nil {
+		influxTypes, _ = ingest.ParseTypeMapping("")
+	}
+	r.Post("/api/v1/write", InfluxWriteHandler(storage, influxTypes, cfg.InfluxPrecision, cfg.Key, cfg.AuditFile, cfg.AuditURL, limits))
+
+	otlp := ingest.NewOTLPConverter(ingest.OTLPOptions{
+		ResourceLabels: ingest.ParseResourceLabels(cfg.OTLPResourceLabels),
+		ResourcePrefix: cfg.OTLPResourcePrefix,
+	})
+	r.Post("/v1/metrics", OTLPHandler(storage, otlp, cfg.AuditFile, cfg.AuditURL, limits))
 
 	r.Route("/update", func(r chi.Router) {
 		r.Post("/", UpdateJSONHandler(storage, cfg.Key))
@@ -69,6 +95,14 @@ func NewRouter(storage repository.Storage, sugar *zap.SugaredLogger, cfg config.
 		r.Post("/", GetJSONHandler(storage, cfg.Key))
 	})
 
+	if cfg.AdminToken != "" {
+		r.Route("/admin/counters/{metric}", func(r chi.Router) {
+			r.Use(AdminAuthMiddleware(cfg.AdminToken))
+			r.Post("/reset", ResetCounterHandler(storage, cfg.AuditFile, cfg.AuditURL))
+			r.Post("/set/{value}", SetCounterHandler(storage, cfg.AuditFile, cfg.AuditURL))
+		})
+	}
+
 	return r
 }
 
@@ -109,12 +143,12 @@ func LoggerMiddleware(sugar *zap.SugaredLogger) func(h http.Handler) http.Handle
 // DecompressMiddleware создает middleware для автоматической декомпрессии gzip-сжатых запросов.
 // Проверяет заголовок Content-Encoding и при значении "gzip" распаковывает тело запроса.
 //
-// После декомпрессии:
-//   - Заменяет r.Body на распакованные данные
-//   - Обновляет r.ContentLength для корректной обработки
+// Тело распаковывается потоково по мере чтения обработчиком, а не целиком в память.
+// Если maxBytes больше нуля, чтение тела (после распаковки) сверх maxBytes байт
+// завершается ошибкой *http.MaxBytesError, и обработчик отвечает 413 Request Entity Too Large.
 //
-// Возвращает HTTP 400 при ошибках декомпрессии.
-func DecompressMiddleware() func(h http.Handler) http.Handler {
+// Возвращает HTTP 400 при некорректном gzip-заголовке.
+func DecompressMiddleware(maxBytes int64) func(h http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 			if r.Header.Get("Content-Encoding") == "gzip" {
@@ -125,14 +159,11 @@ func DecompressMiddleware() func(h http.Handler) http.Handler {
 				}
 				defer gz.Close()
 
-				body, err := io.ReadAll(gz)
-				if err != nil {
-					http.Error(rw, "Failed to read decompressed body", http.StatusInternalServerError)
-					return
-				}
-
-				r.Body = io.NopCloser(bytes.NewReader(body))
-				r.ContentLength = int64(len(body))
+				r.Body = &limitedBody{r: gz, c: r.Body, remaining: maxBytes, limit: maxBytes}
+				r.ContentLength = -1
+				r.Header.Del("Content-Encoding")
+			} else if maxBytes > 0 {
+				r.Body = http.MaxBytesReader(rw, r.Body, maxBytes)
 			}
 
 			h.ServeHTTP(rw, r)
@@ -140,6 +171,50 @@ func DecompressMiddleware() func(h http.Handler) http.Handler {
 	}
 }
 
+// limitedBody ограничивает количество байт, прочитанных из распакованного тела.
+// Нулевой limit снимает ограничение.
+type limitedBody struct {
+	r         io.Reader
+	c         io.Closer
+	remaining int64
+	limit     int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.limit <= 0 {
+		return b.r.Read(p)
+	}
+	if b.remaining <= 0 {
+		// Проверяем, что тело действительно длиннее лимита, а не закончилось ровно на нем.
+		var one [1]byte
+		if _, err := io.ReadFull(b.r, one[:]); err != nil {
+			return 0, err
+		}
+		return 0, &http.MaxBytesError{Limit: b.limit}
+	}
+
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.r.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.c.Close()
+}
+
+// bodyErrorStatus возвращает HTTP-код для ошибки чтения тела запроса:
+// 413 при превышении лимита размера, иначе 400.
+func bodyErrorStatus(err error) int {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
 // DecryptMiddleware создает middleware для проверки HMAC SHA256 подписей запросов.
 // Проверяет заголовки "Hash" или "HashSHA256" и сравнивает с вычисленной подписью.
 //
@@ -152,17 +227,15 @@ func DecompressMiddleware() func(h http.Handler) http.Handler {
 //
 //	key: секретный ключ для HMAC. Если пустой, проверка отключена.
 //
-// Пропускает запросы без подписи или с подписью "none".
+// Пропускает запросы без подписи или с подписью "none". Потоковые NDJSON-запросы
+// не буферизуются: их подпись проверяет обработчик по мере чтения тела.
 // Возвращает HTTP 400 при несовпадении подписей или некорректном формате.
 func DecryptMiddleware(key string) func(h http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
-			receivedHash := r.Header.Get("Hash")
-			if receivedHash == "" {
-				receivedHash = r.Header.Get("HashSHA256")
-			}
+			receivedHash := requestHash(r)
 
-			if receivedHash == "" || receivedHash == "none" {
+			if receivedHash == "" || isNDJSON(r) {
 				h.ServeHTTP(rw, r)
 				return
 			}
@@ -171,7 +244,7 @@ func DecryptMiddleware(key string) func(h http.Handler) http.Handler {
 				body, err := io.ReadAll(r.Body)
 				if err != nil {
 					log.Println("error reading r.Body:", err)
-					http.Error(rw, "read body error", http.StatusBadRequest)
+					http.Error(rw, "read body error", bodyErrorStatus(err))
 					return
 				}
 
@@ -201,6 +274,19 @@ func DecryptMiddleware(key string) func(h http.Handler) http.Handler {
 	}
 }
 
+// requestHash возвращает подпись запроса из заголовка Hash или HashSHA256.
+// Подпись "none" считается отсутствующей.
+func requestHash(r *http.Request) string {
+	hash := r.Header.Get("Hash")
+	if hash == "" {
+		hash = r.Header.Get("HashSHA256")
+	}
+	if hash == "none" {
+		return ""
+	}
+	return hash
+}
+
 // PingHandler возвращает обработчик для проверки доступности базы данных.
 // Выполняет ping к хранилищу с таймаутом 2 секунды.
 //
@@ -224,78 +310,253 @@ func PingHandler(dbConn repository.Storage) http.HandlerFunc {
 	}
 }
 
+// HealthHandler возвращает обработчик, сообщающий состояние хранилища.
+// Если хранилище поддерживает деградированный режим (repository.BufferedStorage),
+// в ответ включаются признак деградации, глубина и емкость буфера записи.
+//
+// Формат ответа:
+//
+//	{"status":"degraded","degraded":true,"buffer_depth":42,"buffer_capacity":10000,"rejected":0}
+//
+// Ответы:
+//
+//	200 OK - состояние возвращено (в том числе в деградированном режиме)
+func HealthHandler(storage repository.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		health := repository.Health{Status: "ok"}
+		if hs, ok := storage.(interface{ Health() repository.Health }); ok {
+			health = hs.Health()
+		}
+
+		data, err := json.Marshal(health)
+		if err != nil {
+			http.Error(rw, "encode error", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusOK)
+		if _, err := rw.Write(data); err != nil {
+			log.Printf("health write error: %v", err)
+		}
+	}
+}
+
+// storageErrorStatus возвращает HTTP-код для ошибки записи в хранилище:
+// 400 для метрики, не прошедшей проверку (в том числе при переполнении счетчика),
+// 503 при переполнении буфера деградированного режима или недоступности хранилища,
+// иначе 500.
+func storageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, validation.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrBufferFull), errors.Is(err, repository.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// readErrorStatus возвращает HTTP-код для ошибки чтения метрики: 503, пока
+// хранилище в деградированном режиме, иначе 404.
+func readErrorStatus(err error) int {
+	if errors.Is(err, repository.ErrUnavailable) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusNotFound
+}
+
+// clientIP возвращает IP-адрес клиента для событий аудита.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// BatchLimits задает ограничения пакетных запросов /updates.
+type BatchLimits struct {
+	// MaxItems — максимальное количество метрик в одном запросе. Ноль снимает ограничение.
+	MaxItems int
+
+	// ChunkSize — количество метрик, после накопления которых потоковый
+	// NDJSON-запрос записывается в хранилище.
+	ChunkSize int
+}
+
+// decodeBatch разбирает тело пакетного запроса: JSON-массив метрик или
+// объект ListMetrics в формате снимка хранилища. Если maxItems больше нуля,
+// массив длиннее maxItems отклоняется с ошибкой errTooManyItems.
+func decodeBatch(body []byte, maxItems int) (models.ListMetrics, error) {
+	var metrics models.ListMetrics
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		err := metrics.UnmarshalJSON(trimmed)
+		if err == nil && maxItems > 0 && len(metrics.List) > maxItems {
+			return metrics, errTooManyItems
+		}
+		return metrics, err
+	}
+
+	in := jlexer.Lexer{Data: trimmed}
+	in.Delim('[')
+	for !in.IsDelim(']') {
+		if maxItems > 0 && len(metrics.List) == maxItems {
+			return metrics, errTooManyItems
+		}
+
+		var m models.Metrics
+		m.UnmarshalEasyJSON(&in)
+		metrics.List = append(metrics.List, m)
+		in.WantComma()
+	}
+	in.Delim(']')
+	in.Consumed()
+
+	return metrics, in.Error()
+}
+
 // UpdatesValuesHandler возвращает обработчик для пакетного обновления метрик.
-// Принимает массив метрик в формате JSON и обновляет их одной транзакцией.
+// Принимает массив метрик, проверяет каждую метрику и записывает корректные
+// метрики одной транзакцией.
 //
 // Формат запроса:
 //
-//	POST /updates
+//	POST /updates?mode=best-effort|atomic
 //	Content-Type: application/json
 //	Body: [{"id":"metric1","type":"gauge","value":42.5}, ...]
 //
+// Кроме JSON тело может передаваться в формате application/x-protobuf (сообщение
+// MetricList из api/metrics.proto) или application/msgpack (массив метрик). Формат
+// ответа выбирается по заголовку Accept среди тех же форматов, иначе ответ в HTML.
+//
+// Режим best-effort (по умолчанию) записывает все корректные метрики и отклоняет
+// остальные, в том числе счетчики, сумма которых с сохраненным значением
+// переполняет int64. Режим atomic записывает пакет, только если все метрики корректны.
+// В JSON-ответе для каждой метрики указан статус: applied, rejected (с причиной)
+// или merged, если метрика объединена с предыдущей метрикой с тем же именем.
+// Если база данных недоступна и пакет помещен в буфер записи, вместо applied и
+// merged указывается статус queued.
+//
+// Запрос с Content-Type: application/x-ndjson обрабатывается потоково,
+// см. streamUpdates.
+//
 // Дополнительные функции:
-//   - Создает событие аудита с IP-адресом клиента
+//   - Создает событие аудита с IP-адресом клиента для записанных метрик
 //   - Добавляет HMAC-подпись в ответ, если настроен ключ
-//   - Поддерживает ответы в JSON или HTML формате
+//   - Поддерживает ответы в JSON, protobuf, MessagePack или HTML формате
 //
 // Ответы:
 //
-//	200 OK - метрики успешно обновлены
-//	400 Bad Request - некорректный формат JSON
+//	200 OK - пакет обработан, результаты по метрикам в теле ответа
+//	400 Bad Request - некорректное тело запроса, неизвестный режим или переполнение
+//	    сохраненного счетчика в режиме atomic
+//	413 Request Entity Too Large - превышен размер тела или количество метрик
+//	422 Unprocessable Entity - пакет отклонен: в режиме atomic есть некорректные
+//	    метрики или в пакете нет ни одной корректной метрики
 //	500 Internal Server Error - ошибка при сохранении
-func UpdatesValuesHandler(storage repository.Storage, key, path, url string) http.HandlerFunc {
+//	503 Service Unavailable - база недоступна и буфер записи переполнен
+func UpdatesValuesHandler(storage repository.Storage, key, path, url string, limits BatchLimits) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			http.Error(rw, "failed to read body", http.StatusBadRequest)
+		storage := repository.WithContext(storage, r.Context())
+
+		mode := r.URL.Query().Get("mode")
+		switch mode {
+		case "":
+			mode = models.BatchModeBestEffort
+		case models.BatchModeBestEffort, models.BatchModeAtomic:
+		default:
+			http.Error(rw, "unknown batch mode", http.StatusBadRequest)
 			return
 		}
+
 		defer r.Body.Close()
 
-		var metrics models.ListMetrics
-		err = metrics.UnmarshalJSON(body)
-		if err != nil {
-			http.Error(rw, "invalid JSON format", http.StatusBadRequest)
+		if isNDJSON(r) {
+			streamUpdates(rw, r, storage, key, path, url, mode, limits)
 			return
 		}
 
-		err = storage.InsertMetricsBatch(metrics)
+		body, err := io.ReadAll(r.Body)
 		if err != nil {
-			http.Error(rw, "internal server error", http.StatusInternalServerError)
+			http.Error(rw, "failed to read body", bodyErrorStatus(err))
 			return
 		}
 
-		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		format := requestFormat(r)
+		metrics, err := decodeBatchFormat(format, body, limits.MaxItems)
+		if errors.Is(err, errTooManyItems) {
+			http.Error(rw, fmt.Sprintf("%v: limit %d", err, limits.MaxItems), http.StatusRequestEntityTooLarge)
+			return
+		}
 		if err != nil {
-			ip = r.RemoteAddr
+			http.Error(rw, "invalid "+formatName(format)+" format", http.StatusBadRequest)
+			return
 		}
-		audit.NewAuditEvent(metrics, path, url, ip)
 
-		data := []byte(`{"status":"ok"}`)
+		valid, result := repository.PrepareBatch(metrics, mode)
 
-		if key != "" {
-			mac := hmac.New(sha256.New, []byte(key))
-			mac.Write(data)
-			sig := mac.Sum(nil)
-			rw.Header().Set("HashSHA256", hex.EncodeToString(sig))
+		status := http.StatusOK
+		if result.Status == "rejected" && len(result.Items) > 0 {
+			status = http.StatusUnprocessableEntity
+		} else if len(valid.List) > 0 {
+			written, err := repository.InsertBatch(storage, valid, &result)
+			if err != nil {
+				http.Error(rw, "internal server error", storageErrorStatus(err))
+				return
+			}
+			if result.Status == "rejected" {
+				status = http.StatusUnprocessableEntity
+			}
+
+			audit.NewAuditEvent(written, path, url, clientIP(r))
 		}
 
-		if strings.Contains(r.Header.Get("Accept"), "application/json") {
-			rw.Header().Set("Content-Type", "application/json")
-			rw.WriteHeader(http.StatusOK)
+		writeBatchResult(rw, r, key, status, result)
+	}
+}
 
-			_, err := rw.Write(data)
-			if err != nil {
-				log.Printf("json write error: %v", err)
-			}
-		} else {
-			rw.Header().Set("Content-Type", "text/html")
-			rw.WriteHeader(http.StatusOK)
+// writeBatchResult записывает результат пакетного обновления в формате из заголовка
+// Accept (JSON, protobuf или MessagePack) или в HTML и подписывает закодированный
+// результат ключом key. Для HTML-ответа подписывается JSON-представление результата.
+func writeBatchResult(rw http.ResponseWriter, r *http.Request, key string, status int, result models.BatchResult) {
+	format := responseFormat(r)
+	encoding := format
+	if encoding == "" {
+		encoding = formatJSON
+	}
 
-			_, err := rw.Write([]byte("<html><body><h1>OK</h1></body></html>"))
-			if err != nil {
-				log.Printf("html write error: %v", err)
-			}
+	data, err := encodeBatchResult(encoding, result)
+	if err != nil {
+		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if key != "" {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write(data)
+		sig := mac.Sum(nil)
+		rw.Header().Set("HashSHA256", hex.EncodeToString(sig))
+	}
+
+	if format != "" {
+		rw.Header().Set("Content-Type", format)
+		rw.WriteHeader(status)
+
+		_, err := rw.Write(data)
+		if err != nil {
+			log.Printf("%s write error: %v", formatName(format), err)
+		}
+	} else {
+		rw.Header().Set("Content-Type", "text/html")
+		rw.WriteHeader(status)
+
+		_, err := fmt.Fprintf(rw, "<html><body><h1>%s</h1><p>applied: %d, merged: %d, rejected: %d, queued: %d</p></body></html>",
+			strings.ToUpper(result.Status), result.Applied, result.Merged, result.Rejected, result.Queued)
+		if err != nil {
+			log.Printf("html write error: %v", err)
 		}
 	}
 }
@@ -316,10 +577,15 @@ func UpdatesValuesHandler(storage repository.Storage, key, path, url string) htt
 // Ответы:
 //
 //	200 OK - метрика успешно обновлена
-//	400 Bad Request - некорректный тип или значение
+//	202 Accepted - база недоступна, метрика помещена в буфер записи
+//	400 Bad Request - некорректное имя, тип или значение (в том числе NaN и Inf
+//	    для gauge и переполнение для counter)
 //	404 Not Found - отсутствует имя метрики
+//	500 Internal Server Error - ошибка при сохранении
 func UpdateValueHandler(storage repository.Storage, sugar *zap.SugaredLogger) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
+		storage := repository.WithContext(storage, r.Context())
+
 		nameMetric := chi.URLParam(r, "metric")
 		valueMetric := chi.URLParam(r, "value")
 		typeMetric := chi.URLParam(r, "typeMetric")
@@ -329,30 +595,50 @@ func UpdateValueHandler(storage repository.Storage, sugar *zap.SugaredLogger) ht
 			return
 		}
 
+		v := validation.Default()
+		if err := v.Name(nameMetric); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var err error
 		switch typeMetric {
 		case "gauge":
-			valueGauge, err := strconv.ParseFloat(valueMetric, 64)
-			if err != nil {
+			valueGauge, parseErr := strconv.ParseFloat(valueMetric, 64)
+			if parseErr != nil {
 				http.Error(rw, "Invalid type of value", http.StatusBadRequest)
 				return
 			}
-			storage.SetGauge(nameMetric, repository.Gauge(valueGauge))
+			if err := v.Gauge(valueGauge); err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			err = storage.SetGauge(nameMetric, repository.Gauge(valueGauge))
 			sugar.Debugw("Set gauge metric", "name", nameMetric, "value", valueGauge)
 		case "counter":
-			valueCounter, err := strconv.ParseInt(valueMetric, 10, 64)
-			if err != nil {
+			valueCounter, parseErr := strconv.ParseInt(valueMetric, 10, 64)
+			if parseErr != nil {
 				http.Error(rw, "Invalid type of value", http.StatusBadRequest)
 				return
 			}
-			storage.SetCounter(nameMetric, repository.Counter(valueCounter))
+			err = storage.SetCounter(nameMetric, repository.Counter(valueCounter))
 			sugar.Debugw("Set counter metric", "name", nameMetric, "value", valueCounter)
 		default:
 			http.Error(rw, "Unknown type of metric", http.StatusBadRequest)
 			return
 		}
 
-		rw.WriteHeader(http.StatusOK)
-		_, err := rw.Write([]byte("OK"))
+		status, body := http.StatusOK, "OK"
+		if errors.Is(err, repository.ErrQueued) {
+			status, body = http.StatusAccepted, "Queued"
+		} else if err != nil {
+			sugar.Errorw("Failed to update metric", "name", nameMetric, "error", err)
+			http.Error(rw, err.Error(), storageErrorStatus(err))
+			return
+		}
+
+		rw.WriteHeader(status)
+		_, err = rw.Write([]byte(body))
 		if err != nil {
 			log.Printf("write status code error: %v", err)
 		}
@@ -372,41 +658,48 @@ func UpdateValueHandler(storage repository.Storage, sugar *zap.SugaredLogger) ht
 //
 //	Body: {"id":"requests","type":"counter","delta":100}
 //
+// Метрика проверяется пакетом validation: некорректное имя или тип, отсутствующее
+// или бесконечное значение и переполнение счетчика возвращают 400 Bad Request.
+// Если база недоступна и метрика помещена в буфер записи, возвращается
+// 202 Accepted с телом {"status":"queued"}.
 // Добавляет HMAC-подпись в ответ, если настроен ключ.
 // Поддерживает content negotiation (JSON/HTML).
 func UpdateJSONHandler(storage repository.Storage, key string) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
+		storage := repository.WithContext(storage, r.Context())
+
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
-			http.Error(rw, "failed to read body", http.StatusBadRequest)
+			http.Error(rw, "failed to read body", bodyErrorStatus(err))
 			return
 		}
 		defer r.Body.Close()
 
-		var metric models.Metrics
-		err = metric.UnmarshalJSON(body)
+		format := requestFormat(r)
+		metric, err := decodeMetric(format, body)
 		if err != nil {
-			http.Error(rw, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+			http.Error(rw, "invalid "+formatName(format)+": "+err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		switch metric.MType {
-		case "gauge":
-			err := storage.SetGauge(metric.ID, repository.Gauge(*metric.Value))
-			if err != nil {
-				log.Printf("failed to set gauge %s: %v", metric.ID, err)
-			}
-		case "counter":
-			err := storage.SetCounter(metric.ID, repository.Counter(*metric.Delta))
-			if err != nil {
-				log.Printf("failed to set counter %s: %v", metric.ID, err)
-			}
-		default:
-			http.Error(rw, "unknown type of metric", http.StatusBadRequest)
+		if err := validation.Default().Metric(metric); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		data := []byte(`{"status":"ok"}`)
+		if metric.MType == models.Gauge {
+			err = storage.SetGauge(metric.ID, repository.Gauge(*metric.Value))
+		} else {
+			err = storage.SetCounter(metric.ID, repository.Counter(*metric.Delta))
+		}
+		status, data := http.StatusOK, []byte(`{"status":"ok"}`)
+		if errors.Is(err, repository.ErrQueued) {
+			status, data = http.StatusAccepted, []byte(`{"status":"queued"}`)
+		} else if err != nil {
+			log.Printf("failed to set %s %s: %v", metric.MType, metric.ID, err)
+			http.Error(rw, err.Error(), storageErrorStatus(err))
+			return
+		}
 
 		if key != "" {
 			mac := hmac.New(sha256.New, []byte(key))
@@ -417,7 +710,7 @@ func UpdateJSONHandler(storage repository.Storage, key string) http.HandlerFunc
 
 		if strings.Contains(r.Header.Get("Accept"), "application/json") {
 			rw.Header().Set("Content-Type", "application/json")
-			rw.WriteHeader(http.StatusOK)
+			rw.WriteHeader(status)
 
 			_, err = rw.Write(data)
 			if err != nil {
@@ -425,9 +718,9 @@ func UpdateJSONHandler(storage repository.Storage, key string) http.HandlerFunc
 			}
 		} else {
 			rw.Header().Set("Content-Type", "text/html")
-			rw.WriteHeader(http.StatusOK)
+			rw.WriteHeader(status)
 
-			_, err = rw.Write([]byte("<html><body><h1>OK</h1></body></html>"))
+			_, err = rw.Write([]byte("<html><body><h1>" + http.StatusText(status) + "</h1></body></html>"))
 			if err != nil {
 				log.Printf("html write error: %v", err)
 			}
@@ -448,6 +741,10 @@ func UpdateJSONHandler(storage repository.Storage, key string) http.HandlerFunc
 //
 //	{"id":"cpu","type":"gauge","value":45.5}
 //
+// Запрос также принимается в форматах application/x-protobuf (сообщение Metric из
+// api/metrics.proto) и application/msgpack. Ответ кодируется в формате из заголовка
+// Accept, а если он не указан — в формате запроса.
+//
 // Дополнительные функции:
 //   - Добавляет HMAC-подпись в заголовок HashSHA256
 //   - Поддерживает gzip-сжатие ответа (Accept-Encoding: gzip)
@@ -455,21 +752,27 @@ func UpdateJSONHandler(storage repository.Storage, key string) http.HandlerFunc
 // Ответы:
 //
 //	200 OK - метрика найдена и возвращена
-//	400 Bad Request - некорректный JSON или неизвестный тип
+//	400 Bad Request - некорректный JSON, имя или неизвестный тип
 //	404 Not Found - метрика не найдена
+//	503 Service Unavailable - база данных недоступна
 func GetJSONHandler(storage repository.Storage, key string) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
-			http.Error(rw, "failed to read body", http.StatusBadRequest)
+			http.Error(rw, "failed to read body", bodyErrorStatus(err))
 			return
 		}
 		defer r.Body.Close()
 
-		var metric models.Metrics
-		err = metric.UnmarshalJSON(body)
+		format := requestFormat(r)
+		metric, err := decodeMetric(format, body)
 		if err != nil {
-			http.Error(rw, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+			http.Error(rw, "invalid "+formatName(format)+": "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := validation.Default().Name(metric.ID); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
 			return
 		}
 
@@ -478,7 +781,7 @@ func GetJSONHandler(storage repository.Storage, key string) http.HandlerFunc {
 			val, err := storage.GetGauge(metric.ID)
 			if err != nil {
 				log.Printf("read gauge error: %v", err)
-				rw.WriteHeader(http.StatusNotFound)
+				rw.WriteHeader(readErrorStatus(err))
 				return
 			}
 			metric.Value = new(float64)
@@ -488,7 +791,7 @@ func GetJSONHandler(storage repository.Storage, key string) http.HandlerFunc {
 			val, err := storage.GetCounter(metric.ID)
 			if err != nil {
 				log.Printf("read counter error: %v", err)
-				rw.WriteHeader(http.StatusNotFound)
+				rw.WriteHeader(readErrorStatus(err))
 				return
 			}
 			metric.Delta = new(int64)
@@ -499,13 +802,17 @@ func GetJSONHandler(storage repository.Storage, key string) http.HandlerFunc {
 			return
 		}
 
-		data, err := metric.MarshalJSON()
+		if accepted := responseFormat(r); accepted != "" {
+			format = accepted
+		}
+
+		data, err := encodeMetric(format, metric)
 		if err != nil {
 			http.Error(rw, "encode error", http.StatusInternalServerError)
 			return
 		}
 
-		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Content-Type", format)
 
 		if key != "" {
 			mac := hmac.New(sha256.New, []byte(key))
@@ -550,18 +857,24 @@ func GetJSONHandler(storage repository.Storage, key string) http.HandlerFunc {
 // Ответы:
 //
 //	200 OK - возвращает значение метрики в виде текста
-//	400 Bad Request - неизвестный тип метрики
+//	400 Bad Request - некорректное имя или неизвестный тип метрики
 //	404 Not Found - метрика не найдена
+//	503 Service Unavailable - база данных недоступна
 func GetValueHandler(storage repository.Storage) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
 		nameMetric := chi.URLParam(r, "metric")
 
+		if err := validation.Default().Name(nameMetric); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		switch chi.URLParam(r, "typeMetric") {
 		case "gauge":
 			val, err := storage.GetGauge(nameMetric)
 			if err != nil {
 				log.Printf("write error: %v", err)
-				rw.WriteHeader(http.StatusNotFound)
+				rw.WriteHeader(readErrorStatus(err))
 				return
 			}
 			rw.WriteHeader(http.StatusOK)
@@ -573,7 +886,7 @@ func GetValueHandler(storage repository.Storage) http.HandlerFunc {
 			val, err := storage.GetCounter(nameMetric)
 			if err != nil {
 				log.Printf("write error: %v", err)
-				rw.WriteHeader(http.StatusNotFound)
+				rw.WriteHeader(readErrorStatus(err))
 				return
 			}
 			rw.WriteHeader(http.StatusOK)
@@ -612,7 +925,12 @@ func GetListHandler(storage repository.Storage) http.HandlerFunc {
 		accept := r.Header.Get("Accept")
 		metrics, err := storage.GetAll()
 		if err != nil {
-			http.Error(rw, fmt.Sprintf("failed to get all metrics: %v", err), http.StatusInternalServerError)
+			status := http.StatusInternalServerError
+			if errors.Is(err, repository.ErrUnavailable) {
+				status = http.StatusServiceUnavailable
+			}
+			http.Error(rw, fmt.Sprintf("failed to get all metrics: %v", err), status)
+			return
 		}
 
 		if strings.Contains(accept, "text/html") {
@@ -692,3 +1010,119 @@ func GetListHandler(storage repository.Storage) http.HandlerFunc {
 		}
 	}
 }
+
+// AdminAuthMiddleware создает middleware, пропускающий только запросы
+// с заголовком "Authorization: Bearer <token>". Токен сравнивается за постоянное время.
+//
+// Ответы:
+//
+//	401 Unauthorized - токен отсутствует или не совпадает
+func AdminAuthMiddleware(token string) func(h http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				rw.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(rw, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			h.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// ResetCounterHandler возвращает обработчик для обнуления счетчика.
+// Действие записывается в аудит с Action = "counter_reset".
+//
+// Формат запроса:
+//
+//	POST /admin/counters/{metric}/reset
+//	Authorization: Bearer <token>
+//
+// Ответы:
+//
+//	200 OK - счетчик обнулен, в теле новое значение в формате JSON
+//	400 Bad Request - некорректное имя метрики
+//	404 Not Found - счетчик не найден
+//	500 Internal Server Error - ошибка хранилища
+//	503 Service Unavailable - хранилище недоступно, действие не выполнено
+func ResetCounterHandler(storage repository.Storage, path, url string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		storage := repository.WithContext(storage, r.Context())
+
+		name := chi.URLParam(r, "metric")
+		if err := validation.Default().Name(name); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := storage.ResetCounter(name); err != nil {
+			log.Printf("failed to reset counter %s: %v", name, err)
+			if errors.Is(err, repository.ErrMetricNotFound) {
+				http.Error(rw, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(rw, "internal server error", storageErrorStatus(err))
+			return
+		}
+
+		audit.NewAdminAuditEvent(audit.ActionCounterReset, name, 0, path, url, clientIP(r))
+		writeCounter(rw, name, 0)
+	}
+}
+
+// SetCounterHandler возвращает обработчик для установки абсолютного значения счетчика.
+// Действие записывается в аудит с Action = "counter_set" и новым значением.
+//
+// Формат запроса:
+//
+//	POST /admin/counters/{metric}/set/{value}
+//	Authorization: Bearer <token>
+//
+// Ответы:
+//
+//	200 OK - значение установлено, в теле новое значение в формате JSON
+//	400 Bad Request - некорректное имя метрики или значение
+//	500 Internal Server Error - ошибка хранилища
+//	503 Service Unavailable - хранилище недоступно, действие не выполнено
+func SetCounterHandler(storage repository.Storage, path, url string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		storage := repository.WithContext(storage, r.Context())
+
+		name := chi.URLParam(r, "metric")
+		if err := validation.Default().Name(name); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		value, err := strconv.ParseInt(chi.URLParam(r, "value"), 10, 64)
+		if err != nil {
+			http.Error(rw, "Invalid type of value", http.StatusBadRequest)
+			return
+		}
+
+		if err := storage.SetCounterValue(name, repository.Counter(value)); err != nil {
+			log.Printf("failed to set counter %s: %v", name, err)
+			http.Error(rw, "internal server error", storageErrorStatus(err))
+			return
+		}
+
+		audit.NewAdminAuditEvent(audit.ActionCounterSet, name, value, path, url, clientIP(r))
+		writeCounter(rw, name, value)
+	}
+}
+
+// writeCounter записывает в ответ счетчик в формате JSON.
+func writeCounter(rw http.ResponseWriter, name string, value int64) {
+	data, err := models.Metrics{ID: name, MType: models.Counter, Delta: &value}.MarshalJSON()
+	if err != nil {
+		http.Error(rw, "encode error", http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	if _, err := rw.Write(data); err != nil {
+		log.Printf("json write error: %v", err)
+	}
+}
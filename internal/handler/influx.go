@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/levinOo/go-metrics-project/internal/audit"
+	"github.com/levinOo/go-metrics-project/internal/ingest"
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/repository"
+)
+
+// InfluxWriteHandler возвращает обработчик для записи метрик в формате InfluxDB
+// line protocol. Каждое поле точки становится метрикой с именем
+// measurement_field.tag:value, тип определяется правилами mapping.
+//
+// Формат запроса:
+//
+//	POST /api/v1/write?precision=ns|us|ms|s&mode=best-effort|atomic
+//	Body: cpu,host=server01 usage_idle=98.5,usage_user=1.2 1700000000000000000
+//
+// Если precision не указан, используется defaultPrecision. Сжатие gzip, проверка
+// HMAC-подписи и аудит выполняются так же, как для /updates. Метрики записываются
+// одним вызовом InsertMetricsBatch.
+//
+// Ответы:
+//
+//	202 Accepted - база недоступна, все метрики помещены в буфер записи
+//	204 No Content - все метрики записаны
+//	400 Bad Request - есть ошибочные строки или метрики; результаты по ним в теле
+//	    ответа, поле index содержит номер строки. В режиме best-effort корректные
+//	    метрики при этом записаны
+//	413 Request Entity Too Large - превышен размер тела или количество метрик
+//	500 Internal Server Error - ошибка при сохранении
+func InfluxWriteHandler(storage repository.Storage, mapping *ingest.TypeMapping, defaultPrecision, key, path, url string, limits BatchLimits) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		storage := repository.WithContext(storage, r.Context())
+
+		defer r.Body.Close()
+
+		mode := r.URL.Query().Get("mode")
+		switch mode {
+		case "":
+			mode = models.BatchModeBestEffort
+		case models.BatchModeBestEffort, models.BatchModeAtomic:
+		default:
+			http.Error(rw, "unknown batch mode", http.StatusBadRequest)
+			return
+		}
+
+		precisionParam := r.URL.Query().Get("precision")
+		if precisionParam == "" {
+			precisionParam = defaultPrecision
+		}
+		precision, err := ingest.ParsePrecision(precisionParam)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		items, lineErrs, err := mapping.ReadLines(r.Body, precision, limits.MaxItems)
+		if errors.Is(err, ingest.ErrTooManyItems) {
+			http.Error(rw, fmt.Sprintf("%v: limit %d", err, limits.MaxItems), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(rw, "failed to read body", bodyErrorStatus(err))
+			return
+		}
+
+		metrics := models.ListMetrics{List: make([]models.Metrics, len(items))}
+		for i, item := range items {
+			metrics.List[i] = item.Metric
+		}
+
+		valid, result := repository.PrepareBatch(metrics, mode)
+		for i := range result.Items {
+			result.Items[i].Index = items[i].Line
+		}
+
+		if mode == models.BatchModeAtomic && len(lineErrs) > 0 {
+			// Ошибочные строки отклоняют пакет целиком.
+			valid.List = nil
+			for i := range result.Items {
+				if item := &result.Items[i]; item.Status != models.BatchRejected {
+					item.Status = models.BatchRejected
+					item.Reason = "batch rejected"
+				}
+			}
+			result.Rejected = len(result.Items)
+			result.Applied = 0
+			result.Merged = 0
+		}
+		for _, lineErr := range lineErrs {
+			result.Items = append(result.Items, models.BatchItemResult{
+				Index:  lineErr.Line,
+				Status: models.BatchRejected,
+				Reason: lineErr.Err.Error(),
+			})
+			result.Rejected++
+		}
+		sort.SliceStable(result.Items, func(i, j int) bool { return result.Items[i].Index < result.Items[j].Index })
+
+		if len(valid.List) > 0 {
+			written, err := repository.InsertBatch(storage, valid, &result)
+			if err != nil {
+				http.Error(rw, "internal server error", storageErrorStatus(err))
+				return
+			}
+			audit.NewAuditEvent(written, path, url, clientIP(r))
+		}
+
+		switch {
+		case result.Rejected == 0 && result.Queued > 0:
+			rw.WriteHeader(http.StatusAccepted)
+			return
+		case result.Rejected == 0:
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		case result.Applied == 0 && result.Merged == 0 && result.Queued == 0:
+			result.Status = "rejected"
+		default:
+			result.Status = "partial"
+		}
+
+		writeBatchResult(rw, r, key, http.StatusBadRequest, result)
+	}
+}
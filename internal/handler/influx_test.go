@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/levinOo/go-metrics-project/internal/config"
+	"github.com/levinOo/go-metrics-project/internal/ingest"
+	"github.com/levinOo/go-metrics-project/internal/logger"
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/repository"
+)
+
+func TestInfluxWriteHandler(t *testing.T) {
+	storage := repository.NewMemStorage()
+	mapping, err := ingest.ParseTypeMapping("*_requests=counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := NewRouter(storage, logger.NewLogger(), config.Config{}, mapping)
+
+	write := func(query, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/write"+query, strings.NewReader(body))
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := write("?precision=s", "web requests=2i,load=0.5 1700000000\nweb requests=3i,load=0.7 1700000001\n")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+	if val, _ := storage.GetCounter("web_requests"); val != 5 {
+		t.Errorf("expected counter 5, got %d", val)
+	}
+	if val, _ := storage.GetGauge("web_load"); val != 0.7 {
+		t.Errorf("expected latest gauge 0.7, got %v", val)
+	}
+
+	rec = write("?mode=atomic", "web load=1\nweb load=\n")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	var result models.BatchResult
+	if err := result.UnmarshalJSON(rec.Body.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != "rejected" || result.Rejected != 2 || len(result.Items) != 2 || result.Items[1].Index != 2 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if val, _ := storage.GetGauge("web_load"); val != 0.7 {
+		t.Errorf("atomic write with errors must not be applied, got %v", val)
+	}
+
+	if rec := write("?precision=fortnight", "web load=1"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown precision, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/levinOo/go-metrics-project/internal/audit"
+	"github.com/levinOo/go-metrics-project/internal/ingest"
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/repository"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxOTLPErrors ограничивает количество причин отклонения в ответе OTLP.
+const maxOTLPErrors = 10
+
+// OTLPHandler возвращает обработчик OTLP/HTTP для приема метрик от OpenTelemetry SDK
+// и коллекторов.
+//
+// Формат запроса:
+//
+//	POST /v1/metrics
+//	Content-Type: application/x-protobuf или application/json
+//	Body: ExportMetricsServiceRequest
+//
+// Метрики преобразуются converter и записываются одним вызовом InsertMetricsBatch
+// с аудитом, как для /updates. Сжатие gzip обрабатывается DecompressMiddleware.
+// Подпись HMAC не требуется: экспортеры OTLP не умеют ее вычислять.
+//
+// Ответы:
+//
+//	200 OK - ExportMetricsServiceResponse в формате запроса; если часть точек
+//	    отклонена, их количество и причины указываются в partial_success
+//	400 Bad Request - тело запроса нельзя разобрать
+//	413 Request Entity Too Large - превышен размер тела или количество точек
+//	415 Unsupported Media Type - неподдерживаемый Content-Type
+//	500 Internal Server Error - ошибка при сохранении
+//	503 Service Unavailable - буфер записи переполнен, запрос можно повторить
+func OTLPHandler(storage repository.Storage, converter *ingest.OTLPConverter, path, url string, limits BatchLimits) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		storage := repository.WithContext(storage, r.Context())
+
+		defer r.Body.Close()
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/x-protobuf" && mediaType != "application/json" {
+			http.Error(rw, "unsupported content type", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(rw, "failed to read body", bodyErrorStatus(err))
+			return
+		}
+
+		var req metricspb.MetricsData
+		if mediaType == "application/json" {
+			err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, &req)
+		} else {
+			err = proto.Unmarshal(body, &req)
+		}
+		if err != nil {
+			http.Error(rw, "invalid OTLP request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		converted, err := converter.Convert(&req, limits.MaxItems)
+		if errors.Is(err, ingest.ErrTooManyItems) {
+			http.Error(rw, fmt.Sprintf("%v: limit %d", err, limits.MaxItems), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Состояние cumulative-рядов фиксируется только после успешной записи,
+		// иначе повтор запроса экспортером потерял бы приращения.
+		defer converter.Rollback(converted)
+
+		valid, result := repository.PrepareBatch(models.ListMetrics{List: converted.Metrics}, models.BatchModeBestEffort)
+		if len(valid.List) > 0 {
+			// Помещенные в буфер записи точки считаются принятыми: в OTLP нет
+			// отдельного статуса для отложенной записи.
+			written, err := repository.InsertBatch(storage, valid, &result)
+			if err != nil {
+				log.Printf("failed to write OTLP metrics: %v", err)
+				http.Error(rw, "internal server error", storageErrorStatus(err))
+				return
+			}
+			audit.NewAuditEvent(written, path, url, clientIP(r))
+		}
+
+		rejectedIDs := make(map[string]bool)
+		for _, item := range result.Items {
+			if item.Status == models.BatchRejected {
+				rejectedIDs[item.ID] = true
+			}
+		}
+		converter.Commit(converted, rejectedIDs)
+
+		rejected := converted.Rejected + int64(result.Rejected)
+		reasons := make([]string, 0, maxOTLPErrors)
+		for _, err := range converted.Errors {
+			if len(reasons) == maxOTLPErrors {
+				break
+			}
+			reasons = append(reasons, err.Error())
+		}
+		for _, item := range result.Items {
+			if len(reasons) == maxOTLPErrors {
+				break
+			}
+			if item.Status == models.BatchRejected {
+				reasons = append(reasons, fmt.Sprintf("metric %q: %s", item.ID, item.Reason))
+			}
+		}
+
+		writeOTLPResponse(rw, mediaType, rejected, strings.Join(reasons, "; "))
+	}
+}
+
+// otlpPartialSuccess — ExportMetricsPartialSuccess в JSON-кодировке OTLP.
+type otlpPartialSuccess struct {
+	RejectedDataPoints int64  `json:"rejectedDataPoints,string,omitempty"`
+	ErrorMessage       string `json:"errorMessage,omitempty"`
+}
+
+// writeOTLPResponse записывает ExportMetricsServiceResponse. Поле partial_success
+// заполняется, только если есть отклоненные точки.
+func writeOTLPResponse(rw http.ResponseWriter, mediaType string, rejected int64, message string) {
+	var data []byte
+
+	if mediaType == "application/json" {
+		resp := struct {
+			PartialSuccess *otlpPartialSuccess `json:"partialSuccess,omitempty"`
+		}{}
+		if rejected > 0 {
+			resp.PartialSuccess = &otlpPartialSuccess{RejectedDataPoints: rejected, ErrorMessage: message}
+		}
+
+		var err error
+		data, err = json.Marshal(resp)
+		if err != nil {
+			http.Error(rw, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	} else if rejected > 0 {
+		var partial []byte
+		partial = protowire.AppendTag(partial, 1, protowire.VarintType)
+		partial = protowire.AppendVarint(partial, uint64(rejected))
+		if message != "" {
+			partial = protowire.AppendTag(partial, 2, protowire.BytesType)
+			partial = protowire.AppendString(partial, message)
+		}
+		data = protowire.AppendTag(data, 1, protowire.BytesType)
+		data = protowire.AppendBytes(data, partial)
+	}
+
+	rw.Header().Set("Content-Type", mediaType)
+	rw.WriteHeader(http.StatusOK)
+	if _, err := rw.Write(data); err != nil {
+		log.Printf("OTLP response write error: %v", err)
+	}
+}
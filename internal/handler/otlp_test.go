@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/config"
+	"github.com/levinOo/go-metrics-project/internal/logger"
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/repository"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOTLPHandler(t *testing.T) {
+	storage := repository.NewMemStorage()
+	router := NewRouter(storage, logger.NewLogger(), config.Config{}, nil)
+
+	post := func(contentType string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/metrics", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	now := uint64(time.Now().UnixNano())
+	body, err := proto.Marshal(&metricspb.MetricsData{ResourceMetrics: []*metricspb.ResourceMetrics{{
+		ScopeMetrics: []*metricspb.ScopeMetrics{{Metrics: []*metricspb.Metric{
+			{Name: "requests", Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				IsMonotonic:            true,
+				DataPoints: []*metricspb.NumberDataPoint{{
+					StartTimeUnixNano: now,
+					TimeUnixNano:      now + 1,
+					Value:             &metricspb.NumberDataPoint_AsInt{AsInt: 4},
+				}},
+			}}},
+			{Name: "temperature", Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+				DataPoints: []*metricspb.NumberDataPoint{{Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 21.5}}},
+			}}},
+		}}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := post("application/x-protobuf", body)
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Fatalf("expected empty 200 response, got %d: %q", rec.Code, rec.Body)
+	}
+	if val, _ := storage.GetCounter("requests"); val != 4 {
+		t.Errorf("expected counter 4, got %d", val)
+	}
+	if val, _ := storage.GetGauge("temperature"); val != 21.5 {
+		t.Errorf("expected gauge 21.5, got %v", val)
+	}
+
+	rec = post("application/json", []byte(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[
+		{"name":"latency","histogram":{"dataPoints":[{"count":"1"}]}},
+		{"name":"load","gauge":{"dataPoints":[{"asDouble":0.5}]}}]}]}]}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), `"rejectedDataPoints":"1"`) {
+		t.Errorf("expected partial success, got %s", rec.Body)
+	}
+	if val, _ := storage.GetGauge("load"); val != 0.5 {
+		t.Errorf("expected gauge 0.5, got %v", val)
+	}
+
+	if rec := post("text/plain", body); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415, got %d", rec.Code)
+	}
+	if rec := post("application/x-protobuf", []byte{0xff}); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+// failingBatchStorage отклоняет заданное количество пакетных записей.
+type failingBatchStorage struct {
+	*repository.MemStorage
+	failures int
+}
+
+func (s *failingBatchStorage) InsertMetricsBatch(metrics models.ListMetrics) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("storage failure")
+	}
+	return s.MemStorage.InsertMetricsBatch(metrics)
+}
+
+func TestOTLPHandlerRetryAfterFailedWrite(t *testing.T) {
+	storage := &failingBatchStorage{MemStorage: repository.NewMemStorage(), failures: 1}
+	router := NewRouter(storage, logger.NewLogger(), config.Config{}, nil)
+
+	now := uint64(time.Now().UnixNano())
+	body, err := proto.Marshal(&metricspb.MetricsData{ResourceMetrics: []*metricspb.ResourceMetrics{{
+		ScopeMetrics: []*metricspb.ScopeMetrics{{Metrics: []*metricspb.Metric{
+			{Name: "requests", Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				IsMonotonic:            true,
+				DataPoints: []*metricspb.NumberDataPoint{{
+					StartTimeUnixNano: now,
+					TimeUnixNano:      now + 1,
+					Value:             &metricspb.NumberDataPoint_AsInt{AsInt: 4},
+				}},
+			}}},
+		}}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []int{http.StatusInternalServerError, http.StatusOK} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/metrics", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("attempt %d: expected %d, got %d", i, want, rec.Code)
+		}
+	}
+
+	if val, _ := storage.GetCounter("requests"); val != 4 {
+		t.Errorf("retried export must write the full increment 4, got %d", val)
+	}
+}
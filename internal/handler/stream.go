@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/levinOo/go-metrics-project/internal/audit"
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/repository"
+	"github.com/levinOo/go-metrics-project/internal/validation"
+)
+
+// errTooManyItems возвращается, если пакет содержит больше метрик, чем разрешено.
+var errTooManyItems = errors.New("too many metrics in batch")
+
+const (
+	// defaultStreamChunkSize — размер порции записи NDJSON-запроса по умолчанию.
+	defaultStreamChunkSize = 1000
+
+	// maxNDJSONLineSize ограничивает длину одной строки NDJSON.
+	maxNDJSONLineSize = 1 << 20
+
+	// maxReportedItems ограничивает количество отклоненных метрик в ответе на
+	// потоковый запрос. Счетчики в ответе учитывают все метрики.
+	maxReportedItems = 1000
+)
+
+// isNDJSON сообщает, передано ли тело запроса в формате application/x-ndjson.
+func isNDJSON(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/x-ndjson"
+}
+
+// streamUpdates обрабатывает NDJSON-запрос /updates: по одной метрике на строку.
+// Тело читается и разбирается построчно, не загружаясь в память целиком.
+//
+// Неподписанный запрос в режиме best-effort записывается в хранилище порциями по
+// limits.ChunkSize метрик по мере чтения. Если запрос подписан или выбран режим atomic,
+// метрики до конца тела только агрегируются по имени (счетчики суммируются, для gauge
+// остается последнее значение), а запись выполняется одним пакетом после проверки
+// подписи и всех метрик. Память в этом случае пропорциональна числу различных имен.
+//
+// В ответе перечисляются только отклоненные метрики (не более maxReportedItems),
+// счетчики applied, merged, rejected и queued учитывают все строки.
+//
+// При превышении limits.MaxItems запрос прерывается с кодом 413; порции, записанные
+// до этого в потоковом режиме, остаются в хранилище.
+func streamUpdates(rw http.ResponseWriter, r *http.Request, storage repository.Storage, key, path, url, mode string, limits BatchLimits) {
+	body := io.Reader(r.Body)
+
+	var mac hash.Hash
+	var sig []byte
+	if received := requestHash(r); received != "" && key != "" {
+		var err error
+		sig, err = hex.DecodeString(received)
+		if err != nil {
+			http.Error(rw, "bad hash format", http.StatusBadRequest)
+			return
+		}
+		mac = hmac.New(sha256.New, []byte(key))
+		body = io.TeeReader(r.Body, mac)
+	}
+
+	batch := newStreamBatch(storage, path, url, clientIP(r), mode, mac != nil || mode == models.BatchModeAtomic)
+
+	chunkSize := limits.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultStreamChunkSize
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLineSize)
+
+	index := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if limits.MaxItems > 0 && index >= limits.MaxItems {
+			http.Error(rw, fmt.Sprintf("%v: limit %d, already written %d", errTooManyItems, limits.MaxItems, batch.result.Applied+batch.result.Merged+batch.result.Queued),
+				http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		var m models.Metrics
+		if err := m.UnmarshalJSON(line); err != nil {
+			batch.reject(index, m, "invalid JSON: "+err.Error())
+		} else {
+			batch.add(index, m)
+		}
+		index++
+
+		if len(batch.chunk) >= chunkSize {
+			if err := batch.flush(); err != nil {
+				log.Printf("failed to write NDJSON chunk: %v", err)
+				http.Error(rw, "internal server error", storageErrorStatus(err))
+				return
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			http.Error(rw, "NDJSON line is too long", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(rw, "failed to read body", bodyErrorStatus(err))
+		return
+	}
+
+	if mac != nil && !hmac.Equal(mac.Sum(nil), sig) {
+		log.Println("Incorrect hash")
+		http.Error(rw, "invalid hash", http.StatusBadRequest)
+		return
+	}
+
+	if err := batch.commit(); err != nil {
+		log.Printf("failed to write NDJSON batch: %v", err)
+		http.Error(rw, "internal server error", storageErrorStatus(err))
+		return
+	}
+
+	status := http.StatusOK
+	if batch.result.Status == "rejected" {
+		status = http.StatusUnprocessableEntity
+	}
+	writeBatchResult(rw, r, key, status, batch.result)
+}
+
+// streamBatch накапливает метрики потокового запроса и записывает их в хранилище.
+type streamBatch struct {
+	storage      repository.Storage
+	path, url    string
+	ip           string
+	mode         string
+	deferred     bool
+	result       models.BatchResult
+	chunk        []models.Metrics
+	chunkIndexes []int
+
+	// aggregated и order используются в отложенном режиме.
+	aggregated map[string]*models.Metrics
+	order      []string
+}
+
+func newStreamBatch(storage repository.Storage, path, url, ip, mode string, deferred bool) *streamBatch {
+	b := &streamBatch{
+		storage:  storage,
+		path:     path,
+		url:      url,
+		ip:       ip,
+		mode:     mode,
+		deferred: deferred,
+		result:   models.BatchResult{Mode: mode, Items: []models.BatchItemResult{}},
+	}
+	if deferred {
+		b.aggregated = make(map[string]*models.Metrics)
+	}
+	return b
+}
+
+// add принимает очередную метрику: в потоковом режиме добавляет её в текущую
+// порцию, в отложенном — проверяет и объединяет с уже полученными.
+func (b *streamBatch) add(index int, m models.Metrics) {
+	if !b.deferred {
+		b.chunk = append(b.chunk, m)
+		b.chunkIndexes = append(b.chunkIndexes, index)
+		return
+	}
+
+	if err := validation.Default().Metric(m); err != nil {
+		b.reject(index, m, err.Error())
+		return
+	}
+
+	prev, ok := b.aggregated[m.ID]
+	if !ok {
+		b.aggregated[m.ID] = &m
+		b.order = append(b.order, m.ID)
+		b.result.Applied++
+		return
+	}
+
+	if prev.MType != m.MType {
+		b.reject(index, m, fmt.Sprintf("metric already sent as %s in this batch", prev.MType))
+		return
+	}
+
+	if m.MType == models.Counter {
+		sum, err := validation.AddCounter(*prev.Delta, *m.Delta)
+		if err != nil {
+			b.reject(index, m, err.Error())
+			return
+		}
+		*prev.Delta = sum
+	} else {
+		prev.Value = m.Value
+	}
+	b.result.Merged++
+}
+
+// reject учитывает отклоненную метрику.
+func (b *streamBatch) reject(index int, m models.Metrics, reason string) {
+	b.result.Rejected++
+	b.report(models.BatchItemResult{
+		Index:  index,
+		ID:     m.ID,
+		MType:  m.MType,
+		Status: models.BatchRejected,
+		Reason: reason,
+	})
+}
+
+func (b *streamBatch) report(item models.BatchItemResult) {
+	if len(b.result.Items) < maxReportedItems {
+		b.result.Items = append(b.result.Items, item)
+	}
+}
+
+// flush проверяет и записывает текущую порцию потокового режима.
+func (b *streamBatch) flush() error {
+	if len(b.chunk) == 0 {
+		return nil
+	}
+
+	valid, res := repository.PrepareBatch(models.ListMetrics{List: b.chunk}, models.BatchModeBestEffort)
+
+	if len(valid.List) > 0 {
+		written, err := repository.InsertBatch(b.storage, valid, &res)
+		if err != nil {
+			return err
+		}
+		audit.NewAuditEvent(written, b.path, b.url, b.ip)
+	}
+
+	for _, item := range res.Items {
+		if item.Status == models.BatchRejected {
+			item.Index = b.chunkIndexes[item.Index]
+			b.report(item)
+		}
+	}
+
+	b.chunk = b.chunk[:0]
+	b.chunkIndexes = b.chunkIndexes[:0]
+
+	b.result.Applied += res.Applied
+	b.result.Merged += res.Merged
+	b.result.Rejected += res.Rejected
+	b.result.Queued += res.Queued
+	return nil
+}
+
+// commit записывает последнюю порцию потокового режима или весь агрегат
+// отложенного режима и подводит итог.
+func (b *streamBatch) commit() error {
+	if !b.deferred {
+		if err := b.flush(); err != nil {
+			return err
+		}
+	} else if b.mode == models.BatchModeAtomic && b.result.Rejected > 0 {
+		b.result.Rejected += b.result.Applied + b.result.Merged
+		b.result.Applied = 0
+		b.result.Merged = 0
+	} else if len(b.order) > 0 {
+		list := models.ListMetrics{List: make([]models.Metrics, 0, len(b.order))}
+		for _, name := range b.order {
+			list.List = append(list.List, *b.aggregated[name])
+		}
+
+		err := b.storage.InsertMetricsBatch(list)
+		if errors.Is(err, repository.ErrQueued) {
+			repository.MarkQueued(&b.result)
+		} else if err != nil {
+			return err
+		}
+		audit.NewAuditEvent(list, b.path, b.url, b.ip)
+	}
+
+	switch {
+	case b.result.Rejected == 0:
+		b.result.Status = "ok"
+	case b.result.Applied == 0 && b.result.Merged == 0 && b.result.Queued == 0:
+		b.result.Status = "rejected"
+	default:
+		b.result.Status = "partial"
+	}
+	return nil
+}
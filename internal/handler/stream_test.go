@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/levinOo/go-metrics-project/internal/config"
+	"github.com/levinOo/go-metrics-project/internal/logger"
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/repository"
+)
+
+// batchCountingStorage считает вызовы InsertMetricsBatch.
+type batchCountingStorage struct {
+	*repository.MemStorage
+	batches []int
+}
+
+func (s *batchCountingStorage) InsertMetricsBatch(metrics models.ListMetrics) error {
+	s.batches = append(s.batches, len(metrics.List))
+	return s.MemStorage.InsertMetricsBatch(metrics)
+}
+
+func ndjsonBody(n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "{\"id\":\"PollCount\",\"type\":\"counter\",\"delta\":1}\n")
+	}
+	return sb.String()
+}
+
+func postNDJSON(t *testing.T, router http.Handler, body []byte, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/updates", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Accept", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestStreamUpdatesFlushesInChunks(t *testing.T) {
+	storage := &batchCountingStorage{MemStorage: repository.NewMemStorage()}
+	router := NewRouter(storage, logger.NewLogger(), config.Config{StreamChunkSize: 2}, nil)
+
+	body := ndjsonBody(5) + "{\"id\":\"Bad\",\"type\":\"gauge\"}\nnot json\n"
+	rec := postNDJSON(t, router, []byte(body), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	if len(storage.batches) != 3 {
+		t.Errorf("expected 3 chunks, got %v", storage.batches)
+	}
+	if val, _ := storage.GetCounter("PollCount"); val != 5 {
+		t.Errorf("expected counter 5, got %d", val)
+	}
+
+	var result models.BatchResult
+	if err := result.UnmarshalJSON(rec.Body.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != "partial" || result.Rejected != 2 || len(result.Items) != 2 || result.Items[1].Index != 6 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestStreamUpdatesSignedAppliesAfterVerification(t *testing.T) {
+	storage := &batchCountingStorage{MemStorage: repository.NewMemStorage()}
+	router := NewRouter(storage, logger.NewLogger(), config.Config{Key: "secret", StreamChunkSize: 2}, nil)
+
+	body := []byte(ndjsonBody(5))
+
+	rec := postNDJSON(t, router, body, map[string]string{"HashSHA256": hex.EncodeToString(make([]byte, 32))})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid signature, got %d", rec.Code)
+	}
+	if len(storage.batches) != 0 {
+		t.Fatalf("nothing must be written before signature check, got %v", storage.batches)
+	}
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	rec = postNDJSON(t, router, body, map[string]string{"HashSHA256": hex.EncodeToString(mac.Sum(nil))})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(storage.batches) != 1 || storage.batches[0] != 1 {
+		t.Errorf("expected one aggregated batch of 1 metric, got %v", storage.batches)
+	}
+	if val, _ := storage.GetCounter("PollCount"); val != 5 {
+		t.Errorf("expected counter 5, got %d", val)
+	}
+}
+
+func TestStreamUpdatesLimits(t *testing.T) {
+	storage := repository.NewMemStorage()
+	router := NewRouter(storage, logger.NewLogger(), config.Config{MaxBatchItems: 3, MaxBodySize: 1024}, nil)
+
+	rec := postNDJSON(t, router, []byte(ndjsonBody(4)), nil)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for item limit, got %d", rec.Code)
+	}
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(bytes.Repeat([]byte("\n"), 4096))
+	w.Close()
+
+	rec = postNDJSON(t, router, gz.Bytes(), map[string]string{"Content-Encoding": "gzip"})
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for decompressed size limit, got %d", rec.Code)
+	}
+}
+
+// queuingStorage имитирует буфер записи при недоступной базе данных.
+type queuingStorage struct {
+	*repository.MemStorage
+}
+
+func (s queuingStorage) InsertMetricsBatch(models.ListMetrics) error {
+	return repository.ErrQueued
+}
+
+func TestUpdatesReportsQueuedItems(t *testing.T) {
+	router := NewRouter(queuingStorage{repository.NewMemStorage()}, logger.NewLogger(), config.Config{}, nil)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantItems   int
+	}{
+		{"json", "application/json", `[{"id":"c","type":"counter","delta":1},{"id":"c","type":"counter","delta":2}]`, 2},
+		{"ndjson", "application/x-ndjson", ndjsonBody(3), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/updates", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+			}
+
+			var result models.BatchResult
+			if err := result.UnmarshalJSON(rec.Body.Bytes()); err != nil {
+				t.Fatal(err)
+			}
+			if result.Queued == 0 || result.Applied != 0 || result.Merged != 0 || len(result.Items) != tt.wantItems {
+				t.Fatalf("unexpected result: %+v", result)
+			}
+			for _, item := range result.Items {
+				if item.Status != models.BatchQueued {
+					t.Errorf("item %d: expected status queued, got %s", item.Index, item.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdatesRejectsStoredCounterOverflow(t *testing.T) {
+	body := `[{"id":"Big","type":"counter","delta":1},{"id":"PollCount","type":"counter","delta":2},{"id":"Alloc","type":"gauge","value":1.5},{"id":"Big","type":"counter","delta":1}]`
+
+	tests := []struct {
+		name       string
+		mode       string
+		wantStatus int
+		wantPoll   repository.Counter
+	}{
+		{"best-effort", models.BatchModeBestEffort, http.StatusOK, 2},
+		{"atomic", models.BatchModeAtomic, http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := repository.NewMemStorage()
+			if err := storage.SetCounter("Big", math.MaxInt64-1); err != nil {
+				t.Fatal(err)
+			}
+			router := NewRouter(storage, logger.NewLogger(), config.Config{}, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/updates?mode="+tt.mode, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+
+			poll, _ := storage.GetCounter("PollCount")
+			if poll != tt.wantPoll {
+				t.Errorf("PollCount = %d, want %d", poll, tt.wantPoll)
+			}
+			if big, _ := storage.GetCounter("Big"); big != math.MaxInt64-1 {
+				t.Errorf("overflowing counter must stay unchanged, got %d", big)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var result models.BatchResult
+			if err := result.UnmarshalJSON(rec.Body.Bytes()); err != nil {
+				t.Fatal(err)
+			}
+			if result.Status != "partial" || result.Applied != 2 || result.Rejected != 2 {
+				t.Fatalf("unexpected result: %+v", result)
+			}
+			for _, i := range []int{0, 3} {
+				if item := result.Items[i]; item.Status != models.BatchRejected || item.Reason == "" {
+					t.Errorf("item %d: expected rejected with reason, got %+v", i, item)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package ingest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+// typeRule сопоставляет шаблон имени метрики с типом.
+type typeRule struct {
+	pattern string
+	mtype   string
+}
+
+// TypeMapping определяет тип метрики для поля line protocol.
+//
+// Правила задаются строкой вида "pattern=type,pattern=type", где pattern — шаблон
+// path.Match для имени метрики без тегов (measurement_field), а type — "gauge"
+// или "counter". Применяется первое совпавшее правило. Если ни одно правило не
+// совпало, все числовые и логические поля считаются gauge.
+//
+// Значение поля counter трактуется как приращение и должно быть целым числом.
+// Логические значения преобразуются в 0 и 1. Строковые поля не поддерживаются.
+type TypeMapping struct {
+	rules []typeRule
+}
+
+// ParseTypeMapping разбирает строку правил. Пустая строка задает отображение по умолчанию.
+func ParseTypeMapping(spec string) (*TypeMapping, error) {
+	m := &TypeMapping{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		pattern, mtype, ok := strings.Cut(part, "=")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid field type rule %q", part)
+		}
+		if mtype != models.Gauge && mtype != models.Counter {
+			return nil, fmt.Errorf("invalid metric type %q in rule %q", mtype, part)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern in rule %q: %w", part, err)
+		}
+
+		m.rules = append(m.rules, typeRule{pattern: pattern, mtype: mtype})
+	}
+	return m, nil
+}
+
+// Type возвращает тип метрики с именем name (без тегов).
+func (m *TypeMapping) Type(name string) string {
+	for _, rule := range m.rules {
+		if ok, _ := path.Match(rule.pattern, name); ok {
+			return rule.mtype
+		}
+	}
+	return models.Gauge
+}
+
+// Convert преобразует точку в метрики, по одной на поле. Поля, которые нельзя
+// преобразовать, не попадают в результат и возвращаются в списке ошибок.
+func (m *TypeMapping) Convert(p Point) ([]models.Metrics, []error) {
+	metrics := make([]models.Metrics, 0, len(p.Fields))
+	var errs []error
+
+	for _, field := range p.Fields {
+		base := baseName(p.Measurement, field.Key)
+		metric := models.Metrics{ID: MetricName(base, p.Tags), MType: m.Type(base)}
+
+		var err error
+		if metric.MType == models.Counter {
+			metric.Delta, err = counterValue(field)
+		} else {
+			metric.Value, err = gaugeValue(field)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("field %q: %w", field.Key, err))
+			continue
+		}
+
+		metrics = append(metrics, metric)
+	}
+
+	return metrics, errs
+}
+
+// baseName возвращает имя метрики без тегов. Поле "value" не добавляется к имени.
+func baseName(measurement, field string) string {
+	name := sanitize(measurement, true)
+	if field == "value" {
+		return name
+	}
+	return name + "_" + sanitize(field, true)
+}
+
+// MetricName добавляет к имени метрики теги в виде ".key:value" в порядке ключей.
+// Символы, недопустимые в имени метрики, заменяются на "_".
+func MetricName(base string, tags []Tag) string {
+	if len(tags) == 0 {
+		return base
+	}
+
+	var sb strings.Builder
+	sb.WriteString(base)
+	for _, tag := range tags {
+		sb.WriteByte('.')
+		sb.WriteString(sanitize(tag.Key, false))
+		sb.WriteByte(':')
+		sb.WriteString(sanitize(tag.Value, false))
+	}
+	return sb.String()
+}
+
+// sanitize заменяет символы, отличные от букв, цифр, "_" и "-" (и "." при allowDot), на "_".
+func sanitize(s string, allowDot bool) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		case r == '.' && allowDot:
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+func gaugeValue(field Field) (*float64, error) {
+	var v float64
+	switch field.Kind {
+	case FieldFloat:
+		v = field.Float
+	case FieldInt:
+		v = float64(field.Int)
+	case FieldUint:
+		v = float64(field.Uint)
+	case FieldBool:
+		if field.Bool {
+			v = 1
+		}
+	default:
+		return nil, fmt.Errorf("string fields are not supported")
+	}
+	return &v, nil
+}
+
+func counterValue(field Field) (*int64, error) {
+	var v int64
+	switch field.Kind {
+	case FieldInt:
+		v = field.Int
+	case FieldUint:
+		if field.Uint > math.MaxInt64 {
+			return nil, fmt.Errorf("value %d overflows int64", field.Uint)
+		}
+		v = int64(field.Uint)
+	case FieldFloat:
+		if field.Float != math.Trunc(field.Float) || field.Float >= math.MaxInt64 || field.Float < math.MinInt64 {
+			return nil, fmt.Errorf("counter value %v is not an int64", field.Float)
+		}
+		v = int64(field.Float)
+	case FieldBool:
+		if field.Bool {
+			v = 1
+		}
+	default:
+		return nil, fmt.Errorf("string fields are not supported")
+	}
+	return &v, nil
+}
+
+// ErrTooManyItems возвращается ReadLines, если количество метрик превышает лимит.
+var ErrTooManyItems = errors.New("too many metrics in request")
+
+// maxLineSize ограничивает длину одной строки line protocol.
+const maxLineSize = 1 << 20
+
+// Item — метрика, полученная из строки line protocol.
+type Item struct {
+	// Line — номер строки во входных данных, начиная с 1.
+	Line   int
+	Metric models.Metrics
+
+	time time.Time
+}
+
+// LineError — ошибка разбора или преобразования строки line protocol.
+type LineError struct {
+	// Line — номер строки во входных данных, начиная с 1.
+	Line int
+	Err  error
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// ReadLines читает line protocol из r построчно и преобразует точки в метрики.
+// Пустые строки и комментарии (#) пропускаются. Ошибочные строки и поля не прерывают
+// чтение и возвращаются в списке ошибок.
+//
+// Метрики упорядочиваются по метке времени точки, точки без метки считаются
+// полученными сейчас. Поэтому при объединении пакета для gauge остается значение
+// самой поздней точки. Если maxItems больше нуля и метрик больше, возвращается
+// ErrTooManyItems.
+func (m *TypeMapping) ReadLines(r io.Reader, precision time.Duration, maxItems int) ([]Item, []LineError, error) {
+	var items []Item
+	var lineErrs []LineError
+	now := time.Now()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		p, err := ParseLine(line, precision)
+		if err != nil {
+			lineErrs = append(lineErrs, LineError{Line: lineNo, Err: err})
+			continue
+		}
+		if p.Time.IsZero() {
+			p.Time = now
+		}
+
+		metrics, errs := m.Convert(p)
+		for _, err := range errs {
+			lineErrs = append(lineErrs, LineError{Line: lineNo, Err: err})
+		}
+		for _, metric := range metrics {
+			if maxItems > 0 && len(items) == maxItems {
+				return nil, nil, ErrTooManyItems
+			}
+			items = append(items, Item{Line: lineNo, Metric: metric, time: p.Time})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].time.Before(items[j].time) })
+	return items, lineErrs, nil
+}
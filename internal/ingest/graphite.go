@@ -0,0 +1,89 @@
+package ingest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+// Sample — одно значение метрики из протоколов Graphite и StatsD до агрегации.
+type Sample struct {
+	// Name — имя метрики вместе с тегами.
+	Name string
+
+	// MType — models.Gauge или models.Counter.
+	MType string
+
+	// Value — значение gauge или приращение counter. Приращение может быть дробным,
+	// например после учета частоты выборки StatsD.
+	Value float64
+
+	// Relative означает, что Value изменяет текущее значение gauge, а не заменяет его.
+	Relative bool
+
+	// Time равно нулевому значению, если метка времени не передана.
+	Time time.Time
+}
+
+// ParseGraphiteLine разбирает строку протокола Graphite plaintext:
+//
+//	path value [timestamp]
+//
+// Путь может содержать теги в формате Graphite: path;tag=value;tag2=value2. Метка
+// времени задается в секундах, отсутствующая метка или -1 означают текущее время.
+// Тип метрики определяется правилами mapping по пути без тегов.
+func (m *TypeMapping) ParseGraphiteLine(line string) (Sample, error) {
+	var s Sample
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return s, fmt.Errorf("%w: expected \"path value [timestamp]\"", ErrSyntax)
+	}
+
+	path, rawTags, _ := strings.Cut(fields[0], ";")
+	if path == "" {
+		return s, fmt.Errorf("%w: empty metric path", ErrSyntax)
+	}
+
+	var tags []Tag
+	if rawTags != "" {
+		for _, rawTag := range strings.Split(rawTags, ";") {
+			key, value, ok := strings.Cut(rawTag, "=")
+			if !ok || key == "" || value == "" {
+				return s, fmt.Errorf("%w: invalid tag %q", ErrSyntax, rawTag)
+			}
+			tags = append(tags, Tag{Key: key, Value: value})
+		}
+		sort.SliceStable(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	}
+
+	base := sanitize(path, true)
+	s.Name = MetricName(base, tags)
+	s.MType = m.Type(base)
+
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return s, fmt.Errorf("%w: invalid value %q", ErrSyntax, fields[1])
+	}
+	s.Value = value
+
+	if len(fields) == 3 && fields[2] != "-1" {
+		ts, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil || ts < 0 || ts > math.MaxInt64/float64(time.Second) {
+			return s, fmt.Errorf("%w: invalid timestamp %q", ErrSyntax, fields[2])
+		}
+		sec, frac := math.Modf(ts)
+		s.Time = time.Unix(int64(sec), int64(frac*float64(time.Second)))
+	}
+
+	if s.MType == models.Counter && value != math.Trunc(value) {
+		return s, fmt.Errorf("counter value %v is not an integer", value)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,86 @@
+package ingest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+func TestParseGraphiteLine(t *testing.T) {
+	mapping, _ := ParseTypeMapping("*.requests=counter")
+
+	s, err := mapping.ParseGraphiteLine("web.requests;region=eu;host=a 3 1700000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "web.requests.host:a.region:eu" || s.MType != models.Counter || s.Value != 3 || !s.Time.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected sample: %+v", s)
+	}
+
+	s, err = mapping.ParseGraphiteLine("disk.used 0.75 -1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.MType != models.Gauge || !s.Time.IsZero() {
+		t.Errorf("unexpected sample: %+v", s)
+	}
+
+	for _, line := range []string{"disk.used", "disk.used abc 1", "disk.used 1 2 3", "web.requests 1.5", "x;tag 1"} {
+		if _, err := mapping.ParseGraphiteLine(line); err == nil {
+			t.Errorf("%q: expected error", line)
+		}
+	}
+}
+
+func TestParseStatsDLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want Sample
+	}{
+		{"jobs.done:2|c", Sample{Name: "jobs.done", MType: models.Counter, Value: 2}},
+		{"jobs.done:1|c|@0.25|#queue:mail", Sample{Name: "jobs.done.queue:mail", MType: models.Counter, Value: 4}},
+		{"pool.size:10|g", Sample{Name: "pool.size", MType: models.Gauge, Value: 10}},
+		{"pool.size:-3|g", Sample{Name: "pool.size", MType: models.Gauge, Value: -3, Relative: true}},
+	}
+	for _, tt := range tests {
+		got, err := ParseStatsDLine(tt.line)
+		if err != nil {
+			t.Errorf("%q: %v", tt.line, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+
+	for _, line := range []string{"jobs.done", ":1|c", "jobs.done:x|c", "latency:10|ms", "jobs.done:1|c|@2"} {
+		if _, err := ParseStatsDLine(line); err == nil {
+			t.Errorf("%q: expected error", line)
+		}
+	}
+}
+
+func TestParseStatsD(t *testing.T) {
+	s, err := ParseStatsD("api.latency:12.5|ms|@0.1|#route:GET")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Type != StatsDTimer || s.Value != 12.5 || s.Rate != 0.1 || s.Base != "api.latency" || s.Name != "api.latency.route:GET" {
+		t.Errorf("unexpected timer: %+v", s)
+	}
+
+	s, err = ParseStatsD("users:alice@example.com|s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Type != StatsDSet || s.Member != "alice@example.com" {
+		t.Errorf("unexpected set: %+v", s)
+	}
+
+	for _, line := range []string{"latency:abc|ms", "users:|s", "x:1|z"} {
+		if _, err := ParseStatsD(line); err == nil {
+			t.Errorf("%q: expected error", line)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Package ingest преобразует метрики внешних форматов в модели сервера.
+//
+// Поддерживается протокол InfluxDB line protocol:
+//
+//	measurement[,tag=value...] field=value[,field=value...] [timestamp]
+//
+// Каждое поле точки становится отдельной метрикой gauge или counter. Тип
+// определяется правилами TypeMapping по имени метрики и типу значения поля.
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldKind — тип значения поля line protocol.
+type FieldKind int
+
+const (
+	// FieldFloat — число с плавающей точкой: 1.5, 1, 1e3.
+	FieldFloat FieldKind = iota
+
+	// FieldInt — целое со знаком: 10i.
+	FieldInt
+
+	// FieldUint — целое без знака: 10u.
+	FieldUint
+
+	// FieldBool — логическое значение: t, true, f, false.
+	FieldBool
+
+	// FieldString — строка в двойных кавычках.
+	FieldString
+)
+
+// Tag — тег точки.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Field — поле точки с типизированным значением.
+type Field struct {
+	Key  string
+	Kind FieldKind
+
+	Float  float64
+	Int    int64
+	Uint   uint64
+	Bool   bool
+	String string
+}
+
+// Point — разобранная строка line protocol.
+type Point struct {
+	Measurement string
+
+	// Tags отсортированы по ключу.
+	Tags   []Tag
+	Fields []Field
+
+	// Time равно нулевому значению, если метка времени в строке отсутствует.
+	Time time.Time
+}
+
+// ErrSyntax — общая ошибка разбора строки line protocol.
+var ErrSyntax = errors.New("line protocol syntax error")
+
+// ParsePrecision возвращает единицу метки времени для значения параметра precision.
+// Пустая строка означает наносекунды.
+func ParsePrecision(precision string) (time.Duration, error) {
+	switch precision {
+	case "", "n", "ns":
+		return time.Nanosecond, nil
+	case "u", "us", "µs":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	default:
+		return 0, fmt.Errorf("unknown precision %q", precision)
+	}
+}
+
+// ParseLine разбирает одну строку line protocol. Метка времени интерпретируется
+// в единицах precision.
+func ParseLine(line string, precision time.Duration) (Point, error) {
+	var p Point
+
+	measurement, i := scanToken(line, 0, ", ", ", ")
+	if measurement == "" {
+		return p, fmt.Errorf("%w: missing measurement", ErrSyntax)
+	}
+	p.Measurement = measurement
+
+	for i < len(line) && line[i] == ',' {
+		var key, value string
+		key, i = scanToken(line, i+1, "= ,", ",= ")
+		if i >= len(line) || line[i] != '=' || key == "" {
+			return p, fmt.Errorf("%w: invalid tag at position %d", ErrSyntax, i)
+		}
+		value, i = scanToken(line, i+1, " ,", ",= ")
+		if value == "" {
+			return p, fmt.Errorf("%w: empty value for tag %q", ErrSyntax, key)
+		}
+		p.Tags = append(p.Tags, Tag{Key: key, Value: value})
+	}
+	sort.SliceStable(p.Tags, func(a, b int) bool { return p.Tags[a].Key < p.Tags[b].Key })
+
+	i = skipSpaces(line, i)
+	if i >= len(line) {
+		return p, fmt.Errorf("%w: missing fields", ErrSyntax)
+	}
+
+	for {
+		var key string
+		key, i = scanToken(line, i, "= ,", ",= ")
+		if i >= len(line) || line[i] != '=' || key == "" {
+			return p, fmt.Errorf("%w: invalid field at position %d", ErrSyntax, i)
+		}
+
+		field := Field{Key: key}
+		var err error
+		field, i, err = scanFieldValue(line, i+1, field)
+		if err != nil {
+			return p, err
+		}
+		p.Fields = append(p.Fields, field)
+
+		if i >= len(line) || line[i] != ',' {
+			break
+		}
+		i++
+	}
+
+	i = skipSpaces(line, i)
+	if i < len(line) {
+		end := strings.IndexByte(line[i:], ' ')
+		if end < 0 {
+			end = len(line) - i
+		}
+		if rest := strings.TrimSpace(line[i+end:]); rest != "" {
+			return p, fmt.Errorf("%w: unexpected %q after timestamp", ErrSyntax, rest)
+		}
+
+		ts, err := parseTimestamp(line[i:i+end], precision)
+		if err != nil {
+			return p, err
+		}
+		p.Time = ts
+	}
+
+	return p, nil
+}
+
+// scanToken читает токен начиная с позиции i до первого неэкранированного символа
+// из stops. Обратная косая черта экранирует символы из escapable, перед остальными
+// символами она сохраняется как есть.
+func scanToken(line string, i int, stops, escapable string) (string, int) {
+	var sb strings.Builder
+	for i < len(line) {
+		c := line[i]
+		if c == '\\' && i+1 < len(line) && strings.IndexByte(escapable, line[i+1]) >= 0 {
+			sb.WriteByte(line[i+1])
+			i += 2
+			continue
+		}
+		if strings.IndexByte(stops, c) >= 0 {
+			break
+		}
+		sb.WriteByte(c)
+		i++
+	}
+	return sb.String(), i
+}
+
+func skipSpaces(line string, i int) int {
+	for i < len(line) && line[i] == ' ' {
+		i++
+	}
+	return i
+}
+
+// scanFieldValue читает значение поля начиная с позиции i.
+func scanFieldValue(line string, i int, field Field) (Field, int, error) {
+	if i < len(line) && line[i] == '"' {
+		var sb strings.Builder
+		i++
+		for i < len(line) && line[i] != '"' {
+			if line[i] == '\\' && i+1 < len(line) && (line[i+1] == '"' || line[i+1] == '\\') {
+				i++
+			}
+			sb.WriteByte(line[i])
+			i++
+		}
+		if i >= len(line) {
+			return field, i, fmt.Errorf("%w: unterminated string in field %q", ErrSyntax, field.Key)
+		}
+		field.Kind = FieldString
+		field.String = sb.String()
+		return field, i + 1, nil
+	}
+
+	start := i
+	for i < len(line) && line[i] != ',' && line[i] != ' ' {
+		i++
+	}
+	raw := line[start:i]
+	if raw == "" {
+		return field, i, fmt.Errorf("%w: empty value for field %q", ErrSyntax, field.Key)
+	}
+
+	var err error
+	switch {
+	case strings.HasSuffix(raw, "i"):
+		field.Kind = FieldInt
+		field.Int, err = strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+	case strings.HasSuffix(raw, "u"):
+		field.Kind = FieldUint
+		field.Uint, err = strconv.ParseUint(raw[:len(raw)-1], 10, 64)
+	case raw == "t" || raw == "T" || raw == "true" || raw == "True" || raw == "TRUE":
+		field.Kind = FieldBool
+		field.Bool = true
+	case raw == "f" || raw == "F" || raw == "false" || raw == "False" || raw == "FALSE":
+		field.Kind = FieldBool
+	default:
+		field.Kind = FieldFloat
+		field.Float, err = strconv.ParseFloat(raw, 64)
+		if err == nil && (math.IsNaN(field.Float) || math.IsInf(field.Float, 0)) {
+			err = errors.New("non-finite value")
+		}
+	}
+	if err != nil {
+		return field, i, fmt.Errorf("%w: invalid value %q for field %q", ErrSyntax, raw, field.Key)
+	}
+
+	return field, i, nil
+}
+
+func parseTimestamp(raw string, precision time.Duration) (time.Time, error) {
+	ts, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid timestamp %q", ErrSyntax, raw)
+	}
+
+	unit := int64(precision)
+	if ts > math.MaxInt64/unit || ts < math.MinInt64/unit {
+		return time.Time{}, fmt.Errorf("%w: timestamp %d out of range", ErrSyntax, ts)
+	}
+	return time.Unix(0, ts*unit), nil
+}
@@ -0,0 +1,109 @@
+package ingest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+func TestParseLine(t *testing.T) {
+	p, err := ParseLine(`cpu\ load,host=server\,01,region=us-west idle=98.5,count=3i,ok=t,note="a \"b\"" 1700000000`, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Measurement != "cpu load" {
+		t.Errorf("measurement = %q", p.Measurement)
+	}
+	if len(p.Tags) != 2 || p.Tags[0] != (Tag{"host", "server,01"}) || p.Tags[1] != (Tag{"region", "us-west"}) {
+		t.Errorf("tags = %+v", p.Tags)
+	}
+	if len(p.Fields) != 4 {
+		t.Fatalf("fields = %+v", p.Fields)
+	}
+	if f := p.Fields[0]; f.Kind != FieldFloat || f.Float != 98.5 {
+		t.Errorf("idle = %+v", f)
+	}
+	if f := p.Fields[1]; f.Kind != FieldInt || f.Int != 3 {
+		t.Errorf("count = %+v", f)
+	}
+	if f := p.Fields[2]; f.Kind != FieldBool || !f.Bool {
+		t.Errorf("ok = %+v", f)
+	}
+	if f := p.Fields[3]; f.Kind != FieldString || f.String != `a "b"` {
+		t.Errorf("note = %+v", f)
+	}
+	if !p.Time.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("time = %v", p.Time)
+	}
+}
+
+func TestParseLineErrors(t *testing.T) {
+	for _, line := range []string{
+		`cpu`,
+		`cpu,host value=1`,
+		`cpu value=`,
+		`cpu value=abc`,
+		`cpu value=NaN`,
+		`cpu value="open`,
+		`cpu value=1 notatime`,
+		`cpu value=1 1 2`,
+	} {
+		if _, err := ParseLine(line, time.Nanosecond); !errors.Is(err, ErrSyntax) {
+			t.Errorf("%q: expected syntax error, got %v", line, err)
+		}
+	}
+}
+
+func TestTypeMappingConvert(t *testing.T) {
+	mapping, err := ParseTypeMapping("http_requests=counter,*_total=counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, _ := ParseLine(`http,code=200 requests=5i,latency=0.25,bytes_total=1.5,status="ok"`, time.Nanosecond)
+	metrics, errs := mapping.Convert(p)
+
+	if len(metrics) != 2 || len(errs) != 2 {
+		t.Fatalf("metrics = %+v, errs = %v", metrics, errs)
+	}
+	if m := metrics[0]; m.ID != "http_requests.code:200" || m.MType != models.Counter || *m.Delta != 5 {
+		t.Errorf("requests = %+v", m)
+	}
+	if m := metrics[1]; m.ID != "http_latency.code:200" || m.MType != models.Gauge || *m.Value != 0.25 {
+		t.Errorf("latency = %+v", m)
+	}
+
+	if _, err := ParseTypeMapping("x=histogram"); err == nil {
+		t.Error("expected error for unknown type")
+	}
+}
+
+func TestReadLinesOrdersByTimestamp(t *testing.T) {
+	mapping, _ := ParseTypeMapping("")
+	input := strings.Join([]string{
+		"# comment",
+		"mem value=2 2000",
+		"",
+		"mem value=1 1000",
+		"broken",
+	}, "\n")
+
+	items, lineErrs, err := mapping.ReadLines(strings.NewReader(input), time.Millisecond, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Line != 4 || items[1].Line != 2 {
+		t.Errorf("items must be ordered by timestamp: %+v", items)
+	}
+	if len(lineErrs) != 1 || lineErrs[0].Line != 5 {
+		t.Errorf("lineErrs = %+v", lineErrs)
+	}
+
+	if _, _, err := mapping.ReadLines(strings.NewReader(input), time.Millisecond, 1); !errors.Is(err, ErrTooManyItems) {
+		t.Errorf("expected ErrTooManyItems, got %v", err)
+	}
+}
@@ -0,0 +1,460 @@
+package ingest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// otlpSeriesTTL — время, после которого состояние ряда, не получавшего точек,
+// удаляется из OTLPConverter.
+const otlpSeriesTTL = time.Hour
+
+// OTLPOptions настраивает построение имен метрик OTLP.
+type OTLPOptions struct {
+	// ResourceLabels — атрибуты ресурса, которые добавляются к имени метрики как теги.
+	ResourceLabels []string
+
+	// ResourcePrefix — атрибут ресурса, значение которого становится префиксом имени
+	// метрики. Пустая строка отключает префикс.
+	ResourcePrefix string
+}
+
+// ParseResourceLabels разбирает список атрибутов ресурса, разделенных запятыми.
+func ParseResourceLabels(spec string) []string {
+	var labels []string
+	for _, label := range strings.Split(spec, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// OTLPConverter преобразует запросы OTLP в метрики сервера.
+//
+// Gauge записывается как gauge. Sum с delta-темпоральностью записывается как
+// counter с приращением, равным значению точки. Монотонная Sum с cumulative-
+// темпоральностью также записывается как counter: приращение вычисляется как
+// разница с предыдущей точкой того же ряда, поэтому конвертер хранит последнее
+// значение каждого ряда. Немонотонная cumulative Sum (UpDownCounter) содержит
+// текущее значение и записывается как gauge. Histogram, ExponentialHistogram и
+// Summary не поддерживаются.
+//
+// Счетчики сервера целочисленные, поэтому для значений с плавающей точкой в
+// хранилище передается только целая часть суммы, дробный остаток учитывается
+// в следующих точках ряда.
+//
+// Состояние рядов, вычисленное Convert, применяется только вызовом Commit после
+// успешной записи метрик; Rollback отбрасывает его, и повтор запроса экспортером
+// дает те же приращения. Пока результат не зафиксирован и не отброшен, Convert
+// запросов с теми же рядами ожидает, чтобы приращения не вычислялись дважды от
+// одного и того же значения.
+//
+// Имя метрики строится так же, как для line protocol: к имени метрики OTLP
+// добавляются теги ".key:value" из выбранных атрибутов ресурса и атрибутов точки.
+//
+// OTLPConverter безопасен для конкурентного использования.
+type OTLPConverter struct {
+	opts    OTLPOptions
+	started uint64
+
+	mu        sync.Mutex
+	released  *sync.Cond
+	series    map[string]*otlpSeries
+	pending   map[string]bool
+	lastSweep time.Time
+}
+
+// otlpSeries — состояние ряда Sum между запросами.
+type otlpSeries struct {
+	// start и time — время начала и время последней точки ряда в наносекундах.
+	start uint64
+	time  uint64
+
+	// value и ivalue — последнее cumulative-значение ряда.
+	value  float64
+	ivalue int64
+
+	// carry — дробный остаток delta-суммы с плавающей точкой.
+	carry float64
+
+	seen time.Time
+}
+
+// NewOTLPConverter создает конвертер с заданными параметрами.
+func NewOTLPConverter(opts OTLPOptions) *OTLPConverter {
+	now := time.Now()
+	c := &OTLPConverter{
+		opts:      opts,
+		started:   uint64(now.UnixNano()),
+		series:    make(map[string]*otlpSeries),
+		pending:   make(map[string]bool),
+		lastSweep: now,
+	}
+	c.released = sync.NewCond(&c.mu)
+	return c
+}
+
+// otlpPoint — точка числовой метрики вместе с контекстом, нужным для преобразования.
+type otlpPoint struct {
+	name        string
+	kind        string
+	temporality metricspb.AggregationTemporality
+	monotonic   bool
+	point       *metricspb.NumberDataPoint
+}
+
+// OTLPResult — результат преобразования запроса OTLP.
+type OTLPResult struct {
+	Metrics []models.Metrics
+
+	// Rejected — количество точек, которые нельзя преобразовать в метрики.
+	Rejected int64
+
+	// Errors содержит причины отклонения, по одной на метрику OTLP.
+	Errors []error
+
+	// staged — состояние рядов Sum после преобразования, ожидающее Commit.
+	staged map[string]*otlpSeries
+}
+
+// Convert преобразует запрос OTLP в метрики. Тело запроса ExportMetricsServiceRequest
+// совместимо по формату с MetricsData и разбирается в этот тип. Точки обрабатываются в порядке
+// их меток времени, поэтому для gauge в пакете остается значение самой поздней
+// точки.
+//
+// Если maxItems больше нуля и запрос содержит больше точек, возвращается
+// ErrTooManyItems, состояние конвертера при этом не меняется. Результат без ошибки
+// нужно передать в Commit или Rollback.
+func (c *OTLPConverter) Convert(req *metricspb.MetricsData, maxItems int) (OTLPResult, error) {
+	var points []otlpPoint
+	var result OTLPResult
+
+	for _, rm := range req.GetResourceMetrics() {
+		resourceTags, prefix := c.resourceTags(rm.GetResource().GetAttributes())
+
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				base := sanitize(m.GetName(), true)
+				if prefix != "" {
+					base = prefix + "." + base
+				}
+
+				var kind string
+				var temporality metricspb.AggregationTemporality
+				var monotonic bool
+				var dataPoints []*metricspb.NumberDataPoint
+
+				switch data := m.GetData().(type) {
+				case *metricspb.Metric_Gauge:
+					kind = models.Gauge
+					dataPoints = data.Gauge.GetDataPoints()
+				case *metricspb.Metric_Sum:
+					kind = models.Counter
+					temporality = data.Sum.GetAggregationTemporality()
+					monotonic = data.Sum.GetIsMonotonic()
+					dataPoints = data.Sum.GetDataPoints()
+					if temporality == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE && !monotonic {
+						kind = models.Gauge
+					}
+				default:
+					if n := countDataPoints(m); n > 0 {
+						result.Rejected += n
+						result.Errors = append(result.Errors, fmt.Errorf("metric %q: unsupported data type %T", m.GetName(), data))
+					}
+					continue
+				}
+
+				if kind == models.Counter && temporality == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_UNSPECIFIED {
+					if len(dataPoints) == 0 {
+						continue
+					}
+					result.Rejected += int64(len(dataPoints))
+					result.Errors = append(result.Errors, fmt.Errorf("metric %q: unspecified aggregation temporality", m.GetName()))
+					continue
+				}
+
+				for _, dp := range dataPoints {
+					if dp.GetFlags()&uint32(metricspb.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK) != 0 {
+						continue
+					}
+					if dp.GetValue() == nil {
+						result.Rejected++
+						result.Errors = append(result.Errors, fmt.Errorf("metric %q: data point without value", m.GetName()))
+						continue
+					}
+
+					tags := mergeTags(resourceTags, dp.GetAttributes())
+					points = append(points, otlpPoint{
+						name:        MetricName(base, tags),
+						kind:        kind,
+						temporality: temporality,
+						monotonic:   monotonic,
+						point:       dp,
+					})
+					if maxItems > 0 && len(points) > maxItems {
+						return OTLPResult{}, ErrTooManyItems
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].point.GetTimeUnixNano() < points[j].point.GetTimeUnixNano()
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.isPending(points) {
+		c.released.Wait()
+	}
+
+	result.staged = make(map[string]*otlpSeries)
+	for _, p := range points {
+		if p.kind != models.Counter || result.staged[p.name] != nil {
+			continue
+		}
+		s := &otlpSeries{}
+		if prev, ok := c.series[p.name]; ok {
+			*s = *prev
+		}
+		result.staged[p.name] = s
+		c.pending[p.name] = true
+	}
+
+	now := time.Now()
+	result.Metrics = make([]models.Metrics, 0, len(points))
+	for _, p := range points {
+		if m, ok := c.convertPoint(p, result.staged[p.name], now); ok {
+			result.Metrics = append(result.Metrics, m)
+		}
+	}
+	c.sweep(now)
+
+	return result, nil
+}
+
+// Commit применяет состояние рядов из result после успешной записи его метрик.
+// Ряды с именами из rejected, метрики которых не были записаны, не меняются.
+func (c *OTLPConverter) Commit(result OTLPResult, rejected map[string]bool) {
+	c.release(result, func(name string) bool { return !rejected[name] })
+}
+
+// Rollback отбрасывает состояние рядов из result, если его метрики не записаны.
+// После Commit вызов ничего не делает.
+func (c *OTLPConverter) Rollback(result OTLPResult) {
+	c.release(result, func(string) bool { return false })
+}
+
+func (c *OTLPConverter) release(result OTLPResult, apply func(name string) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, s := range result.staged {
+		if apply(name) {
+			c.series[name] = s
+		}
+		delete(c.pending, name)
+		delete(result.staged, name)
+	}
+	c.released.Broadcast()
+}
+
+// isPending сообщает, ожидает ли фиксации состояние какого-либо ряда из points.
+func (c *OTLPConverter) isPending(points []otlpPoint) bool {
+	for _, p := range points {
+		if p.kind == models.Counter && c.pending[p.name] {
+			return true
+		}
+	}
+	return false
+}
+
+// convertPoint преобразует одну точку. Возвращает false, если точка не дает
+// метрики: первая точка cumulative-ряда, начавшегося до запуска конвертера,
+// служит базой для следующих приращений, а устаревшие точки пропускаются.
+// Состояние ряда s обновляется на месте.
+func (c *OTLPConverter) convertPoint(p otlpPoint, s *otlpSeries, now time.Time) (models.Metrics, bool) {
+	dp := p.point
+	m := models.Metrics{ID: p.name, MType: p.kind}
+
+	if p.kind == models.Gauge {
+		v := numberValue(dp)
+		m.Value = &v
+		return m, true
+	}
+
+	ok := !s.seen.IsZero()
+	s.seen = now
+
+	var delta int64
+	if p.temporality == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA {
+		if iv, isInt := dp.GetValue().(*metricspb.NumberDataPoint_AsInt); isInt {
+			delta = iv.AsInt
+		} else {
+			total := s.carry + dp.GetAsDouble()
+			whole := math.Trunc(total)
+			if whole >= math.MaxInt64 || whole < math.MinInt64 {
+				return m, false
+			}
+			s.carry = total - whole
+			delta = int64(whole)
+		}
+		m.Delta = &delta
+		return m, true
+	}
+
+	if ok && dp.GetTimeUnixNano() < s.time {
+		return m, false
+	}
+
+	_, isInt := dp.GetValue().(*metricspb.NumberDataPoint_AsInt)
+	value := dp.GetAsDouble()
+	ivalue := dp.GetAsInt()
+
+	reset := ok && (dp.GetStartTimeUnixNano() != s.start || (isInt && ivalue < s.ivalue) || (!isInt && value < s.value))
+	switch {
+	case !ok && (dp.GetStartTimeUnixNano() == 0 || dp.GetStartTimeUnixNano() < c.started):
+		// Ряд начался до запуска конвертера: часть значения уже могла быть учтена.
+		ok = false
+	case isInt && (!ok || reset):
+		delta = ivalue
+		ok = true
+	case isInt:
+		delta = ivalue - s.ivalue
+		ok = true
+	case !ok || reset:
+		delta, ok = floorDelta(value, 0)
+	default:
+		delta, ok = floorDelta(value, s.value)
+	}
+
+	s.start = dp.GetStartTimeUnixNano()
+	s.time = dp.GetTimeUnixNano()
+	s.value = value
+	s.ivalue = ivalue
+
+	m.Delta = &delta
+	return m, ok
+}
+
+// floorDelta возвращает разницу целых частей cur и prev.
+func floorDelta(cur, prev float64) (int64, bool) {
+	d := math.Floor(cur) - math.Floor(prev)
+	if math.IsNaN(d) || d >= math.MaxInt64 || d < math.MinInt64 {
+		return 0, false
+	}
+	return int64(d), true
+}
+
+// sweep удаляет состояние рядов, не получавших точек дольше otlpSeriesTTL.
+func (c *OTLPConverter) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < otlpSeriesTTL {
+		return
+	}
+	for name, s := range c.series {
+		if now.Sub(s.seen) > otlpSeriesTTL {
+			delete(c.series, name)
+		}
+	}
+	c.lastSweep = now
+}
+
+// resourceTags возвращает теги из выбранных атрибутов ресурса и префикс имени.
+func (c *OTLPConverter) resourceTags(attrs []*commonpb.KeyValue) ([]Tag, string) {
+	var tags []Tag
+	var prefix string
+
+	for _, kv := range attrs {
+		value, ok := attributeValue(kv.GetValue())
+		if !ok || value == "" {
+			continue
+		}
+		if c.opts.ResourcePrefix != "" && kv.GetKey() == c.opts.ResourcePrefix {
+			prefix = sanitize(value, true)
+		}
+		for _, label := range c.opts.ResourceLabels {
+			if kv.GetKey() == label {
+				tags = append(tags, Tag{Key: label, Value: value})
+				break
+			}
+		}
+	}
+	return tags, prefix
+}
+
+// mergeTags объединяет теги ресурса с атрибутами точки и сортирует их по ключу.
+// Атрибут точки заменяет одноименный атрибут ресурса.
+func mergeTags(resource []Tag, attrs []*commonpb.KeyValue) []Tag {
+	tags := make([]Tag, 0, len(resource)+len(attrs))
+	for _, tag := range resource {
+		if !hasAttribute(attrs, tag.Key) {
+			tags = append(tags, tag)
+		}
+	}
+	for _, kv := range attrs {
+		if value, ok := attributeValue(kv.GetValue()); ok && value != "" {
+			tags = append(tags, Tag{Key: kv.GetKey(), Value: value})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	return tags
+}
+
+func hasAttribute(attrs []*commonpb.KeyValue, key string) bool {
+	for _, kv := range attrs {
+		if kv.GetKey() == key {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeValue возвращает строковое представление скалярного атрибута.
+// Массивы, вложенные наборы и байты не поддерживаются.
+func attributeValue(v *commonpb.AnyValue) (string, bool) {
+	switch v := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue, true
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue), true
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10), true
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+func numberValue(dp *metricspb.NumberDataPoint) float64 {
+	if v, ok := dp.GetValue().(*metricspb.NumberDataPoint_AsInt); ok {
+		return float64(v.AsInt)
+	}
+	return dp.GetAsDouble()
+}
+
+// countDataPoints возвращает количество точек метрики неподдерживаемого типа.
+func countDataPoints(m *metricspb.Metric) int64 {
+	switch data := m.GetData().(type) {
+	case *metricspb.Metric_Histogram:
+		return int64(len(data.Histogram.GetDataPoints()))
+	case *metricspb.Metric_ExponentialHistogram:
+		return int64(len(data.ExponentialHistogram.GetDataPoints()))
+	case *metricspb.Metric_Summary:
+		return int64(len(data.Summary.GetDataPoints()))
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,175 @@
+package ingest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+func stringAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func otlpRequest(metrics ...*metricspb.Metric) *metricspb.MetricsData {
+	return &metricspb.MetricsData{ResourceMetrics: []*metricspb.ResourceMetrics{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			stringAttr("service.name", "checkout"),
+			stringAttr("host.name", "node-1"),
+		}},
+		ScopeMetrics: []*metricspb.ScopeMetrics{{Metrics: metrics}},
+	}}}
+}
+
+func cumulativeSum(name string, start, ts uint64, value int64) *metricspb.Metric {
+	return &metricspb.Metric{Name: name, Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+		AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		IsMonotonic:            true,
+		DataPoints: []*metricspb.NumberDataPoint{{
+			StartTimeUnixNano: start,
+			TimeUnixNano:      ts,
+			Value:             &metricspb.NumberDataPoint_AsInt{AsInt: value},
+		}},
+	}}}
+}
+
+func convertDeltas(t *testing.T, c *OTLPConverter, m *metricspb.Metric) []int64 {
+	t.Helper()
+	res, err := c.Convert(otlpRequest(m), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Commit(res, nil)
+	var deltas []int64
+	for _, metric := range res.Metrics {
+		deltas = append(deltas, *metric.Delta)
+	}
+	return deltas
+}
+
+func TestOTLPCumulativeToDelta(t *testing.T) {
+	c := NewOTLPConverter(OTLPOptions{ResourceLabels: []string{"service.name"}})
+	start := c.started + 1
+
+	res, err := c.Convert(otlpRequest(cumulativeSum("http.requests", start, start+10, 5)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Commit(res, nil)
+	if len(res.Metrics) != 1 {
+		t.Fatalf("metrics = %+v", res.Metrics)
+	}
+	if m := res.Metrics[0]; m.ID != "http.requests.service_name:checkout" || m.MType != models.Counter || *m.Delta != 5 {
+		t.Errorf("series started after converter must report full value: %+v", m)
+	}
+
+	if d := convertDeltas(t, c, cumulativeSum("http.requests", start, start+20, 12)); len(d) != 1 || d[0] != 7 {
+		t.Errorf("expected delta 7, got %v", d)
+	}
+	if d := convertDeltas(t, c, cumulativeSum("http.requests", start, start+15, 9)); len(d) != 0 {
+		t.Errorf("out of order point must be dropped, got %v", d)
+	}
+	if d := convertDeltas(t, c, cumulativeSum("http.requests", start+30, start+40, 3)); len(d) != 1 || d[0] != 3 {
+		t.Errorf("reset must report the new value, got %v", d)
+	}
+}
+
+func TestOTLPRollback(t *testing.T) {
+	c := NewOTLPConverter(OTLPOptions{})
+	start := c.started + 1
+
+	convertDeltas(t, c, cumulativeSum("jobs", start, start+10, 5))
+
+	res, err := c.Convert(otlpRequest(cumulativeSum("jobs", start, start+20, 12)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Rollback(res)
+	if d := convertDeltas(t, c, cumulativeSum("jobs", start, start+20, 12)); len(d) != 1 || d[0] != 7 {
+		t.Errorf("retry after rollback must report the same delta 7, got %v", d)
+	}
+
+	res, err = c.Convert(otlpRequest(cumulativeSum("jobs", start, start+30, 20)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Commit(res, map[string]bool{"jobs": true})
+	if d := convertDeltas(t, c, cumulativeSum("jobs", start, start+40, 25)); len(d) != 1 || d[0] != 13 {
+		t.Errorf("rejected series must not advance, expected delta 13, got %v", d)
+	}
+}
+
+func TestOTLPCumulativeBaseline(t *testing.T) {
+	c := NewOTLPConverter(OTLPOptions{})
+	start := c.started - uint64(time.Hour)
+
+	if d := convertDeltas(t, c, cumulativeSum("jobs", start, c.started+1, 100)); len(d) != 0 {
+		t.Errorf("first point of an older series is a baseline, got %v", d)
+	}
+	if d := convertDeltas(t, c, cumulativeSum("jobs", start, c.started+2, 104)); len(d) != 1 || d[0] != 4 {
+		t.Errorf("expected delta 4, got %v", d)
+	}
+}
+
+func TestOTLPDeltaDoubleCarry(t *testing.T) {
+	c := NewOTLPConverter(OTLPOptions{})
+	sum := func(v float64) *metricspb.Metric {
+		return &metricspb.Metric{Name: "cpu.seconds", Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+			IsMonotonic:            true,
+			DataPoints:             []*metricspb.NumberDataPoint{{Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: v}}},
+		}}}
+	}
+
+	var total int64
+	for _, v := range []float64{0.6, 0.6, 0.9} {
+		for _, d := range convertDeltas(t, c, sum(v)) {
+			total += d
+		}
+	}
+	if total != 2 {
+		t.Errorf("expected carried total 2, got %d", total)
+	}
+}
+
+func TestOTLPGaugesAndUnsupported(t *testing.T) {
+	c := NewOTLPConverter(OTLPOptions{ResourcePrefix: "service.name"})
+
+	gauge := &metricspb.Metric{Name: "queue.size", Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+		DataPoints: []*metricspb.NumberDataPoint{
+			{TimeUnixNano: 2, Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 7}, Attributes: []*commonpb.KeyValue{stringAttr("queue", "emails")}},
+			{TimeUnixNano: 1, Value: &metricspb.NumberDataPoint_AsInt{AsInt: 3}, Attributes: []*commonpb.KeyValue{stringAttr("queue", "emails")}},
+		},
+	}}}
+	upDown := &metricspb.Metric{Name: "connections", Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+		AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		DataPoints:             []*metricspb.NumberDataPoint{{Value: &metricspb.NumberDataPoint_AsInt{AsInt: -2}}},
+	}}}
+	histogram := &metricspb.Metric{Name: "latency", Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+		DataPoints: []*metricspb.HistogramDataPoint{{}, {}},
+	}}}
+
+	res, err := c.Convert(otlpRequest(gauge, upDown, histogram), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rejected != 2 || len(res.Errors) != 1 {
+		t.Errorf("expected 2 rejected histogram points, got %d %v", res.Rejected, res.Errors)
+	}
+	if len(res.Metrics) != 3 {
+		t.Fatalf("metrics = %+v", res.Metrics)
+	}
+	if m := res.Metrics[2]; m.ID != "checkout.queue.size.queue:emails" || m.MType != models.Gauge || *m.Value != 7 {
+		t.Errorf("latest gauge point must be last: %+v", m)
+	}
+	if m := res.Metrics[0]; m.ID != "checkout.connections" || m.MType != models.Gauge || *m.Value != -2 {
+		t.Errorf("non-monotonic cumulative sum must be a gauge: %+v", m)
+	}
+
+	if _, err := c.Convert(otlpRequest(gauge), 1); err != ErrTooManyItems {
+		t.Errorf("expected ErrTooManyItems, got %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package ingest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Типы семейств метрик формата экспозиции Prometheus.
+const (
+	PromCounter   = "counter"
+	PromGauge     = "gauge"
+	PromUntyped   = "untyped"
+	PromHistogram = "histogram"
+	PromSummary   = "summary"
+)
+
+// PromSample — отсчет формата экспозиции Prometheus.
+type PromSample struct {
+	// Name — имя отсчета, Family — имя семейства из строки # TYPE. Для счетчиков
+	// OpenMetrics Name оканчивается на _total, а Family — нет.
+	Name   string
+	Family string
+	Type   string
+	Labels []Tag
+	Value  float64
+}
+
+// ParsePrometheus разбирает текстовый формат экспозиции Prometheus (и совместимый
+// с ним OpenMetrics). Метки отсчета сортируются по ключу. Отсчеты семейств без
+// строки # TYPE получают тип PromUntyped.
+func ParsePrometheus(r io.Reader) ([]PromSample, error) {
+	types := make(map[string]string)
+	var samples []PromSample
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+			if len(fields) >= 4 && fields[1] == "TYPE" {
+				types[fields[2]] = strings.ToLower(fields[3])
+			}
+			continue
+		}
+
+		s, err := parsePromSample(line)
+		if err != nil {
+			return samples, fmt.Errorf("line %d: %w", n, err)
+		}
+		s.Family, s.Type = promFamily(s.Name, types)
+		samples = append(samples, s)
+	}
+	return samples, scanner.Err()
+}
+
+// promFamily находит семейство отсчета по объявленным типам с учетом суффиксов
+// _total, _created, _sum, _count и _bucket.
+func promFamily(name string, types map[string]string) (string, string) {
+	if t, ok := types[name]; ok {
+		return name, t
+	}
+	for _, suffix := range []string{"_total", "_created", "_sum", "_count", "_bucket"} {
+		if family, ok := strings.CutSuffix(name, suffix); ok {
+			if t, ok := types[family]; ok {
+				return family, t
+			}
+		}
+	}
+	return name, PromUntyped
+}
+
+// parsePromSample разбирает строку name[{labels}] value [timestamp].
+func parsePromSample(line string) (PromSample, error) {
+	var s PromSample
+
+	i := strings.IndexAny(line, "{ \t")
+	if i <= 0 {
+		return s, fmt.Errorf("%w: expected \"name value\"", ErrSyntax)
+	}
+	s.Name = line[:i]
+	rest := line[i:]
+
+	if rest[0] == '{' {
+		labels, n, err := parsePromLabels(rest)
+		if err != nil {
+			return s, err
+		}
+		s.Labels = labels
+		rest = rest[n:]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) < 1 || len(fields) > 2 {
+		return s, fmt.Errorf("%w: expected value and optional timestamp", ErrSyntax)
+	}
+
+	value, err := parsePromValue(fields[0])
+	if err != nil {
+		return s, err
+	}
+	s.Value = value
+	return s, nil
+}
+
+// parsePromLabels разбирает {key="value",...} и возвращает число прочитанных байт.
+func parsePromLabels(s string) ([]Tag, int, error) {
+	var labels []Tag
+	i := 1
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("%w: unterminated label set", ErrSyntax)
+		}
+		if s[i] == '}' {
+			i++
+			break
+		}
+
+		eq := strings.IndexByte(s[i:], '=')
+		if eq <= 0 {
+			return nil, 0, fmt.Errorf("%w: invalid label", ErrSyntax)
+		}
+		key := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+		if i >= len(s) || s[i] != '"' {
+			return nil, 0, fmt.Errorf("%w: label %q value must be quoted", ErrSyntax, key)
+		}
+		i++
+
+		var value strings.Builder
+		for {
+			if i >= len(s) {
+				return nil, 0, fmt.Errorf("%w: unterminated label %q value", ErrSyntax, key)
+			}
+			c := s[i]
+			i++
+			if c == '"' {
+				break
+			}
+			if c == '\\' && i < len(s) {
+				c = s[i]
+				i++
+				if c == 'n' {
+					c = '\n'
+				}
+			}
+			value.WriteByte(c)
+		}
+		labels = append(labels, Tag{Key: key, Value: value.String()})
+	}
+
+	sort.Slice(labels, func(a, b int) bool { return labels[a].Key < labels[b].Key })
+	return labels, i, nil
+}
+
+func parsePromValue(s string) (float64, error) {
+	switch s {
+	case "+Inf", "Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid value %q", ErrSyntax, s)
+	}
+	return v, nil
+}
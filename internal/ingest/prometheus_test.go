@@ -0,0 +1,59 @@
+package ingest
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParsePrometheus(t *testing.T) {
+	input := `# HELP http_requests_total Requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",code="200"} 1027 1395066363000
+http_requests_total{code="500",method="POST"} 3
+# TYPE queue_depth gauge
+queue_depth 12.5
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.5"} 0.05
+rpc_duration_seconds_count 10
+# TYPE jobs counter
+jobs_total 4
+jobs_created 1.7e9
+label_escapes{path="C:\\dir \"x\"\nnext"} NaN
+no_type 1
+# EOF
+`
+	samples, err := ParsePrometheus(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 9 {
+		t.Fatalf("expected 9 samples, got %d: %+v", len(samples), samples)
+	}
+
+	first := samples[0]
+	if first.Type != PromCounter || first.Value != 1027 || len(first.Labels) != 2 || first.Labels[0].Key != "code" {
+		t.Errorf("unexpected first sample: %+v", first)
+	}
+	if samples[2].Type != PromGauge || samples[2].Value != 12.5 {
+		t.Errorf("unexpected gauge: %+v", samples[2])
+	}
+	if samples[4].Family != "rpc_duration_seconds" || samples[4].Type != PromSummary {
+		t.Errorf("unexpected summary count: %+v", samples[4])
+	}
+	if samples[5].Family != "jobs" || samples[5].Type != PromCounter {
+		t.Errorf("unexpected OpenMetrics counter: %+v", samples[5])
+	}
+	if s := samples[7]; s.Labels[0].Value != "C:\\dir \"x\"\nnext" || !math.IsNaN(s.Value) {
+		t.Errorf("unexpected escaped labels: %+v", s)
+	}
+	if samples[8].Type != PromUntyped {
+		t.Errorf("expected untyped sample, got %+v", samples[8])
+	}
+
+	for _, bad := range []string{"name", `name{a="1" 1`, `name{a=1} 1`, "name abc"} {
+		if _, err := ParsePrometheus(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
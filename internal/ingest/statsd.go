@@ -0,0 +1,132 @@
+package ingest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+// Типы строк протокола StatsD.
+const (
+	StatsDCounter   = "c"
+	StatsDGauge     = "g"
+	StatsDTimer     = "ms"
+	StatsDHistogram = "h"
+	StatsDDistrib   = "d"
+	StatsDSet       = "s"
+)
+
+// StatsDSample — строка протокола StatsD любого типа.
+type StatsDSample struct {
+	// Name — имя метрики вместе с тегами.
+	Name string
+
+	// Base и Tags — имя метрики без тегов и теги, отсортированные по ключу.
+	Base string
+	Tags []Tag
+
+	// Type — один из типов StatsDCounter, StatsDGauge, StatsDTimer и т.д.
+	Type string
+
+	// Value — значение строки. Для счетчика уже разделено на частоту выборки.
+	// Для множества не используется.
+	Value float64
+
+	// Rate — частота выборки @rate, по умолчанию 1.
+	Rate float64
+
+	// Relative означает, что значение gauge со знаком изменяет текущее значение.
+	Relative bool
+
+	// Member — элемент множества для типа StatsDSet.
+	Member string
+}
+
+// ParseStatsD разбирает строку протокола StatsD:
+//
+//	name:value|type[|@rate][|#tag:value,tag2:value2]
+//
+// Поддерживаются типы c, g, ms, h, d и s. Значение счетчика делится на частоту
+// выборки @rate. Значение множества s может быть произвольной строкой. Теги в
+// формате DogStatsD добавляются к имени метрики.
+func ParseStatsD(line string) (StatsDSample, error) {
+	s := StatsDSample{Rate: 1}
+
+	parts := strings.Split(line, "|")
+	if len(parts) < 2 {
+		return s, fmt.Errorf("%w: expected \"name:value|type\"", ErrSyntax)
+	}
+
+	sep := strings.LastIndexByte(parts[0], ':')
+	if sep <= 0 || sep == len(parts[0])-1 {
+		return s, fmt.Errorf("%w: missing metric name or value", ErrSyntax)
+	}
+	name, rawValue := parts[0][:sep], parts[0][sep+1:]
+
+	s.Type = parts[1]
+	switch s.Type {
+	case StatsDCounter, StatsDGauge, StatsDTimer, StatsDHistogram, StatsDDistrib:
+		value, err := strconv.ParseFloat(rawValue, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return s, fmt.Errorf("%w: invalid value %q", ErrSyntax, rawValue)
+		}
+		s.Value = value
+		s.Relative = s.Type == StatsDGauge && (rawValue[0] == '+' || rawValue[0] == '-')
+	case StatsDSet:
+		s.Member = rawValue
+	default:
+		return s, fmt.Errorf("%w: unknown metric type %q", ErrSyntax, parts[1])
+	}
+
+	var tags []Tag
+	for _, ext := range parts[2:] {
+		switch {
+		case strings.HasPrefix(ext, "@"):
+			rate, err := strconv.ParseFloat(ext[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return s, fmt.Errorf("%w: invalid sample rate %q", ErrSyntax, ext)
+			}
+			s.Rate = rate
+			if s.Type == StatsDCounter {
+				s.Value /= rate
+			}
+		case strings.HasPrefix(ext, "#"):
+			for _, rawTag := range strings.Split(ext[1:], ",") {
+				key, tagValue, _ := strings.Cut(rawTag, ":")
+				if key != "" && tagValue != "" {
+					tags = append(tags, Tag{Key: key, Value: tagValue})
+				}
+			}
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+
+	s.Base, s.Tags = sanitize(name, true), tags
+	s.Name = MetricName(s.Base, tags)
+	return s, nil
+}
+
+// ParseStatsDLine разбирает строку протокола StatsD типа c (counter) или g (gauge),
+// см. ParseStatsD. Значение gauge со знаком + или - изменяет текущее значение
+// метрики. Таймеры, гистограммы и множества не поддерживаются.
+func ParseStatsDLine(line string) (Sample, error) {
+	parsed, err := ParseStatsD(line)
+	if err != nil {
+		return Sample{}, err
+	}
+
+	s := Sample{Name: parsed.Name, Value: parsed.Value, Relative: parsed.Relative}
+	switch parsed.Type {
+	case StatsDCounter:
+		s.MType = models.Counter
+	case StatsDGauge:
+		s.MType = models.Gauge
+	default:
+		return Sample{}, fmt.Errorf("unsupported StatsD metric type %q", parsed.Type)
+	}
+	return s, nil
+}
@@ -0,0 +1,137 @@
+package listener
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/ingest"
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/repository"
+)
+
+// gaugeState — накопленное за интервал значение gauge.
+type gaugeState struct {
+	// set означает, что за интервал получено абсолютное значение value.
+	set   bool
+	value float64
+	time  time.Time
+
+	// delta — сумма относительных изменений StatsD после последнего абсолютного значения.
+	delta float64
+}
+
+// aggregator накапливает значения метрик между сбросами в хранилище.
+// Приращения счетчиков суммируются, для gauge остается последнее значение.
+type aggregator struct {
+	mu       sync.Mutex
+	counters map[string]float64
+	gauges   map[string]*gaugeState
+}
+
+func newAggregator() *aggregator {
+	return &aggregator{
+		counters: make(map[string]float64),
+		gauges:   make(map[string]*gaugeState),
+	}
+}
+
+// add учитывает значение. Значения без метки времени считаются полученными сейчас.
+// Абсолютное значение gauge с меткой времени раньше уже полученного игнорируется.
+func (a *aggregator) add(s ingest.Sample) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if s.MType == models.Counter {
+		a.counters[s.Name] += s.Value
+		return
+	}
+
+	st, ok := a.gauges[s.Name]
+	if !ok {
+		st = &gaugeState{}
+		a.gauges[s.Name] = st
+	}
+
+	if s.Relative {
+		st.delta += s.Value
+		return
+	}
+
+	ts := s.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	if st.set && ts.Before(st.time) {
+		return
+	}
+	st.set = true
+	st.value = s.Value
+	st.time = ts
+	st.delta = 0
+}
+
+// take забирает накопленные значения и возвращает их в виде пакета метрик.
+// Дробная часть суммы счетчика остается в агрегаторе до следующего сброса.
+// Для относительных изменений gauge без абсолютного значения за основу берется
+// текущее значение из storage.
+func (a *aggregator) take(storage repository.Storage) models.ListMetrics {
+	a.mu.Lock()
+	counters, gauges := a.counters, a.gauges
+	a.counters = make(map[string]float64)
+	a.gauges = make(map[string]*gaugeState)
+
+	list := models.ListMetrics{List: make([]models.Metrics, 0, len(counters)+len(gauges))}
+	for name, sum := range counters {
+		whole := math.Trunc(sum)
+		if rest := sum - whole; rest != 0 {
+			a.counters[name] = rest
+		}
+		if whole == 0 {
+			continue
+		}
+		if whole >= math.MaxInt64 || whole < math.MinInt64 {
+			continue
+		}
+
+		delta := int64(whole)
+		list.List = append(list.List, models.Metrics{ID: name, MType: models.Counter, Delta: &delta})
+	}
+	a.mu.Unlock()
+
+	for name, st := range gauges {
+		value := st.value + st.delta
+		if !st.set {
+			current, err := storage.GetGauge(name)
+			if err != nil && !errors.Is(err, repository.ErrMetricNotFound) {
+				// Основа неизвестна: изменение возвращается в агрегатор до следующего сброса.
+				a.add(ingest.Sample{Name: name, MType: models.Gauge, Value: st.delta, Relative: true})
+				continue
+			}
+			value = float64(current) + st.delta
+		}
+
+		v := value
+		list.List = append(list.List, models.Metrics{ID: name, MType: models.Gauge, Value: &v})
+	}
+
+	return list
+}
+
+// restore возвращает в агрегатор пакет, который не удалось записать. Значения
+// gauge, полученные после take, не перезаписываются.
+func (a *aggregator) restore(list models.ListMetrics) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, m := range list.List {
+		if m.MType == models.Counter {
+			a.counters[m.ID] += float64(*m.Delta)
+			continue
+		}
+		if _, ok := a.gauges[m.ID]; !ok {
+			a.gauges[m.ID] = &gaugeState{set: true, value: *m.Value}
+		}
+	}
+}
@@ -0,0 +1,315 @@
+// Package listener предоставляет приемники метрик по протоколам Graphite plaintext
+// (TCP) и StatsD (UDP).
+//
+// Полученные значения агрегируются в памяти и раз в интервал сброса записываются
+// в хранилище одним вызовом InsertMetricsBatch: приращения счетчиков суммируются,
+// для gauge остается последнее значение.
+package listener
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/audit"
+	"github.com/levinOo/go-metrics-project/internal/ingest"
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/repository"
+)
+
+const (
+	// maxGraphiteLineSize ограничивает длину строки Graphite.
+	maxGraphiteLineSize = 64 * 1024
+
+	// maxStatsDPacketSize — максимальный размер UDP-пакета StatsD.
+	maxStatsDPacketSize = 65535
+
+	// graphiteIdleTimeout — время бездействия, после которого TCP-соединение закрывается.
+	graphiteIdleTimeout = 5 * time.Minute
+)
+
+// Options задает параметры приемников.
+type Options struct {
+	// GraphiteAddr — TCP-адрес приемника Graphite. Пустая строка отключает приемник.
+	GraphiteAddr string
+
+	// StatsDAddr — UDP-адрес приемника StatsD. Пустая строка отключает приемник.
+	StatsDAddr string
+
+	// FlushInterval — интервал записи накопленных метрик в хранилище.
+	FlushInterval time.Duration
+
+	// GraphiteTypes определяет тип метрик Graphite по пути. По умолчанию все
+	// метрики Graphite записываются как gauge.
+	GraphiteTypes *ingest.TypeMapping
+
+	// AuditFile и AuditURL задают получателей событий аудита для записанных пакетов.
+	AuditFile string
+	AuditURL  string
+}
+
+// Listeners управляет приемниками Graphite и StatsD и периодической записью
+// накопленных метрик. Приемники необходимо запустить методом Start и остановить
+// методом Stop.
+type Listeners struct {
+	storage repository.Storage
+	opts    Options
+	agg     *aggregator
+
+	tcp net.Listener
+	udp net.PacketConn
+
+	connMu sync.Mutex
+	conns  map[net.Conn]struct{}
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+// New создает приемники, записывающие метрики в storage.
+func New(storage repository.Storage, opts Options) *Listeners {
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 10 * time.Second
+	}
+	if opts.GraphiteTypes == nil {
+		opts.GraphiteTypes, _ = ingest.ParseTypeMapping("")
+	}
+
+	return &Listeners{
+		storage: storage,
+		opts:    opts,
+		agg:     newAggregator(),
+		conns:   make(map[net.Conn]struct{}),
+		stopCh:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start открывает сокеты включенных приемников и запускает их в фоновых горутинах.
+// Если сокет открыть не удалось, уже открытые закрываются и возвращается ошибка.
+func (l *Listeners) Start() error {
+	if l.opts.GraphiteAddr != "" {
+		tcp, err := net.Listen("tcp", l.opts.GraphiteAddr)
+		if err != nil {
+			return fmt.Errorf("failed to start Graphite listener: %w", err)
+		}
+		l.tcp = tcp
+	}
+
+	if l.opts.StatsDAddr != "" {
+		udp, err := net.ListenPacket("udp", l.opts.StatsDAddr)
+		if err != nil {
+			if l.tcp != nil {
+				l.tcp.Close()
+			}
+			return fmt.Errorf("failed to start StatsD listener: %w", err)
+		}
+		l.udp = udp
+	}
+
+	if l.tcp != nil {
+		l.wg.Add(1)
+		go l.acceptGraphite()
+	}
+	if l.udp != nil {
+		l.wg.Add(1)
+		go l.readStatsD()
+	}
+
+	go func() {
+		defer close(l.done)
+		ticker := time.NewTicker(l.opts.FlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				l.flush()
+			case <-l.stopCh:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// GraphiteAddr возвращает адрес приемника Graphite или nil, если он не запущен.
+func (l *Listeners) GraphiteAddr() net.Addr {
+	if l.tcp == nil {
+		return nil
+	}
+	return l.tcp.Addr()
+}
+
+// StatsDAddr возвращает адрес приемника StatsD или nil, если он не запущен.
+func (l *Listeners) StatsDAddr() net.Addr {
+	if l.udp == nil {
+		return nil
+	}
+	return l.udp.LocalAddr()
+}
+
+// Stop закрывает сокеты и соединения Graphite, дожидается обработки уже
+// прочитанных строк и записывает накопленные метрики в хранилище. Вызывается
+// только после успешного Start.
+func (l *Listeners) Stop() {
+	close(l.stopCh)
+
+	if l.tcp != nil {
+		l.tcp.Close()
+	}
+	if l.udp != nil {
+		l.udp.Close()
+	}
+
+	l.connMu.Lock()
+	for conn := range l.conns {
+		conn.SetReadDeadline(time.Now())
+	}
+	l.connMu.Unlock()
+
+	l.wg.Wait()
+	<-l.done
+	l.flush()
+}
+
+func (l *Listeners) acceptGraphite() {
+	defer l.wg.Done()
+
+	for {
+		conn, err := l.tcp.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("Graphite accept error: %v", err)
+			}
+			return
+		}
+
+		l.connMu.Lock()
+		l.conns[conn] = struct{}{}
+		l.connMu.Unlock()
+
+		l.wg.Add(1)
+		go l.serveGraphite(conn)
+	}
+}
+
+func (l *Listeners) serveGraphite(conn net.Conn) {
+	defer l.wg.Done()
+	defer func() {
+		l.connMu.Lock()
+		delete(l.conns, conn)
+		l.connMu.Unlock()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(&idleReader{conn: conn, l: l})
+	scanner.Buffer(make([]byte, 0, 4096), maxGraphiteLineSize)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		sample, err := l.opts.GraphiteTypes.ParseGraphiteLine(string(line))
+		if err != nil {
+			log.Printf("Graphite line from %s rejected: %v", conn.RemoteAddr(), err)
+			continue
+		}
+		l.agg.add(sample)
+	}
+
+	if err := scanner.Err(); err != nil && !isTimeout(err) {
+		log.Printf("Graphite connection %s error: %v", conn.RemoteAddr(), err)
+	}
+}
+
+// idleReader продлевает таймаут чтения соединения перед каждым чтением,
+// пока приемники не останавливаются. Продление выполняется под connMu, чтобы
+// не отменить таймаут, выставленный в Stop.
+type idleReader struct {
+	conn net.Conn
+	l    *Listeners
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	r.l.connMu.Lock()
+	select {
+	case <-r.l.stopCh:
+	default:
+		r.conn.SetReadDeadline(time.Now().Add(graphiteIdleTimeout))
+	}
+	r.l.connMu.Unlock()
+
+	return r.conn.Read(p)
+}
+
+func (l *Listeners) readStatsD() {
+	defer l.wg.Done()
+
+	buf := make([]byte, maxStatsDPacketSize)
+	for {
+		n, addr, err := l.udp.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("StatsD read error: %v", err)
+			}
+			return
+		}
+
+		for _, line := range bytes.Split(buf[:n], []byte{'\n'}) {
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+
+			sample, err := ingest.ParseStatsDLine(string(line))
+			if err != nil {
+				log.Printf("StatsD line from %s rejected: %v", addr, err)
+				continue
+			}
+			l.agg.add(sample)
+		}
+	}
+}
+
+// flush записывает накопленные метрики. Если хранилище недоступно, метрики
+// возвращаются в агрегатор и записываются при следующем сбросе.
+func (l *Listeners) flush() {
+	metrics := l.agg.take(l.storage)
+	if len(metrics.List) == 0 {
+		return
+	}
+
+	valid, result := repository.PrepareBatch(metrics, models.BatchModeBestEffort)
+	for _, item := range result.Items {
+		if item.Status == models.BatchRejected {
+			log.Printf("Listener metric %s rejected: %s", item.ID, item.Reason)
+		}
+	}
+	if len(valid.List) == 0 {
+		return
+	}
+
+	if err := l.storage.InsertMetricsBatch(valid); err != nil && !errors.Is(err, repository.ErrQueued) {
+		log.Printf("Failed to write listener metrics: %v", err)
+		if !errors.Is(err, repository.ErrInvalidMetric) {
+			l.agg.restore(valid)
+		}
+		return
+	}
+	audit.NewAuditEvent(valid, l.opts.AuditFile, l.opts.AuditURL, "")
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
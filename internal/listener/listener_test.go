@@ -0,0 +1,87 @@
+package listener
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/repository"
+)
+
+func TestListeners(t *testing.T) {
+	storage := repository.NewMemStorage()
+	if err := storage.SetGauge("pool.size", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	l := New(storage, Options{
+		GraphiteAddr:  "127.0.0.1:0",
+		StatsDAddr:    "127.0.0.1:0",
+		FlushInterval: time.Hour,
+	})
+	if err := l.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	tcp, err := net.Dial("tcp", l.GraphiteAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(tcp, "disk.used 1 100\ndisk.used 3 300\ndisk.used 2 200\nbroken\n")
+
+	udp, err := net.Dial("udp", l.StatsDAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(udp, "jobs:1|c|@0.5\njobs:3|c\npool.size:-4|g")
+	udp.Close()
+
+	// Дожидаемся, пока приемники прочитают данные: Stop закрывает сокеты.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		l.agg.mu.Lock()
+		n := len(l.agg.counters) + len(l.agg.gauges)
+		l.agg.mu.Unlock()
+		if n == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("listeners received %d metrics", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	l.Stop()
+	tcp.Close()
+
+	if val, _ := storage.GetGauge("disk.used"); val != 3 {
+		t.Errorf("expected latest Graphite value 3, got %v", val)
+	}
+	if val, _ := storage.GetCounter("jobs"); val != 5 {
+		t.Errorf("expected counter 5, got %d", val)
+	}
+	if val, _ := storage.GetGauge("pool.size"); val != 6 {
+		t.Errorf("expected relative gauge 6, got %v", val)
+	}
+}
+
+func TestAggregatorCarry(t *testing.T) {
+	storage := repository.NewMemStorage()
+	a := newAggregator()
+
+	for i := 0; i < 3; i++ {
+		a.counters["ratio"] += 0.5
+		list := a.take(storage)
+		if err := storage.InsertMetricsBatch(list); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if val, _ := storage.GetCounter("ratio"); val != 1 {
+		t.Errorf("expected counter 1, got %d", val)
+	}
+	if rest := a.counters["ratio"]; rest != 0.5 {
+		t.Errorf("expected carry 0.5, got %v", rest)
+	}
+}
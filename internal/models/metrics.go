@@ -3,6 +3,11 @@
 package models
 
 //go:generate go run ../../cmd/reset/main.go
+//go:generate go run github.com/tinylib/msgp -file metrics.go -o metrics_msgp.go -tests=false
+
+// Кодирование MessagePack генерируется только для типов, передаваемых по HTTP.
+// ListMetrics кодируется массивом вручную, см. MarshalMsgpack.
+//msgp:ignore ListMetrics Data DataList
 
 // Константы типов метрик
 const (
@@ -27,21 +32,21 @@ type ListMetrics struct {
 // generate:reset
 type Metrics struct {
 	// ID содержит уникальное имя метрики.
-	ID string `json:"id"`
+	ID string `json:"id" msg:"id"`
 
 	// MType определяет тип метрики: "gauge" или "counter".
-	MType string `json:"type"`
+	MType string `json:"type" msg:"type"`
 
 	// Delta содержит значение для counter-метрик (изменение счётчика).
 	// Используется только когда MType = "counter".
-	Delta *int64 `json:"delta,omitempty"`
+	Delta *int64 `json:"delta,omitempty" msg:"delta,omitempty"`
 
 	// Value содержит значение для gauge-метрик (текущее измерение).
 	// Используется только когда MType = "gauge".
-	Value *float64 `json:"value,omitempty"`
+	Value *float64 `json:"value,omitempty" msg:"value,omitempty"`
 
 	// Hash содержит HMAC SHA256 подпись метрики для проверки целостности.
-	Hash string `json:"hash,omitempty"`
+	Hash string `json:"hash,omitempty" msg:"hash,omitempty"`
 }
 
 // Data представляет событие аудита с информацией об обновлении метрик.
@@ -57,9 +62,82 @@ type Data struct {
 
 	// IP содержит IP-адрес клиента, выполнившего операцию.
 	IP string `json:"ip_address"`
+
+	// Action содержит административное действие, например "counter_reset" или
+	// "counter_set". Для обычного обновления метрик поле пустое.
+	Action string `json:"action,omitempty"`
+
+	// Value содержит значение, установленное административным действием.
+	Value *int64 `json:"value,omitempty"`
 }
 
 // generate:reset
 type DataList struct {
 	Events []Data `json:"events"`
 }
+
+// Статусы обработки отдельной метрики в пакетном обновлении.
+const (
+	// BatchApplied означает, что метрика записана в хранилище.
+	BatchApplied = "applied"
+
+	// BatchRejected означает, что метрика не прошла проверку и не записана.
+	BatchRejected = "rejected"
+
+	// BatchMerged означает, что метрика объединена с предыдущей метрикой
+	// с тем же именем в этом же пакете.
+	BatchMerged = "merged"
+
+	// BatchQueued означает, что хранилище недоступно и метрика помещена в буфер
+	// записи: она будет записана после восстановления хранилища.
+	BatchQueued = "queued"
+)
+
+// Режимы пакетного обновления.
+const (
+	// BatchModeAtomic — пакет применяется целиком или не применяется вовсе.
+	BatchModeAtomic = "atomic"
+
+	// BatchModeBestEffort — применяются все корректные метрики пакета.
+	BatchModeBestEffort = "best-effort"
+)
+
+// BatchItemResult описывает результат обработки одной метрики пакета.
+
+// generate:reset
+type BatchItemResult struct {
+	// Index содержит позицию метрики в исходном пакете.
+	Index int `json:"index" msg:"index"`
+
+	// ID содержит имя метрики.
+	ID string `json:"id" msg:"id"`
+
+	// MType содержит тип метрики.
+	MType string `json:"type" msg:"type"`
+
+	// Status содержит статус обработки: "applied", "rejected", "merged" или "queued".
+	Status string `json:"status" msg:"status"`
+
+	// Reason содержит причину отклонения метрики.
+	Reason string `json:"reason,omitempty" msg:"reason,omitempty"`
+}
+
+// BatchResult содержит результаты пакетного обновления метрик.
+
+// generate:reset
+type BatchResult struct {
+	// Status содержит итог обработки пакета: "ok", "partial" или "rejected".
+	Status string `json:"status" msg:"status"`
+
+	// Mode содержит режим обработки пакета.
+	Mode string `json:"mode" msg:"mode"`
+
+	// Applied, Merged, Rejected и Queued содержат количество метрик с соответствующим статусом.
+	Applied  int `json:"applied" msg:"applied"`
+	Merged   int `json:"merged" msg:"merged"`
+	Rejected int `json:"rejected" msg:"rejected"`
+	Queued   int `json:"queued,omitempty" msg:"queued,omitempty"`
+
+	// Items содержит результаты по каждой метрике в порядке следования в пакете.
+	Items []BatchItemResult `json:"items" msg:"items"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// ErrMsgpackTooManyItems возвращается UnmarshalMsgpack, если массив содержит больше
+// метрик, чем разрешено.
+var ErrMsgpackTooManyItems = errors.New("too many metrics in MessagePack array")
+
+// MarshalMsgpack кодирует список метрик как массив MessagePack, аналогичный
+// JSON-массиву в теле запроса /updates.
+func (v ListMetrics) MarshalMsgpack() ([]byte, error) {
+	b := msgp.AppendArrayHeader(nil, uint32(len(v.List)))
+	for i := range v.List {
+		var err error
+		b, err = v.List[i].MarshalMsg(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalMsgpack разбирает массив метрик MessagePack. Если maxItems больше нуля
+// и метрик больше, возвращается ErrMsgpackTooManyItems.
+func (v *ListMetrics) UnmarshalMsgpack(data []byte, maxItems int) error {
+	n, rest, err := msgp.ReadArrayHeaderBytes(data)
+	if err != nil {
+		return err
+	}
+	if maxItems > 0 && int(n) > maxItems {
+		return ErrMsgpackTooManyItems
+	}
+	// Заголовок массива не подтверждает, что элементы действительно есть в теле.
+	if int(n) > len(rest) {
+		return msgp.ErrShortBytes
+	}
+
+	v.List = make([]Metrics, n)
+	for i := range v.List {
+		rest, err = v.List[i].UnmarshalMsg(rest)
+		if err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%d trailing bytes after MessagePack array", len(rest))
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Кодирование Protocol Buffers по схеме api/metrics.proto. Неизвестные поля
+// при разборе пропускаются.
+
+// ErrProtoTooManyItems возвращается UnmarshalProto, если список содержит больше
+// метрик, чем разрешено.
+var ErrProtoTooManyItems = errors.New("too many metrics in protobuf list")
+
+// MarshalProto кодирует метрику как сообщение Metric.
+func (v Metrics) MarshalProto() ([]byte, error) {
+	return v.appendProto(nil), nil
+}
+
+func (v Metrics) appendProto(b []byte) []byte {
+	b = appendProtoString(b, 1, v.ID)
+	b = appendProtoString(b, 2, v.MType)
+	if v.Delta != nil {
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(*v.Delta))
+	}
+	if v.Value != nil {
+		b = protowire.AppendTag(b, 4, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(*v.Value))
+	}
+	b = appendProtoString(b, 5, v.Hash)
+	return b
+}
+
+// UnmarshalProto разбирает сообщение Metric.
+func (v *Metrics) UnmarshalProto(data []byte) error {
+	*v = Metrics{}
+	return consumeProtoFields(data, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			s, n := protowire.ConsumeString(b)
+			v.ID = s
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			s, n := protowire.ConsumeString(b)
+			v.MType = s
+			return n, nil
+		case num == 3 && typ == protowire.VarintType:
+			x, n := protowire.ConsumeVarint(b)
+			delta := int64(x)
+			v.Delta = &delta
+			return n, nil
+		case num == 4 && typ == protowire.Fixed64Type:
+			x, n := protowire.ConsumeFixed64(b)
+			value := math.Float64frombits(x)
+			v.Value = &value
+			return n, nil
+		case num == 5 && typ == protowire.BytesType:
+			s, n := protowire.ConsumeString(b)
+			v.Hash = s
+			return n, nil
+		default:
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+	})
+}
+
+// MarshalProto кодирует список как сообщение MetricList.
+func (v ListMetrics) MarshalProto() ([]byte, error) {
+	var b []byte
+	for _, m := range v.List {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.appendProto(nil))
+	}
+	return b, nil
+}
+
+// UnmarshalProto разбирает сообщение MetricList. Если maxItems больше нуля и
+// метрик больше, возвращается ErrProtoTooManyItems.
+func (v *ListMetrics) UnmarshalProto(data []byte, maxItems int) error {
+	v.List = nil
+	return consumeProtoFields(data, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num != 1 || typ != protowire.BytesType {
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+
+		msg, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return n, nil
+		}
+		if maxItems > 0 && len(v.List) == maxItems {
+			return 0, ErrProtoTooManyItems
+		}
+
+		var m Metrics
+		if err := m.UnmarshalProto(msg); err != nil {
+			return 0, fmt.Errorf("item %d: %w", len(v.List), err)
+		}
+		v.List = append(v.List, m)
+		return n, nil
+	})
+}
+
+// MarshalProto кодирует результат как сообщение BatchResult.
+func (v BatchResult) MarshalProto() ([]byte, error) {
+	var b []byte
+	b = appendProtoString(b, 1, v.Status)
+	b = appendProtoString(b, 2, v.Mode)
+	b = appendProtoInt(b, 3, int64(v.Applied))
+	b = appendProtoInt(b, 4, int64(v.Merged))
+	b = appendProtoInt(b, 5, int64(v.Rejected))
+	b = appendProtoInt(b, 7, int64(v.Queued))
+	for _, item := range v.Items {
+		var ib []byte
+		ib = appendProtoInt(ib, 1, int64(item.Index))
+		ib = appendProtoString(ib, 2, item.ID)
+		ib = appendProtoString(ib, 3, item.MType)
+		ib = appendProtoString(ib, 4, item.Status)
+		ib = appendProtoString(ib, 5, item.Reason)
+
+		b = protowire.AppendTag(b, 6, protowire.BytesType)
+		b = protowire.AppendBytes(b, ib)
+	}
+	return b, nil
+}
+
+// UnmarshalProto разбирает сообщение BatchResult.
+func (v *BatchResult) UnmarshalProto(data []byte) error {
+	*v = BatchResult{}
+	return consumeProtoFields(data, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			s, n := protowire.ConsumeString(b)
+			v.Status = s
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			s, n := protowire.ConsumeString(b)
+			v.Mode = s
+			return n, nil
+		case (num >= 3 && num <= 5 || num == 7) && typ == protowire.VarintType:
+			x, n := protowire.ConsumeVarint(b)
+			switch num {
+			case 3:
+				v.Applied = int(int64(x))
+			case 4:
+				v.Merged = int(int64(x))
+			case 5:
+				v.Rejected = int(int64(x))
+			default:
+				v.Queued = int(int64(x))
+			}
+			return n, nil
+		case num == 6 && typ == protowire.BytesType:
+			msg, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			item, err := unmarshalProtoItem(msg)
+			if err != nil {
+				return 0, err
+			}
+			v.Items = append(v.Items, item)
+			return n, nil
+		default:
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+	})
+}
+
+func unmarshalProtoItem(data []byte) (BatchItemResult, error) {
+	var item BatchItemResult
+	err := consumeProtoFields(data, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.VarintType {
+			x, n := protowire.ConsumeVarint(b)
+			item.Index = int(int64(x))
+			return n, nil
+		}
+		if num < 2 || num > 5 || typ != protowire.BytesType {
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+
+		s, n := protowire.ConsumeString(b)
+		switch num {
+		case 2:
+			item.ID = s
+		case 3:
+			item.MType = s
+		case 4:
+			item.Status = s
+		default:
+			item.Reason = s
+		}
+		return n, nil
+	})
+	return item, err
+}
+
+// consumeProtoFields перебирает поля сообщения. Функция field разбирает значение
+// поля и возвращает количество прочитанных байт или отрицательное значение при ошибке.
+func consumeProtoFields(data []byte, field func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		n, err := field(num, typ, data)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// appendProtoString добавляет строковое поле, пустые строки не кодируются.
+func appendProtoString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+// appendProtoInt добавляет целочисленное поле, нулевые значения не кодируются.
+func appendProtoInt(b []byte, num protowire.Number, x int64) []byte {
+	if x == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(x))
+}
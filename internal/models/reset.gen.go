@@ -20,6 +20,8 @@ func (s *Data) Reset() {
 	s.TS = 0
 	s.MetricNames = nil
 	s.IP = ""
+	s.Action = ""
+	s.Value = nil
 
 }
 
@@ -27,3 +29,23 @@ func (s *DataList) Reset() {
 	s.Events = nil
 
 }
+
+func (s *BatchItemResult) Reset() {
+	s.Index = 0
+	s.ID = ""
+	s.MType = ""
+	s.Status = ""
+	s.Reason = ""
+
+}
+
+func (s *BatchResult) Reset() {
+	s.Status = ""
+	s.Mode = ""
+	s.Applied = 0
+	s.Merged = 0
+	s.Rejected = 0
+	s.Queued = 0
+	s.Items = nil
+
+}
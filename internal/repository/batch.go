@@ -0,0 +1,268 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/validation"
+)
+
+// ErrInvalidMetric возвращается хранилищем, если пакет содержит некорректную метрику.
+// Совпадает с validation.ErrInvalid, поэтому с ним сопоставляются ошибки всех правил проверки.
+var ErrInvalidMetric = validation.ErrInvalid
+
+// checkBatch проверяет все метрики пакета до начала записи, чтобы хранилище
+// не применяло пакет частично. Сумма приращений одного счетчика внутри пакета
+// также не должна переполнять int64.
+func checkBatch(metrics models.ListMetrics) error {
+	v := validation.Default()
+	sums := make(map[string]int64)
+
+	for i, m := range metrics.List {
+		if err := v.Metric(m); err != nil {
+			return fmt.Errorf("item %d (%s): %w", i, m.ID, err)
+		}
+
+		if m.MType == models.Counter {
+			sum, err := validation.AddCounter(sums[m.ID], *m.Delta)
+			if err != nil {
+				return fmt.Errorf("item %d (%s): %w", i, m.ID, err)
+			}
+			sums[m.ID] = sum
+		}
+	}
+	return nil
+}
+
+// PrepareBatch проверяет каждую метрику пакета и возвращает метрики, которые
+// следует передать в InsertMetricsBatch, вместе с результатами по каждой метрике.
+//
+// Первая корректная метрика с данным именем получает статус "applied", последующие —
+// "merged": хранилище суммирует их delta для counter или перезаписывает value для gauge.
+// Метрика, имя которой в этом же пакете уже встречалось с другим типом, отклоняется,
+// как и приращение счетчика, при котором сумма приращений пакета переполняет int64.
+//
+// В режиме BatchModeAtomic при наличии хотя бы одной отклоненной метрики возвращается
+// пустой список, а все метрики пакета получают статус "rejected".
+func PrepareBatch(metrics models.ListMetrics, mode string) (models.ListMetrics, models.BatchResult) {
+	result := models.BatchResult{
+		Mode:  mode,
+		Items: make([]models.BatchItemResult, len(metrics.List)),
+	}
+	valid := models.ListMetrics{List: make([]models.Metrics, 0, len(metrics.List))}
+	seen := make(map[string]string, len(metrics.List))
+	sums := make(map[string]int64)
+	v := validation.Default()
+
+	for i, m := range metrics.List {
+		item := models.BatchItemResult{Index: i, ID: m.ID, MType: m.MType}
+
+		var reason string
+		if err := v.Metric(m); err != nil {
+			reason = err.Error()
+		} else if mtype, ok := seen[m.ID]; ok && mtype != m.MType {
+			reason = fmt.Sprintf("metric already sent as %s in this batch", mtype)
+		} else if m.MType == models.Counter {
+			sum, err := validation.AddCounter(sums[m.ID], *m.Delta)
+			if err != nil {
+				reason = err.Error()
+			}
+			sums[m.ID] = sum
+		}
+
+		switch {
+		case reason != "":
+			item.Status = models.BatchRejected
+			item.Reason = reason
+			result.Rejected++
+		case seen[m.ID] != "":
+			item.Status = models.BatchMerged
+			result.Merged++
+			valid.List = append(valid.List, m)
+		default:
+			item.Status = models.BatchApplied
+			result.Applied++
+			seen[m.ID] = m.MType
+			valid.List = append(valid.List, m)
+		}
+
+		result.Items[i] = item
+	}
+
+	switch {
+	case result.Rejected == 0:
+		result.Status = "ok"
+	case mode == models.BatchModeAtomic || len(valid.List) == 0:
+		rejectAll(&result)
+		return models.ListMetrics{}, result
+	default:
+		result.Status = "partial"
+	}
+
+	return valid, result
+}
+
+// rejectAll помечает все метрики пакета отклоненными.
+func rejectAll(result *models.BatchResult) {
+	for i := range result.Items {
+		item := &result.Items[i]
+		if item.Status != models.BatchRejected {
+			item.Status = models.BatchRejected
+			item.Reason = "batch rejected"
+		}
+	}
+
+	result.Status = "rejected"
+	result.Rejected = len(result.Items)
+	result.Applied = 0
+	result.Merged = 0
+}
+
+// MarkQueued помечает метрики, переданные в хранилище, статусом "queued", если
+// запись завершилась ошибкой ErrQueued.
+func MarkQueued(result *models.BatchResult) {
+	for i := range result.Items {
+		item := &result.Items[i]
+		if item.Status == models.BatchApplied || item.Status == models.BatchMerged {
+			item.Status = models.BatchQueued
+		}
+	}
+
+	result.Queued += result.Applied + result.Merged
+	result.Applied = 0
+	result.Merged = 0
+}
+
+// InsertBatch записывает метрики valid, подготовленные PrepareBatch, и обновляет result.
+// Возвращает метрики, которые записаны в хранилище или помещены в буфер.
+//
+// Если пакет режима BatchModeBestEffort отклонен хранилищем из-за переполнения
+// сохраненного значения счетчика, он повторяется по частям: метрики с одним именем
+// не разделяются, а часть с переполнением делится пополам, пока не останется одно
+// имя. Его метрики получают статус "rejected", остальные записываются. В режиме
+// BatchModeAtomic ошибка возвращается без изменения result.
+func InsertBatch(storage Storage, valid models.ListMetrics, result *models.BatchResult) (models.ListMetrics, error) {
+	err := storage.InsertMetricsBatch(valid)
+	switch {
+	case err == nil:
+		return valid, nil
+	case errors.Is(err, ErrQueued):
+		MarkQueued(result)
+		return valid, nil
+	case result.Mode != models.BatchModeBestEffort || !errors.Is(err, validation.ErrCounterOverflow):
+		return models.ListMetrics{}, err
+	}
+
+	p := partialInsert{
+		storage:  storage,
+		queued:   make(map[string]bool),
+		rejected: make(map[string]string),
+	}
+	err = p.split(groupByName(valid.List), err)
+
+	for i := range result.Items {
+		item := &result.Items[i]
+		if item.Status != models.BatchApplied && item.Status != models.BatchMerged {
+			continue
+		}
+		if reason, ok := p.rejected[item.ID]; ok {
+			item.Status = models.BatchRejected
+			item.Reason = reason
+		} else if p.queued[item.ID] {
+			item.Status = models.BatchQueued
+		}
+	}
+	recount(result)
+
+	return p.written, err
+}
+
+// partialInsert записывает пакет частями и запоминает исход для каждого имени.
+type partialInsert struct {
+	storage  Storage
+	written  models.ListMetrics
+	queued   map[string]bool
+	rejected map[string]string
+}
+
+// insert записывает группы метрик одним пакетом.
+func (p *partialInsert) insert(groups [][]models.Metrics) error {
+	list := models.ListMetrics{}
+	for _, group := range groups {
+		list.List = append(list.List, group...)
+	}
+
+	err := p.storage.InsertMetricsBatch(list)
+	switch {
+	case errors.Is(err, ErrQueued):
+		for _, group := range groups {
+			p.queued[group[0].ID] = true
+		}
+	case errors.Is(err, validation.ErrCounterOverflow):
+		return p.split(groups, err)
+	case err != nil:
+		return err
+	}
+
+	p.written.List = append(p.written.List, list.List...)
+	return nil
+}
+
+// split обрабатывает группы, пакет которых завершился переполнением cause.
+func (p *partialInsert) split(groups [][]models.Metrics, cause error) error {
+	if len(groups) == 1 {
+		p.rejected[groups[0][0].ID] = cause.Error()
+		return nil
+	}
+
+	mid := len(groups) / 2
+	if err := p.insert(groups[:mid]); err != nil {
+		return err
+	}
+	return p.insert(groups[mid:])
+}
+
+// groupByName разбивает метрики на группы с одинаковым именем в порядке
+// первого появления имени.
+func groupByName(metrics []models.Metrics) [][]models.Metrics {
+	index := make(map[string]int)
+	var groups [][]models.Metrics
+
+	for _, m := range metrics {
+		i, ok := index[m.ID]
+		if !ok {
+			i = len(groups)
+			index[m.ID] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], m)
+	}
+	return groups
+}
+
+// recount пересчитывает количество метрик по статусам и итоговый статус пакета.
+func recount(result *models.BatchResult) {
+	result.Applied, result.Merged, result.Rejected, result.Queued = 0, 0, 0, 0
+	for _, item := range result.Items {
+		switch item.Status {
+		case models.BatchApplied:
+			result.Applied++
+		case models.BatchMerged:
+			result.Merged++
+		case models.BatchRejected:
+			result.Rejected++
+		case models.BatchQueued:
+			result.Queued++
+		}
+	}
+
+	switch {
+	case result.Rejected == 0:
+		result.Status = "ok"
+	case result.Applied == 0 && result.Merged == 0 && result.Queued == 0:
+		result.Status = "rejected"
+	default:
+		result.Status = "partial"
+	}
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/validation"
+)
+
+func TestPrepareBatch(t *testing.T) {
+	value := 1.5
+	delta := int64(2)
+
+	metrics := models.ListMetrics{List: []models.Metrics{
+		{ID: "Alloc", MType: models.Gauge, Value: &value},
+		{ID: "PollCount", MType: models.Counter, Delta: &delta},
+		{ID: "PollCount", MType: models.Counter, Delta: &delta},
+		{ID: "Broken", MType: models.Gauge},
+		{ID: "Alloc", MType: models.Counter, Delta: &delta},
+		{ID: "Other", MType: "histogram"},
+	}}
+
+	want := []string{models.BatchApplied, models.BatchApplied, models.BatchMerged,
+		models.BatchRejected, models.BatchRejected, models.BatchRejected}
+
+	valid, result := PrepareBatch(metrics, models.BatchModeBestEffort)
+	if result.Status != "partial" || result.Applied != 2 || result.Merged != 1 || result.Rejected != 3 {
+		t.Errorf("unexpected best-effort result: %+v", result)
+	}
+	for i, item := range result.Items {
+		if item.Status != want[i] {
+			t.Errorf("item %d: got status %s, want %s", i, item.Status, want[i])
+		}
+		if item.Status == models.BatchRejected && item.Reason == "" {
+			t.Errorf("item %d: rejected without reason", i)
+		}
+	}
+	if len(valid.List) != 3 {
+		t.Errorf("expected 3 metrics to store, got %d", len(valid.List))
+	}
+
+	valid, result = PrepareBatch(metrics, models.BatchModeAtomic)
+	if result.Status != "rejected" || result.Rejected != len(metrics.List) || len(valid.List) != 0 {
+		t.Errorf("atomic batch with invalid metrics must be rejected: %+v", result)
+	}
+}
+
+func TestMemStorageInsertMetricsBatchInvalid(t *testing.T) {
+	value := 1.5
+	storage := NewMemStorage()
+
+	err := storage.InsertMetricsBatch(models.ListMetrics{List: []models.Metrics{
+		{ID: "Alloc", MType: models.Gauge, Value: &value},
+		{ID: "Broken", MType: models.Gauge},
+	}})
+	if !errors.Is(err, ErrInvalidMetric) {
+		t.Fatalf("expected ErrInvalidMetric, got %v", err)
+	}
+
+	if _, err := storage.GetGauge("Alloc"); err == nil {
+		t.Error("invalid batch must not be applied partially")
+	}
+}
+
+func TestMemStorageCounterOverflow(t *testing.T) {
+	storage := NewMemStorage()
+
+	if err := storage.SetCounter("PollCount", math.MaxInt64); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.SetCounter("PollCount", 1); !errors.Is(err, validation.ErrCounterOverflow) {
+		t.Errorf("expected ErrCounterOverflow, got %v", err)
+	}
+
+	value := 1.5
+	delta := int64(1)
+	err := storage.InsertMetricsBatch(models.ListMetrics{List: []models.Metrics{
+		{ID: "Alloc", MType: models.Gauge, Value: &value},
+		{ID: "PollCount", MType: models.Counter, Delta: &delta},
+	}})
+	if !errors.Is(err, validation.ErrCounterOverflow) {
+		t.Errorf("expected ErrCounterOverflow from batch, got %v", err)
+	}
+	if _, err := storage.GetGauge("Alloc"); err == nil {
+		t.Error("batch with overflowing counter must not be applied partially")
+	}
+
+	val, _ := storage.GetCounter("PollCount")
+	if val != math.MaxInt64 {
+		t.Errorf("counter changed after overflow: %d", val)
+	}
+}
+
+func TestDBStorageCounterOverflow(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare(`INSERT INTO metrics`).
+		ExpectExec().
+		WillReturnError(&pgconn.PgError{Code: "22003", Message: "bigint out of range"})
+
+	err = NewDBStorage(db).SetCounter("PollCount", 1)
+	if !errors.Is(err, validation.ErrCounterOverflow) {
+		t.Errorf("expected ErrCounterOverflow, got %v", err)
+	}
+}
+
+func TestCounterResetAndSet(t *testing.T) {
+	storage := NewMemStorage()
+
+	if err := storage.ResetCounter("PollCount"); !errors.Is(err, ErrMetricNotFound) {
+		t.Errorf("expected ErrMetricNotFound, got %v", err)
+	}
+
+	storage.SetCounter("PollCount", 10)
+	if err := storage.ResetCounter("PollCount"); err != nil {
+		t.Fatal(err)
+	}
+	if val, _ := storage.GetCounter("PollCount"); val != 0 {
+		t.Errorf("expected 0 after reset, got %d", val)
+	}
+
+	if err := storage.SetCounterValue("PollCount", 5); err != nil {
+		t.Fatal(err)
+	}
+	storage.SetCounter("PollCount", 1)
+	if val, _ := storage.GetCounter("PollCount"); val != 6 {
+		t.Errorf("expected 6 after set and increment, got %d", val)
+	}
+}
+
+func TestDBStorageResetCounterNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare(`UPDATE metrics SET delta = 0`).
+		ExpectExec().
+		WithArgs("PollCount").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := NewDBStorage(db).ResetCounter("PollCount"); !errors.Is(err, ErrMetricNotFound) {
+		t.Errorf("expected ErrMetricNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,433 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+// ErrBufferFull возвращается при записи в деградированном режиме, если буфер заполнен.
+var ErrBufferFull = errors.New("write buffer is full")
+
+// ErrQueued возвращается записью, помещенной в буфер деградированного режима: она
+// будет применена после восстановления хранилища.
+var ErrQueued = errors.New("write queued until storage recovers")
+
+// ErrUnavailable возвращается чтением в деградированном режиме.
+var ErrUnavailable = errors.New("storage is unavailable")
+
+// BufferOptions задает параметры BufferedStorage.
+type BufferOptions struct {
+	// Capacity — максимальное количество метрик в буфере записи.
+	Capacity int
+
+	// CheckInterval — интервал проверки доступности хранилища в деградированном режиме.
+	CheckInterval time.Duration
+
+	// IsUnavailable определяет, означает ли ошибка записи недоступность хранилища.
+	IsUnavailable func(error) bool
+
+	// IsSafeToRetry определяет, что запись гарантированно не была применена.
+	// Увеличение счетчика, завершившееся другой ошибкой недоступности, не
+	// помещается в буфер: повтор мог бы учесть его дважды.
+	IsSafeToRetry func(error) bool
+
+	// OnRecover вызывается после успешного Ping перед сбросом буфера,
+	// например для применения миграций. Ошибка оставляет хранилище в деградированном режиме.
+	OnRecover func(ctx context.Context) error
+}
+
+// Health описывает состояние хранилища для эндпоинта /health.
+type Health struct {
+	// Status — "ok" или "degraded".
+	Status string `json:"status"`
+
+	// Degraded равен true, пока записи принимаются в буфер.
+	Degraded bool `json:"degraded"`
+
+	// DegradedSince — время перехода в деградированный режим.
+	DegradedSince *time.Time `json:"degraded_since,omitempty"`
+
+	// BufferDepth — количество метрик, ожидающих записи.
+	BufferDepth int `json:"buffer_depth"`
+
+	// BufferCapacity — емкость буфера в метриках.
+	BufferCapacity int `json:"buffer_capacity"`
+
+	// Rejected — количество метрик, отклоненных из-за переполнения буфера.
+	Rejected uint64 `json:"rejected"`
+
+	// Retry содержит счетчики повторов, если хранилище обернуто в RetryStorage.
+	Retry *RetryStats `json:"retry,omitempty"`
+
+	// Cache содержит статистику кеша, если хранилище обернуто в CachedStorage.
+	Cache *CacheStats `json:"cache,omitempty"`
+}
+
+type opKind int
+
+const (
+	opGauge opKind = iota
+	opCounter
+	opCounterSet
+	opCounterReset
+	opBatch
+)
+
+// bufferedOp — отложенная операция записи.
+type bufferedOp struct {
+	kind    opKind
+	name    string
+	gauge   Gauge
+	counter Counter
+	batch   models.ListMetrics
+}
+
+func (op bufferedOp) size() int {
+	if op.kind == opBatch {
+		return len(op.batch.List)
+	}
+	return 1
+}
+
+// deferrable сообщает, можно ли отложить операцию до восстановления хранилища.
+// Административные установка и сброс счетчика не откладываются: вызывающая
+// сторона должна знать, что операция применена, прежде чем записать её в аудит.
+func (op bufferedOp) deferrable() bool {
+	return op.kind != opCounterSet && op.kind != opCounterReset
+}
+
+// idempotent сообщает, дает ли повторное применение операции тот же результат.
+func (op bufferedOp) idempotent() bool {
+	switch op.kind {
+	case opCounter:
+		return false
+	case opBatch:
+		for _, m := range op.batch.List {
+			if m.MType == models.Counter {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// BufferedStorage — декоратор Storage для работы при недоступной базе данных.
+//
+// Если запись завершилась ошибкой недоступности, хранилище переходит в деградированный
+// режим: последующие записи складываются в ограниченный буфер в порядке поступления
+// и завершаются ошибкой ErrQueued, а фоновая горутина периодически вызывает Ping.
+// Когда хранилище снова доступно, буфер сбрасывается в исходном порядке, и только
+// после его опустошения записи снова идут напрямую.
+//
+// Запись, на которой обнаружена недоступность, попадает в буфер, только если она
+// идемпотентна или гарантированно не была применена (IsSafeToRetry), иначе
+// возвращается исходная ошибка. Чтения в деградированном режиме сразу завершаются
+// ошибкой ErrUnavailable, не обращаясь к хранилищу. Так же завершаются установка
+// и сброс счетчика: они не откладываются.
+//
+// BufferedStorage должен оборачивать хранилище без повторов: повторы откладывали
+// бы переход в деградированный режим и сброс буфера.
+type BufferedStorage struct {
+	inner Storage
+	opts  BufferOptions
+
+	// gate удерживается на чтение на время прямой записи и на запись при переходе
+	// в деградированный режим, чтобы прямые записи не обгоняли уже отложенные.
+	gate sync.RWMutex
+
+	mu            sync.Mutex
+	degraded      bool
+	degradedSince time.Time
+	buf           []bufferedOp
+	depth         int
+	rejected      uint64
+
+	// flushMu гарантирует, что буфер сбрасывается одной горутиной.
+	flushMu sync.Mutex
+
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+// NewBufferedStorage создает декоратор с буфером записи над inner.
+// Фоновую проверку необходимо запустить методом Start и остановить методом Stop.
+func NewBufferedStorage(inner Storage, opts BufferOptions) *BufferedStorage {
+	if opts.Capacity <= 0 {
+		opts.Capacity = 10000
+	}
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = 5 * time.Second
+	}
+	if opts.IsUnavailable == nil {
+		opts.IsUnavailable = func(error) bool { return false }
+	}
+	if opts.IsSafeToRetry == nil {
+		opts.IsSafeToRetry = func(error) bool { return false }
+	}
+
+	return &BufferedStorage{
+		inner:  inner,
+		opts:   opts,
+		stopCh: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// MarkDegraded переводит хранилище в деградированный режим, например если база
+// данных недоступна уже при запуске сервера.
+func (b *BufferedStorage) MarkDegraded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.enterDegraded()
+}
+
+func (b *BufferedStorage) enterDegraded() {
+	if !b.degraded {
+		b.degraded = true
+		b.degradedSince = time.Now()
+		log.Printf("Storage is unavailable, buffering writes (capacity %d)", b.opts.Capacity)
+	}
+}
+
+// Health возвращает текущее состояние хранилища и буфера.
+func (b *BufferedStorage) Health() Health {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := Health{
+		Status:         "ok",
+		Degraded:       b.degraded,
+		BufferDepth:    b.depth,
+		BufferCapacity: b.opts.Capacity,
+		Rejected:       b.rejected,
+	}
+	if b.degraded {
+		since := b.degradedSince
+		h.Status = "degraded"
+		h.DegradedSince = &since
+	}
+	return h
+}
+
+// Start запускает фоновую проверку доступности хранилища и сброс буфера.
+func (b *BufferedStorage) Start() {
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(b.opts.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				b.tryRecover()
+			case <-b.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop останавливает фоновую проверку и делает последнюю попытку сбросить буфер.
+func (b *BufferedStorage) Stop() {
+	close(b.stopCh)
+	<-b.done
+	b.tryRecover()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.depth > 0 {
+		log.Printf("Storage is still unavailable, %d buffered metrics are lost", b.depth)
+	}
+}
+
+func (b *BufferedStorage) tryRecover() {
+	b.mu.Lock()
+	degraded := b.degraded
+	b.mu.Unlock()
+
+	if !degraded {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := b.inner.Ping(ctx); err != nil {
+		return
+	}
+
+	if b.opts.OnRecover != nil {
+		if err := b.opts.OnRecover(ctx); err != nil {
+			log.Printf("Storage recovery hook failed: %v", err)
+			return
+		}
+	}
+
+	b.flush()
+}
+
+// flush применяет отложенные операции по порядку. Если хранилище снова недоступно,
+// сброс прерывается до следующей проверки, а операция остается в буфере.
+func (b *BufferedStorage) flush() {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	flushed := 0
+	for {
+		b.mu.Lock()
+		if len(b.buf) == 0 {
+			b.degraded = false
+			b.buf = nil
+			b.mu.Unlock()
+			if flushed > 0 {
+				log.Printf("Storage recovered, flushed %d buffered metrics", flushed)
+			}
+			return
+		}
+		op := b.buf[0]
+		b.mu.Unlock()
+
+		if err := b.apply(op); err != nil {
+			if b.opts.IsUnavailable(err) {
+				log.Printf("Failed to flush buffered writes: %v", err)
+				return
+			}
+			// Ошибка не связана с доступностью (например, сброс отсутствующего
+			// счетчика): повтор не поможет, операция отбрасывается.
+			log.Printf("Dropping buffered write: %v", err)
+		}
+
+		b.mu.Lock()
+		b.buf = b.buf[1:]
+		b.depth -= op.size()
+		b.mu.Unlock()
+		flushed += op.size()
+	}
+}
+
+func (b *BufferedStorage) apply(op bufferedOp) error {
+	switch op.kind {
+	case opGauge:
+		return b.inner.SetGauge(op.name, op.gauge)
+	case opCounter:
+		return b.inner.SetCounter(op.name, op.counter)
+	case opCounterSet:
+		return b.inner.SetCounterValue(op.name, op.counter)
+	case opCounterReset:
+		return b.inner.ResetCounter(op.name)
+	default:
+		return b.inner.InsertMetricsBatch(op.batch)
+	}
+}
+
+// write выполняет операцию напрямую или помещает её в буфер в деградированном режиме.
+func (b *BufferedStorage) write(op bufferedOp) error {
+	b.gate.RLock()
+	b.mu.Lock()
+	if b.degraded {
+		err := ErrUnavailable
+		if op.deferrable() {
+			err = b.enqueue(op)
+		}
+		b.mu.Unlock()
+		b.gate.RUnlock()
+		return err
+	}
+	b.mu.Unlock()
+
+	err := b.apply(op)
+	b.gate.RUnlock()
+	if err == nil || !b.opts.IsUnavailable(err) {
+		return err
+	}
+
+	// Переход ждет завершения начатых прямых записей.
+	b.gate.Lock()
+	defer b.gate.Unlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.enterDegraded()
+	if !op.deferrable() || !op.idempotent() && !b.opts.IsSafeToRetry(err) {
+		return err
+	}
+	return b.enqueue(op)
+}
+
+// enqueue помещает операцию в буфер и возвращает ErrQueued или ErrBufferFull.
+func (b *BufferedStorage) enqueue(op bufferedOp) error {
+	if b.depth+op.size() > b.opts.Capacity {
+		b.rejected += uint64(op.size())
+		return ErrBufferFull
+	}
+
+	b.buf = append(b.buf, op)
+	b.depth += op.size()
+	return ErrQueued
+}
+
+// available возвращает ErrUnavailable в деградированном режиме.
+func (b *BufferedStorage) available() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.degraded {
+		return ErrUnavailable
+	}
+	return nil
+}
+
+func (b *BufferedStorage) SetGauge(name string, value Gauge) error {
+	return b.write(bufferedOp{kind: opGauge, name: name, gauge: value})
+}
+
+func (b *BufferedStorage) GetGauge(name string) (Gauge, error) {
+	if err := b.available(); err != nil {
+		return 0, err
+	}
+	return b.inner.GetGauge(name)
+}
+
+func (b *BufferedStorage) SetCounter(name string, value Counter) error {
+	return b.write(bufferedOp{kind: opCounter, name: name, counter: value})
+}
+
+func (b *BufferedStorage) GetCounter(name string) (Counter, error) {
+	if err := b.available(); err != nil {
+		return 0, err
+	}
+	return b.inner.GetCounter(name)
+}
+
+// SetCounterValue в деградированном режиме возвращает ErrUnavailable.
+func (b *BufferedStorage) SetCounterValue(name string, value Counter) error {
+	return b.write(bufferedOp{kind: opCounterSet, name: name, counter: value})
+}
+
+// ResetCounter в деградированном режиме возвращает ErrUnavailable.
+func (b *BufferedStorage) ResetCounter(name string) error {
+	return b.write(bufferedOp{kind: opCounterReset, name: name})
+}
+
+func (b *BufferedStorage) GetAll() (*models.ListMetrics, error) {
+	if err := b.available(); err != nil {
+		return nil, err
+	}
+	return b.inner.GetAll()
+}
+
+// InsertMetricsBatch копирует список метрик перед помещением в буфер,
+// так как вызывающая сторона может переиспользовать срез.
+func (b *BufferedStorage) InsertMetricsBatch(metrics models.ListMetrics) error {
+	batch := models.ListMetrics{List: append([]models.Metrics(nil), metrics.List...)}
+	return b.write(bufferedOp{kind: opBatch, batch: batch})
+}
+
+func (b *BufferedStorage) Ping(ctx context.Context) error {
+	return b.inner.Ping(ctx)
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+var (
+	errUnavailable = errors.New("connection refused")
+	errConnLost    = errors.New("connection reset by peer")
+)
+
+// flakyStorage имитирует базу данных, которую можно "выключить".
+type flakyStorage struct {
+	*MemStorage
+	mu  sync.Mutex
+	err error
+	ops []string
+}
+
+func (f *flakyStorage) setDown(down bool) {
+	f.fail(nil)
+	if down {
+		f.fail(errUnavailable)
+	}
+}
+
+// fail задает ошибку всех последующих операций, nil включает хранилище.
+func (f *flakyStorage) fail(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func (f *flakyStorage) check(op string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.ops = append(f.ops, op)
+	return nil
+}
+
+func (f *flakyStorage) SetGauge(name string, value Gauge) error {
+	if err := f.check("gauge:" + name); err != nil {
+		return err
+	}
+	return f.MemStorage.SetGauge(name, value)
+}
+
+func (f *flakyStorage) SetCounter(name string, value Counter) error {
+	if err := f.check("counter:" + name); err != nil {
+		return err
+	}
+	return f.MemStorage.SetCounter(name, value)
+}
+
+func (f *flakyStorage) SetCounterValue(name string, value Counter) error {
+	if err := f.check("counter-set:" + name); err != nil {
+		return err
+	}
+	return f.MemStorage.SetCounterValue(name, value)
+}
+
+func (f *flakyStorage) ResetCounter(name string) error {
+	if err := f.check("counter-reset:" + name); err != nil {
+		return err
+	}
+	return f.MemStorage.ResetCounter(name)
+}
+
+func (f *flakyStorage) InsertMetricsBatch(metrics models.ListMetrics) error {
+	if err := f.check("batch"); err != nil {
+		return err
+	}
+	return f.MemStorage.InsertMetricsBatch(metrics)
+}
+
+func (f *flakyStorage) Ping(ctx context.Context) error {
+	return f.check("ping")
+}
+
+func newTestBuffered(capacity int) (*BufferedStorage, *flakyStorage) {
+	inner := &flakyStorage{MemStorage: NewMemStorage()}
+	buffered := NewBufferedStorage(inner, BufferOptions{
+		Capacity:      capacity,
+		CheckInterval: time.Hour,
+		IsUnavailable: func(err error) bool {
+			return errors.Is(err, errUnavailable) || errors.Is(err, errConnLost)
+		},
+		IsSafeToRetry: func(err error) bool { return errors.Is(err, errUnavailable) },
+	})
+	return buffered, inner
+}
+
+func TestBufferedStorageBuffersAndFlushesInOrder(t *testing.T) {
+	buffered, inner := newTestBuffered(10)
+	inner.setDown(true)
+
+	delta := int64(5)
+	writes := []func() error{
+		func() error { return buffered.SetCounter("c", 1) },
+		func() error { return buffered.SetGauge("g", 1) },
+		func() error {
+			return buffered.InsertMetricsBatch(models.ListMetrics{List: []models.Metrics{{ID: "c", MType: "counter", Delta: &delta}}})
+		},
+		func() error { return buffered.SetGauge("g", 2) },
+	}
+	for i, write := range writes {
+		if err := write(); !errors.Is(err, ErrQueued) {
+			t.Fatalf("write %d must be buffered, got %v", i, err)
+		}
+	}
+
+	h := buffered.Health()
+	if !h.Degraded || h.BufferDepth != 4 || h.Status != "degraded" {
+		t.Fatalf("unexpected health while down: %+v", h)
+	}
+
+	buffered.tryRecover()
+	if !buffered.Health().Degraded {
+		t.Fatal("storage must stay degraded while ping fails")
+	}
+
+	inner.setDown(false)
+	buffered.tryRecover()
+
+	h = buffered.Health()
+	if h.Degraded || h.BufferDepth != 0 {
+		t.Fatalf("unexpected health after recovery: %+v", h)
+	}
+
+	want := []string{"ping", "counter:c", "gauge:g", "batch", "gauge:g"}
+	if len(inner.ops) != len(want) {
+		t.Fatalf("unexpected operations: %v", inner.ops)
+	}
+	for i := range want {
+		if inner.ops[i] != want[i] {
+			t.Fatalf("unexpected operations order: %v", inner.ops)
+		}
+	}
+
+	if c, _ := inner.GetCounter("c"); c != 6 {
+		t.Errorf("expected counter 6, got %d", c)
+	}
+	if g, _ := inner.GetGauge("g"); g != 2 {
+		t.Errorf("expected gauge 2, got %v", g)
+	}
+}
+
+func TestBufferedStorageRejectsWhenFull(t *testing.T) {
+	buffered, inner := newTestBuffered(2)
+	buffered.MarkDegraded()
+	inner.setDown(true)
+
+	buffered.SetGauge("a", 1)
+	buffered.SetGauge("b", 1)
+
+	if err := buffered.SetGauge("c", 1); !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("expected ErrBufferFull, got %v", err)
+	}
+
+	if h := buffered.Health(); h.Rejected != 1 || h.BufferDepth != 2 {
+		t.Errorf("unexpected health: %+v", h)
+	}
+}
+
+func TestBufferedStorageDoesNotQueueAdminWrites(t *testing.T) {
+	b, inner := newTestBuffered(10)
+	inner.MemStorage.SetCounterValue("PollCount", 3)
+	inner.setDown(true)
+
+	if err := b.ResetCounter("PollCount"); !errors.Is(err, errUnavailable) {
+		t.Fatalf("reset on failure: expected original error, got %v", err)
+	}
+	if err := b.SetCounterValue("PollCount", 1); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("set while degraded: expected ErrUnavailable, got %v", err)
+	}
+	if err := b.SetCounter("PollCount", 1); !errors.Is(err, ErrQueued) {
+		t.Fatalf("increment while degraded must be buffered, got %v", err)
+	}
+
+	inner.setDown(false)
+	b.tryRecover()
+
+	if h := b.Health(); h.Degraded || h.BufferDepth != 0 {
+		t.Fatalf("expected buffer to be flushed, got %+v", h)
+	}
+	if val, _ := inner.GetCounter("PollCount"); val != 4 {
+		t.Errorf("expected counter 4 after flush, got %d", val)
+	}
+}
+
+func TestBufferedStorageDoesNotQueueAmbiguousIncrements(t *testing.T) {
+	b, inner := newTestBuffered(10)
+	inner.fail(errConnLost)
+
+	if err := b.SetCounter("c", 1); !errors.Is(err, errConnLost) {
+		t.Fatalf("increment that may have been applied must fail, got %v", err)
+	}
+	if h := b.Health(); !h.Degraded || h.BufferDepth != 0 {
+		t.Fatalf("expected degraded storage with empty buffer, got %+v", h)
+	}
+
+	b2, inner2 := newTestBuffered(10)
+	inner2.fail(errConnLost)
+	if err := b2.SetGauge("g", 1); !errors.Is(err, ErrQueued) {
+		t.Fatalf("idempotent write must be buffered, got %v", err)
+	}
+}
+
+func TestBufferedStorageReadsFailFastWhenDegraded(t *testing.T) {
+	b, inner := newTestBuffered(10)
+	inner.MemStorage.SetGauge("g", 1)
+	b.MarkDegraded()
+
+	if _, err := b.GetGauge("g"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("GetGauge: expected ErrUnavailable, got %v", err)
+	}
+	if _, err := b.GetCounter("c"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("GetCounter: expected ErrUnavailable, got %v", err)
+	}
+	if _, err := b.GetAll(); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("GetAll: expected ErrUnavailable, got %v", err)
+	}
+
+	b.tryRecover()
+	if g, err := b.GetGauge("g"); err != nil || g != 1 {
+		t.Errorf("expected gauge 1 after recovery, got %v, %v", g, err)
+	}
+}
@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+// CacheOptions задает параметры CachedStorage.
+type CacheOptions struct {
+	// Size — максимальное количество метрик в кеше. При переполнении вытесняются
+	// давно не использованные записи.
+	Size int
+
+	// TTL — время жизни записи кеша.
+	TTL time.Duration
+}
+
+// CacheStats содержит счетчики попаданий в кеш.
+type CacheStats struct {
+	// Hits — количество чтений, обслуженных из кеша.
+	Hits uint64 `json:"hits"`
+
+	// Misses — количество чтений, выполненных в обернутом хранилище.
+	Misses uint64 `json:"misses"`
+
+	// Size — текущее количество метрик в кеше.
+	Size int `json:"size"`
+}
+
+type cacheKey struct {
+	mtype string
+	name  string
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	gauge   Gauge
+	counter Counter
+	expires time.Time
+}
+
+// CachedStorage — декоратор Storage, обслуживающий чтения из памяти.
+//
+// Запись выполняется сквозным образом в обернутое хранилище, после чего затронутые
+// метрики удаляются из кеша и заново читаются при следующем обращении: итоговое
+// значение счетчика после инкремента известно только базе данных. Список всех метрик
+// кешируется целиком и сбрасывается при любой записи. Чтение, начатое до записи
+// в ту же метрику, не кладет свой результат в кеш, поэтому устаревшее значение
+// не переживает запись; записи в другие метрики на него не влияют.
+type CachedStorage struct {
+	inner Storage
+	opts  CacheOptions
+
+	mu         sync.Mutex
+	items      map[cacheKey]*list.Element
+	lru        *list.List
+	all        *models.ListMetrics
+	allExpires time.Time
+
+	// seq увеличивается при каждой записи.
+	seq uint64
+	// written хранит seq последней записи в каждый ключ, пока есть незавершенные
+	// чтения из обернутого хранилища (их количество — в reading).
+	written map[cacheKey]uint64
+	reading int
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewCachedStorage создает кеширующий декоратор над inner.
+func NewCachedStorage(inner Storage, opts CacheOptions) *CachedStorage {
+	if opts.Size <= 0 {
+		opts.Size = 10000
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 10 * time.Second
+	}
+
+	return &CachedStorage{
+		inner:   inner,
+		opts:    opts,
+		items:   make(map[cacheKey]*list.Element),
+		lru:     list.New(),
+		written: make(map[cacheKey]uint64),
+	}
+}
+
+// Stats возвращает счетчики попаданий и текущий размер кеша.
+func (c *CachedStorage) Stats() CacheStats {
+	c.mu.Lock()
+	size := c.lru.Len()
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Size:   size,
+	}
+}
+
+// Health возвращает состояние обернутого хранилища, дополненное статистикой кеша.
+func (c *CachedStorage) Health() Health {
+	h := Health{Status: "ok"}
+	if hs, ok := c.inner.(interface{ Health() Health }); ok {
+		h = hs.Health()
+	}
+
+	stats := c.Stats()
+	h.Cache = &stats
+	return h
+}
+
+// get возвращает копию записи кеша. При промахе возвращается номер последней
+// записи, который нужно передать в put после чтения из обернутого хранилища.
+func (c *CachedStorage) get(key cacheKey) (cacheEntry, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if ok {
+		entry := el.Value.(*cacheEntry)
+		if time.Now().Before(entry.expires) {
+			c.lru.MoveToFront(el)
+			c.hits.Add(1)
+			return *entry, 0, true
+		}
+		c.lru.Remove(el)
+		delete(c.items, key)
+	}
+
+	c.misses.Add(1)
+	c.reading++
+	return cacheEntry{}, c.seq, false
+}
+
+// put завершает чтение, начатое промахом в get. Запись кладется в кеш, если
+// после промаха в этот ключ ничего не записывалось; nil только завершает чтение.
+func (c *CachedStorage) put(key cacheKey, entry *cacheEntry, since uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stale := c.written[key] > since
+	c.reading--
+	if c.reading == 0 {
+		clear(c.written)
+	}
+	if entry == nil || stale {
+		return
+	}
+
+	entry.expires = time.Now().Add(c.opts.TTL)
+
+	if el, ok := c.items[key]; ok {
+		*el.Value.(*cacheEntry) = *entry
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.lru.PushFront(entry)
+
+	for c.lru.Len() > c.opts.Size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// invalidate удаляет ключи из кеша и сбрасывает закешированный список всех метрик.
+func (c *CachedStorage) invalidate(keys ...cacheKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq++
+	for _, key := range keys {
+		if c.reading > 0 {
+			c.written[key] = c.seq
+		}
+		if el, ok := c.items[key]; ok {
+			c.lru.Remove(el)
+			delete(c.items, key)
+		}
+	}
+	c.all = nil
+}
+
+func (c *CachedStorage) SetGauge(name string, value Gauge) error {
+	err := c.inner.SetGauge(name, value)
+	c.invalidate(cacheKey{mtype: models.Gauge, name: name})
+	return err
+}
+
+func (c *CachedStorage) GetGauge(name string) (Gauge, error) {
+	key := cacheKey{mtype: models.Gauge, name: name}
+	entry, since, ok := c.get(key)
+	if ok {
+		return entry.gauge, nil
+	}
+
+	val, err := c.inner.GetGauge(name)
+	if err != nil {
+		c.put(key, nil, since)
+		return 0, err
+	}
+
+	c.put(key, &cacheEntry{key: key, gauge: val}, since)
+	return val, nil
+}
+
+func (c *CachedStorage) SetCounter(name string, value Counter) error {
+	err := c.inner.SetCounter(name, value)
+	c.invalidate(cacheKey{mtype: models.Counter, name: name})
+	return err
+}
+
+func (c *CachedStorage) GetCounter(name string) (Counter, error) {
+	key := cacheKey{mtype: models.Counter, name: name}
+	entry, since, ok := c.get(key)
+	if ok {
+		return entry.counter, nil
+	}
+
+	val, err := c.inner.GetCounter(name)
+	if err != nil {
+		c.put(key, nil, since)
+		return 0, err
+	}
+
+	c.put(key, &cacheEntry{key: key, counter: val}, since)
+	return val, nil
+}
+
+func (c *CachedStorage) ResetCounter(name string) error {
+	err := c.inner.ResetCounter(name)
+	c.invalidate(cacheKey{mtype: models.Counter, name: name})
+	return err
+}
+
+func (c *CachedStorage) SetCounterValue(name string, value Counter) error {
+	err := c.inner.SetCounterValue(name, value)
+	c.invalidate(cacheKey{mtype: models.Counter, name: name})
+	return err
+}
+
+// GetAll возвращает копию закешированного списка, если после последней записи
+// он не устарел.
+func (c *CachedStorage) GetAll() (*models.ListMetrics, error) {
+	c.mu.Lock()
+	if c.all != nil && time.Now().Before(c.allExpires) {
+		list := cloneList(c.all)
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return list, nil
+	}
+	since := c.seq
+	c.mu.Unlock()
+	c.misses.Add(1)
+
+	list, err := c.inner.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if since == c.seq {
+		c.all = cloneList(list)
+		c.allExpires = time.Now().Add(c.opts.TTL)
+	}
+	c.mu.Unlock()
+
+	return list, nil
+}
+
+// InsertMetricsBatch записывает пакет и удаляет из кеша все затронутые метрики,
+// в том числе счетчики, увеличенные пакетом.
+func (c *CachedStorage) InsertMetricsBatch(metrics models.ListMetrics) error {
+	err := c.inner.InsertMetricsBatch(metrics)
+
+	keys := make([]cacheKey, 0, len(metrics.List))
+	for _, m := range metrics.List {
+		keys = append(keys, cacheKey{mtype: m.MType, name: m.ID})
+	}
+	c.invalidate(keys...)
+
+	return err
+}
+
+func (c *CachedStorage) Ping(ctx context.Context) error {
+	return c.inner.Ping(ctx)
+}
+
+// cloneList копирует список вместе со значениями, чтобы изменения у вызывающего
+// не затрагивали кеш.
+func cloneList(src *models.ListMetrics) *models.ListMetrics {
+	dst := &models.ListMetrics{List: make([]models.Metrics, len(src.List))}
+	for i, m := range src.List {
+		if m.Value != nil {
+			v := *m.Value
+			m.Value = &v
+		}
+		if m.Delta != nil {
+			d := *m.Delta
+			m.Delta = &d
+		}
+		dst.List[i] = m
+	}
+	return dst
+}
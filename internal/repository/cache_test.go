@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+// countingStorage считает чтения, дошедшие до обернутого хранилища.
+type countingStorage struct {
+	*MemStorage
+	reads atomic.Int64
+}
+
+func (s *countingStorage) GetGauge(name string) (Gauge, error) {
+	s.reads.Add(1)
+	return s.MemStorage.GetGauge(name)
+}
+
+func (s *countingStorage) GetCounter(name string) (Counter, error) {
+	s.reads.Add(1)
+	return s.MemStorage.GetCounter(name)
+}
+
+func (s *countingStorage) GetAll() (*models.ListMetrics, error) {
+	s.reads.Add(1)
+	return s.MemStorage.GetAll()
+}
+
+func TestCachedStorageServesReadsFromCache(t *testing.T) {
+	inner := &countingStorage{MemStorage: NewMemStorage()}
+	cache := NewCachedStorage(inner, CacheOptions{Size: 10, TTL: time.Minute})
+
+	if err := cache.SetGauge("Alloc", 1.5); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		val, err := cache.GetGauge("Alloc")
+		if err != nil || val != 1.5 {
+			t.Fatalf("GetGauge = %v, %v; want 1.5", val, err)
+		}
+	}
+	if got := inner.reads.Load(); got != 1 {
+		t.Errorf("expected 1 read from inner storage, got %d", got)
+	}
+
+	if err := cache.SetGauge("Alloc", 2.5); err != nil {
+		t.Fatal(err)
+	}
+	if val, _ := cache.GetGauge("Alloc"); val != 2.5 {
+		t.Errorf("expected updated gauge 2.5, got %v", val)
+	}
+
+	if _, err := cache.GetGauge("Missing"); err == nil {
+		t.Error("expected error for missing metric")
+	}
+}
+
+func TestCachedStorageBatchInvalidatesCounters(t *testing.T) {
+	inner := &countingStorage{MemStorage: NewMemStorage()}
+	cache := NewCachedStorage(inner, CacheOptions{Size: 10, TTL: time.Minute})
+
+	if err := cache.SetCounter("PollCount", 5); err != nil {
+		t.Fatal(err)
+	}
+	if val, _ := cache.GetCounter("PollCount"); val != 5 {
+		t.Fatalf("expected 5, got %v", val)
+	}
+	all, err := cache.GetAll()
+	if err != nil || len(all.List) != 1 {
+		t.Fatalf("GetAll = %v, %v", all, err)
+	}
+
+	delta := int64(3)
+	batch := models.ListMetrics{List: []models.Metrics{{ID: "PollCount", MType: models.Counter, Delta: &delta}}}
+	if err := cache.InsertMetricsBatch(batch); err != nil {
+		t.Fatal(err)
+	}
+
+	if val, _ := cache.GetCounter("PollCount"); val != 8 {
+		t.Errorf("expected counter 8 after batch, got %v", val)
+	}
+	all, _ = cache.GetAll()
+	if len(all.List) != 1 || *all.List[0].Delta != 8 {
+		t.Errorf("expected GetAll to reflect batch, got %+v", all.List)
+	}
+}
+
+func TestCachedStorageEvictionAndTTL(t *testing.T) {
+	inner := &countingStorage{MemStorage: NewMemStorage()}
+	cache := NewCachedStorage(inner, CacheOptions{Size: 2, TTL: 50 * time.Millisecond})
+
+	for _, name := range []string{"a", "b", "c"} {
+		inner.MemStorage.SetGauge(name, 1)
+		cache.GetGauge(name)
+	}
+	if size := cache.Stats().Size; size != 2 {
+		t.Errorf("expected cache size 2, got %d", size)
+	}
+
+	inner.reads.Store(0)
+	cache.GetGauge("a")
+	if inner.reads.Load() != 1 {
+		t.Error("expected evicted entry to be read from inner storage")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	inner.reads.Store(0)
+	cache.GetGauge("a")
+	if inner.reads.Load() != 1 {
+		t.Error("expected expired entry to be read from inner storage")
+	}
+}
+
+func TestCachedStorageInvalidatesOnlyWrittenKeys(t *testing.T) {
+	cache := NewCachedStorage(NewMemStorage(), CacheOptions{Size: 10, TTL: time.Minute})
+	a := cacheKey{mtype: models.Gauge, name: "a"}
+	b := cacheKey{mtype: models.Gauge, name: "b"}
+
+	// Чтение a, во время которого записывается другая метрика, попадает в кеш.
+	_, since, _ := cache.get(a)
+	cache.SetGauge("b", 1)
+	cache.put(a, &cacheEntry{key: a, gauge: 1}, since)
+	if _, _, ok := cache.get(a); !ok {
+		t.Error("write to another key must not discard a concurrent read")
+	}
+
+	// Чтение b, во время которого записывается сама b, в кеш не попадает.
+	_, since, _ = cache.get(b)
+	cache.SetGauge("b", 2)
+	cache.put(b, &cacheEntry{key: b, gauge: 1}, since)
+	if _, _, ok := cache.get(b); ok {
+		t.Error("read that raced with a write to the same key must not be cached")
+	}
+}
+
+func TestCachedStorageGetAllReturnsCopy(t *testing.T) {
+	inner := &countingStorage{MemStorage: NewMemStorage()}
+	cache := NewCachedStorage(inner, CacheOptions{Size: 10, TTL: time.Minute})
+	inner.MemStorage.SetGauge("Alloc", 1)
+
+	for i := 0; i < 2; i++ {
+		all, err := cache.GetAll()
+		if err != nil || len(all.List) != 1 {
+			t.Fatalf("GetAll = %v, %v", all, err)
+		}
+		if *all.List[0].Value != 1 {
+			t.Fatalf("caller modification leaked into cache: %v", *all.List[0].Value)
+		}
+		*all.List[0].Value = 42
+		all.List[0].ID = "changed"
+	}
+	if got := inner.reads.Load(); got != 1 {
+		t.Errorf("expected 1 read from inner storage, got %d", got)
+	}
+}
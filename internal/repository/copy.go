@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// errCopyUnsupported возвращается, если драйвер соединения не pgx и COPY недоступен.
+// В этом случае пакет записывается обычными порциями INSERT.
+var errCopyUnsupported = errors.New("copy is not supported by driver")
+
+const (
+	queryCreateStage = `
+		CREATE TEMP TABLE metrics_stage (
+			name TEXT,
+			type TEXT,
+			value DOUBLE PRECISION,
+			delta BIGINT
+		) ON COMMIT DROP
+	`
+	queryMergeStage = `
+		INSERT INTO metrics (name, delta, type, value)
+		SELECT name, delta, type, value FROM metrics_stage
+		ORDER BY name
+	` + batchConflictClause
+)
+
+// copyBatch загружает пакет через COPY во временную таблицу и сливает её с metrics
+// одним INSERT ... SELECT. Временная таблица удаляется при фиксации транзакции.
+func (d *DBStorage) copyBatch(ctx context.Context, rows []batchRow) error {
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		sc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errCopyUnsupported
+		}
+
+		tx, err := sc.Conn().Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("begin transaction: %w", err)
+		}
+		defer tx.Rollback(ctx)
+
+		if _, err := tx.Exec(ctx, queryCreateStage); err != nil {
+			return fmt.Errorf("create stage table: %w", err)
+		}
+
+		source := pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
+			return []any{rows[i].name, rows[i].mtype, rows[i].value, rows[i].delta}, nil
+		})
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"metrics_stage"}, []string{"name", "type", "value", "delta"}, source); err != nil {
+			return fmt.Errorf("copy into stage table: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, queryMergeStage); err != nil {
+			return fmt.Errorf("merge stage table: %w", err)
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("commit transaction: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,73 @@
+//go:build integration
+
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/levinOo/go-metrics-project/internal/config/db"
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+// TestInsertMetricsBatchCopy проверяет запись через COPY на реальной базе данных:
+//
+//	TEST_DATABASE_DSN=postgres://... go test -tags integration ./internal/repository/
+func TestInsertMetricsBatchCopy(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	if err := db.RunMigrations(dsn); err != nil {
+		t.Fatalf("RunMigrations error: %v", err)
+	}
+
+	conn, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	const prefix = "copy_test_"
+	cleanup := func() {
+		if _, err := conn.Exec(`DELETE FROM metrics WHERE name LIKE $1`, prefix+"%"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cleanup()
+	defer cleanup()
+
+	storage := NewDBStorageWithOptions(conn, DBOptions{ChunkSize: 2, CopyThreshold: 3})
+	defer storage.Close()
+
+	if err := storage.SetCounter(prefix+"c0", 10); err != nil {
+		t.Fatalf("SetCounter error: %v", err)
+	}
+
+	delta := int64(1)
+	value := 2.5
+	metrics := models.ListMetrics{List: []models.Metrics{
+		{ID: prefix + "g", MType: models.Gauge, Value: &value},
+	}}
+	for i := 0; i < 3; i++ {
+		metrics.List = append(metrics.List, models.Metrics{ID: fmt.Sprintf("%sc%d", prefix, i), MType: models.Counter, Delta: &delta})
+	}
+
+	if err := storage.InsertMetricsBatch(metrics); err != nil {
+		t.Fatalf("InsertMetricsBatch error: %v", err)
+	}
+
+	if got, err := storage.GetCounter(prefix + "c0"); err != nil || got != 11 {
+		t.Errorf("counter must be merged with stored value: got %d, %v", got, err)
+	}
+	if got, err := storage.GetCounter(prefix + "c2"); err != nil || got != 1 {
+		t.Errorf("new counter: got %d, %v", got, err)
+	}
+	if got, err := storage.GetGauge(prefix + "g"); err != nil || got != 2.5 {
+		t.Errorf("gauge: got %v, %v", got, err)
+	}
+}
@@ -8,10 +8,13 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"sync"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/levinOo/go-metrics-project/internal/models"
+	"github.com/levinOo/go-metrics-project/internal/validation"
 )
 
 type (
@@ -19,11 +22,22 @@ type (
 	Counter int64
 )
 
+// ErrMetricNotFound возвращается при чтении или сбросе отсутствующей метрики.
+var ErrMetricNotFound = errors.New("metric not found")
+
 type Storage interface {
 	SetGauge(name string, value Gauge) error
 	GetGauge(name string) (Gauge, error)
 	SetCounter(name string, value Counter) error
 	GetCounter(name string) (Counter, error)
+
+	// ResetCounter обнуляет существующий счетчик. Для отсутствующего счетчика
+	// возвращается ErrMetricNotFound.
+	ResetCounter(name string) error
+
+	// SetCounterValue устанавливает абсолютное значение счетчика, создавая его при необходимости.
+	SetCounterValue(name string, value Counter) error
+
 	GetAll() (*models.ListMetrics, error)
 	Ping(ctx context.Context) error
 	InsertMetricsBatch(models.ListMetrics) error
@@ -31,54 +45,264 @@ type Storage interface {
 
 // --------------------- DBStorage ---------------------
 
+const (
+	queryUpsertGauge = `
+		INSERT INTO metrics (name, value, type) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value
+	`
+	queryUpsertCounter = `
+		INSERT INTO metrics (name, delta, type) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET delta = metrics.delta + EXCLUDED.delta
+	`
+	querySetCounter = `
+		INSERT INTO metrics (name, delta, type) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET type = EXCLUDED.type, delta = EXCLUDED.delta
+	`
+	queryResetCounter  = `UPDATE metrics SET delta = 0 WHERE name=$1 AND type='counter'`
+	querySelectGauge   = `SELECT value FROM metrics WHERE name=$1`
+	querySelectCounter = `SELECT delta FROM metrics WHERE name=$1`
+
+	// batchConflictClause объединяет новые значения с существующими:
+	// счетчики суммируются, gauge перезаписываются.
+	batchConflictClause = `
+		ON CONFLICT (name) DO UPDATE
+		SET type = EXCLUDED.type,
+			delta = CASE 
+				WHEN EXCLUDED.type = 'counter' THEN metrics.delta + EXCLUDED.delta 
+				ELSE EXCLUDED.delta 
+			END,
+			value = CASE 
+				WHEN EXCLUDED.type = 'gauge' THEN EXCLUDED.value 
+				ELSE metrics.value 
+			END
+	`
+
+	// batchRowParams — количество параметров на одну строку пакетного INSERT.
+	batchRowParams = 4
+
+	// maxBatchChunkSize ограничивает размер порции лимитом PostgreSQL в 65535 параметров.
+	maxBatchChunkSize = 65535 / batchRowParams
+)
+
+// DBOptions задает параметры пакетной записи в DBStorage.
+type DBOptions struct {
+	// ChunkSize — максимальное количество строк в одном INSERT.
+	// Все порции одного пакета пишутся в одной транзакции.
+	ChunkSize int
+
+	// CopyThreshold — количество метрик, начиная с которого пакет загружается
+	// через COPY во временную таблицу с последующим слиянием. 0 отключает COPY.
+	CopyThreshold int
+}
+
+// DefaultDBOptions возвращает параметры пакетной записи по умолчанию.
+func DefaultDBOptions() DBOptions {
+	return DBOptions{
+		ChunkSize:     1000,
+		CopyThreshold: 5000,
+	}
+}
+
 // generate:reset
 type DBStorage struct {
-	db *sql.DB
+	db     *sql.DB
+	opts   DBOptions
+	stmtMu *sync.Mutex
+	stmts  map[string]*sql.Stmt
+
+	// chunkQuery — текст INSERT для полной порции из ChunkSize строк. Он одинаков
+	// для всех полных порций, поэтому драйвер переиспользует подготовленный запрос.
+	chunkQuery string
 }
 
 func NewDBStorage(db *sql.DB) *DBStorage {
-	return &DBStorage{db: db}
+	return NewDBStorageWithOptions(db, DefaultDBOptions())
+}
+
+// NewDBStorageWithOptions создает DBStorage с заданными параметрами пакетной записи.
+// Некорректный размер порции заменяется значением по умолчанию или лимитом PostgreSQL.
+func NewDBStorageWithOptions(db *sql.DB, opts DBOptions) *DBStorage {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultDBOptions().ChunkSize
+	}
+	if opts.ChunkSize > maxBatchChunkSize {
+		opts.ChunkSize = maxBatchChunkSize
+	}
+
+	return &DBStorage{
+		db:         db,
+		opts:       opts,
+		stmtMu:     &sync.Mutex{},
+		stmts:      make(map[string]*sql.Stmt),
+		chunkQuery: batchInsertQuery(opts.ChunkSize),
+	}
+}
+
+// Close закрывает подготовленные запросы. Соединение с базой данных не закрывается:
+// им владеет вызывающая сторона.
+func (d *DBStorage) Close() error {
+	d.stmtMu.Lock()
+	defer d.stmtMu.Unlock()
+
+	var errs []error
+	for query, st := range d.stmts {
+		if err := st.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(d.stmts, query)
+	}
+	return errors.Join(errs...)
+}
+
+// stmt возвращает подготовленный запрос, подготавливая его при первом обращении.
+// database/sql сам переподготавливает запрос на других соединениях пула.
+func (d *DBStorage) stmt(query string) (*sql.Stmt, error) {
+	d.stmtMu.Lock()
+	defer d.stmtMu.Unlock()
+
+	if st, ok := d.stmts[query]; ok {
+		return st, nil
+	}
+
+	st, err := d.db.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("prepare statement: %w", err)
+	}
+	d.stmts[query] = st
+	return st, nil
 }
 
 func (d *DBStorage) SetGauge(name string, value Gauge) error {
-	_, err := d.db.Exec(`
-		INSERT INTO metrics (name, value, type) VALUES ($1, $2, $3)
-		ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value
-	`, name, float64(value), "gauge")
+	st, err := d.stmt(queryUpsertGauge)
+	if err != nil {
+		return err
+	}
+	_, err = st.Exec(name, float64(value), "gauge")
 	return err
 }
 
 func (d *DBStorage) GetGauge(name string) (Gauge, error) {
+	st, err := d.stmt(querySelectGauge)
+	if err != nil {
+		return 0, err
+	}
+
 	var val float64
-	err := d.db.QueryRow(`SELECT value FROM metrics WHERE name=$1`, name).Scan(&val)
+	err = st.QueryRow(name).Scan(&val)
 	if err == sql.ErrNoRows {
-		return 0, errors.New("metric not found")
+		return 0, ErrMetricNotFound
 	}
 	return Gauge(val), err
 }
 
 func (d *DBStorage) SetCounter(name string, value Counter) error {
-	_, err := d.db.Exec(`
-		INSERT INTO metrics (name, delta, type) VALUES ($1, $2, $3)
-		ON CONFLICT (name) DO UPDATE SET delta = metrics.delta + EXCLUDED.delta
-	`, name, int64(value), "counter")
-	return err
+	st, err := d.stmt(queryUpsertCounter)
+	if err != nil {
+		return err
+	}
+	_, err = st.Exec(name, int64(value), "counter")
+	return overflowError(err)
 }
 
 func (d *DBStorage) GetCounter(name string) (Counter, error) {
+	st, err := d.stmt(querySelectCounter)
+	if err != nil {
+		return 0, err
+	}
+
 	var val int64
-	err := d.db.QueryRow(`SELECT delta FROM metrics WHERE name=$1`, name).Scan(&val)
+	err = st.QueryRow(name).Scan(&val)
 	if err == sql.ErrNoRows {
-		return 0, errors.New("metric not found")
+		return 0, ErrMetricNotFound
 	}
 	return Counter(val), err
 }
 
+func (d *DBStorage) ResetCounter(name string) error {
+	st, err := d.stmt(queryResetCounter)
+	if err != nil {
+		return err
+	}
+
+	res, err := st.Exec(name)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrMetricNotFound
+	}
+	return nil
+}
+
+func (d *DBStorage) SetCounterValue(name string, value Counter) error {
+	st, err := d.stmt(querySetCounter)
+	if err != nil {
+		return err
+	}
+	_, err = st.Exec(name, int64(value), "counter")
+	return err
+}
+
+// batchRow — агрегированная строка пакета: одна на каждое имя метрики.
+type batchRow struct {
+	name  string
+	mtype string
+	value interface{}
+	delta interface{}
+}
+
+// InsertMetricsBatch записывает пакет метрик в одной транзакции. Метрики с одинаковым
+// именем предварительно объединяются. Небольшие пакеты пишутся порциями INSERT по
+// ChunkSize строк, пакеты от CopyThreshold метрик — через COPY во временную таблицу.
+// Пакет с некорректной метрикой отклоняется целиком с ошибкой ErrInvalidMetric.
 func (d *DBStorage) InsertMetricsBatch(metrics models.ListMetrics) error {
-	if len(metrics.List) == 0 {
+	if err := checkBatch(metrics); err != nil {
+		return err
+	}
+
+	rows := aggregateBatch(metrics)
+	if len(rows) == 0 {
 		return nil
 	}
 
+	ctx := context.Background()
+
+	if d.opts.CopyThreshold > 0 && len(rows) >= d.opts.CopyThreshold {
+		err := d.copyBatch(ctx, rows)
+		if !errors.Is(err, errCopyUnsupported) {
+			if err != nil {
+				log.Printf("Batch copy error: %v", err)
+			}
+			return overflowError(err)
+		}
+	}
+
+	if err := d.insertBatchChunks(ctx, rows); err != nil {
+		log.Printf("Batch insert error: %v", err)
+		return overflowError(err)
+	}
+
+	return nil
+}
+
+// overflowError заменяет ошибку PostgreSQL 22003 (numeric_value_out_of_range),
+// возникающую при переполнении BIGINT, на validation.ErrCounterOverflow.
+func overflowError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "22003" {
+		return fmt.Errorf("%w: %s", validation.ErrCounterOverflow, pgErr.Message)
+	}
+	return err
+}
+
+// aggregateBatch объединяет метрики пакета по имени и сортирует их, чтобы конкурирующие
+// транзакции блокировали строки в одном порядке и не вставали в deadlock.
+func aggregateBatch(metrics models.ListMetrics) []batchRow {
 	type batchItem struct {
 		MType string
 		Value *float64
@@ -112,54 +336,76 @@ func (d *DBStorage) InsertMetricsBatch(metrics models.ListMetrics) error {
 		tmp[metric.ID] = b
 	}
 
-	if len(tmp) == 0 {
-		return nil
-	}
-
-	valueStrings := make([]string, 0, len(tmp))
-	valueArgs := make([]interface{}, 0, len(tmp)*4)
-	argIndex := 1
-
+	rows := make([]batchRow, 0, len(tmp))
 	for id, b := range tmp {
-		var val interface{} = nil
-		var delta interface{} = nil
+		row := batchRow{name: id, mtype: b.MType}
 
 		switch b.MType {
 		case "gauge":
-			val = *b.Value
+			row.value = *b.Value
 		case "counter":
-			delta = *b.Delta
+			row.delta = *b.Delta
+		default:
+			continue
 		}
 
-		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d)", argIndex, argIndex+1, argIndex+2, argIndex+3))
-		valueArgs = append(valueArgs, id, delta, b.MType, val)
-		argIndex += 4
+		rows = append(rows, row)
 	}
 
-	query := fmt.Sprintf(`
-		INSERT INTO metrics (name, delta, type, value)
-		VALUES %s
-		ON CONFLICT (name) DO UPDATE
-		SET type = EXCLUDED.type,
-			delta = CASE 
-				WHEN EXCLUDED.type = 'counter' THEN metrics.delta + EXCLUDED.delta 
-				ELSE EXCLUDED.delta 
-			END,
-			value = CASE 
-				WHEN EXCLUDED.type = 'gauge' THEN EXCLUDED.value 
-				ELSE metrics.value 
-			END
-	`, strings.Join(valueStrings, ","))
+	sort.Slice(rows, func(i, j int) bool { return rows[i].name < rows[j].name })
+	return rows
+}
 
-	_, err := d.db.Exec(query, valueArgs...)
+func (d *DBStorage) insertBatchChunks(ctx context.Context, rows []batchRow) error {
+	tx, err := d.db.BeginTx(ctx, nil)
 	if err != nil {
-		log.Printf("Batch insert error: %v", err)
-		return err
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	args := make([]interface{}, 0, min(len(rows), d.opts.ChunkSize)*batchRowParams)
+
+	for start := 0; start < len(rows); start += d.opts.ChunkSize {
+		end := min(start+d.opts.ChunkSize, len(rows))
+
+		args = args[:0]
+		for _, row := range rows[start:end] {
+			args = append(args, row.name, row.delta, row.mtype, row.value)
+		}
+
+		query := d.chunkQuery
+		if end-start != d.opts.ChunkSize {
+			query = batchInsertQuery(end - start)
+		}
+
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
 	}
 
 	return nil
 }
 
+// batchInsertQuery строит пакетный INSERT на n строк.
+func batchInsertQuery(n int) string {
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO metrics (name, delta, type, value) VALUES ")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		p := i*batchRowParams + 1
+		fmt.Fprintf(&sb, "($%d, $%d, $%d, $%d)", p, p+1, p+2, p+3)
+	}
+	sb.WriteString(batchConflictClause)
+
+	return sb.String()
+}
+
 func (d *DBStorage) GetAll() (*models.ListMetrics, error) {
 	var list models.ListMetrics
 
@@ -208,98 +454,195 @@ func (d *DBStorage) Ping(ctx context.Context) error {
 
 // --------------------- MemStorage ---------------------
 
+// memShardCount задает количество сегментов MemStorage. Должно быть степенью двойки.
+const memShardCount = 32
+
+type memShard struct {
+	mu       sync.RWMutex
+	gauges   map[string]Gauge
+	counters map[string]Counter
+}
+
+// MemStorage хранит метрики в памяти. Пространство имен разбито на сегменты,
+// каждый со своим RWMutex, поэтому запись и чтение разных метрик не конкурируют
+// за одну блокировку, а чтения одного сегмента выполняются параллельно.
+
 // generate:reset
 type MemStorage struct {
-	mu       *sync.Mutex
-	Gauges   map[string]Gauge
-	Counters map[string]Counter
+	shards []*memShard
 }
 
 func NewMemStorage() *MemStorage {
-	return &MemStorage{
-		mu:       &sync.Mutex{},
-		Gauges:   make(map[string]Gauge),
-		Counters: make(map[string]Counter),
+	shards := make([]*memShard, memShardCount)
+	for i := range shards {
+		shards[i] = &memShard{
+			gauges:   make(map[string]Gauge),
+			counters: make(map[string]Counter),
+		}
+	}
+	return &MemStorage{shards: shards}
+}
+
+// shard возвращает сегмент для метрики.
+func (m *MemStorage) shard(name string) *memShard {
+	return m.shards[shardIndex(name)]
+}
+
+// shardIndex возвращает номер сегмента по хешу FNV-1a от имени метрики.
+func shardIndex(name string) int {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+
+	h := uint32(offset32)
+	for i := 0; i < len(name); i++ {
+		h ^= uint32(name[i])
+		h *= prime32
 	}
+	return int(h & (memShardCount - 1))
 }
 
 func (m *MemStorage) SetGauge(name string, value Gauge) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.Gauges[name] = value
+	s := m.shard(name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gauges[name] = value
 	return nil
 }
 
 func (m *MemStorage) GetGauge(name string) (Gauge, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	val, ok := m.Gauges[name]
+	s := m.shard(name)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	val, ok := s.gauges[name]
 	if !ok {
-		return 0, errors.New("metric not found")
+		return 0, ErrMetricNotFound
 	}
 	return val, nil
 }
 
+// SetCounter увеличивает счетчик. При переполнении int64 значение не меняется
+// и возвращается validation.ErrCounterOverflow.
 func (m *MemStorage) SetCounter(name string, value Counter) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.Counters[name] += value
+	s := m.shard(name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sum, err := validation.AddCounter(int64(s.counters[name]), int64(value))
+	if err != nil {
+		return fmt.Errorf("counter %s: %w", name, err)
+	}
+	s.counters[name] = Counter(sum)
 	return nil
 }
 
 func (m *MemStorage) GetCounter(name string) (Counter, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	val, ok := m.Counters[name]
+	s := m.shard(name)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	val, ok := s.counters[name]
 	if !ok {
-		return 0, errors.New("metric not found")
+		return 0, ErrMetricNotFound
 	}
 	return val, nil
 }
 
+func (m *MemStorage) ResetCounter(name string) error {
+	s := m.shard(name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.counters[name]; !ok {
+		return ErrMetricNotFound
+	}
+	s.counters[name] = 0
+	return nil
+}
+
+func (m *MemStorage) SetCounterValue(name string, value Counter) error {
+	s := m.shard(name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counters[name] = value
+	return nil
+}
+
+// InsertMetricsBatch записывает пакет метрик. Пакет с некорректной метрикой
+// или переполняющий счетчик отклоняется целиком. На время записи блокируются
+// все затронутые сегменты в порядке возрастания номера, чтобы проверка переполнения
+// и запись выполнялись атомарно.
 func (m *MemStorage) InsertMetricsBatch(metrics models.ListMetrics) error {
+	if err := checkBatch(metrics); err != nil {
+		return err
+	}
+
+	var touched [memShardCount]bool
 	for _, metric := range metrics.List {
-		switch metric.MType {
-		case "gauge":
-			err := m.SetGauge(metric.ID, Gauge(*metric.Value))
-			if err != nil {
-				log.Printf("Failed to set gauge %s: %v", metric.ID, err)
-			}
-		case "counter":
-			err := m.SetCounter(metric.ID, Counter(*metric.Delta))
-			if err != nil {
-				log.Printf("Failed to set counter %s: %v", metric.ID, err)
-			}
-		default:
+		touched[shardIndex(metric.ID)] = true
+	}
+	for i, ok := range touched {
+		if ok {
+			m.shards[i].mu.Lock()
+			defer m.shards[i].mu.Unlock()
+		}
+	}
+
+	counters := make(map[string]int64)
+	for _, metric := range metrics.List {
+		if metric.MType != models.Counter {
 			continue
 		}
+
+		current, ok := counters[metric.ID]
+		if !ok {
+			current = int64(m.shard(metric.ID).counters[metric.ID])
+		}
+
+		sum, err := validation.AddCounter(current, *metric.Delta)
+		if err != nil {
+			return fmt.Errorf("counter %s: %w", metric.ID, err)
+		}
+		counters[metric.ID] = sum
+	}
+
+	for _, metric := range metrics.List {
+		if metric.MType == models.Gauge {
+			m.shard(metric.ID).gauges[metric.ID] = Gauge(*metric.Value)
+		}
+	}
+	for name, value := range counters {
+		m.shard(name).counters[name] = Counter(value)
 	}
 
 	return nil
 }
 
+// GetAll возвращает копию всех метрик. Сегменты блокируются на чтение по очереди,
+// поэтому запись в другие сегменты во время обхода не блокируется.
 func (m *MemStorage) GetAll() (*models.ListMetrics, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	var list models.ListMetrics
 
-	for name, val := range m.Counters {
-		v := int64(val)
-		list.List = append(list.List, models.Metrics{
-			ID:    name,
-			MType: "counter",
-			Delta: &v,
-		})
-	}
+	for _, s := range m.shards {
+		s.mu.RLock()
+		for name, val := range s.counters {
+			v := int64(val)
+			list.List = append(list.List, models.Metrics{
+				ID:    name,
+				MType: "counter",
+				Delta: &v,
+			})
+		}
 
-	for name, val := range m.Gauges {
-		v := float64(val)
-		list.List = append(list.List, models.Metrics{
-			ID:    name,
-			MType: "gauge",
-			Value: &v,
-		})
+		for name, val := range s.gauges {
+			v := float64(val)
+			list.List = append(list.List, models.Metrics{
+				ID:    name,
+				MType: "gauge",
+				Value: &v,
+			})
+		}
+		s.mu.RUnlock()
 	}
 
 	return &list, nil
@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -27,8 +30,10 @@ func BenchmarkInsertBatch(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
+		mock.ExpectBegin()
 		mock.ExpectExec(`INSERT INTO metrics`).
 			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
 
 		err := storage.InsertMetricsBatch(metrics)
 		if err != nil {
@@ -36,3 +41,319 @@ func BenchmarkInsertBatch(b *testing.B) {
 		}
 	}
 }
+
+func TestInsertMetricsBatchChunks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := NewDBStorageWithOptions(db, DBOptions{ChunkSize: 2})
+
+	delta := int64(1)
+	var metrics models.ListMetrics
+	for i := 0; i < 5; i++ {
+		metrics.List = append(metrics.List, models.Metrics{ID: fmt.Sprintf("c%d", i), MType: "counter", Delta: &delta})
+	}
+	// Повторная метрика объединяется с первой и не занимает отдельную строку.
+	metrics.List = append(metrics.List, models.Metrics{ID: "c0", MType: "counter", Delta: &delta})
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO metrics`).
+		WithArgs("c0", int64(2), "counter", nil, "c1", int64(1), "counter", nil).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO metrics`).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO metrics`).
+		WithArgs("c4", int64(1), "counter", nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := storage.InsertMetricsBatch(metrics); err != nil {
+		t.Fatalf("InsertMetricsBatch error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInsertMetricsBatchRollback(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := NewDBStorageWithOptions(db, DBOptions{ChunkSize: 1})
+
+	val := 1.0
+	metrics := models.ListMetrics{List: []models.Metrics{
+		{ID: "g1", MType: "gauge", Value: &val},
+		{ID: "g2", MType: "gauge", Value: &val},
+	}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO metrics`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO metrics`).WillReturnError(errors.New("boom"))
+	mock.ExpectRollback()
+
+	if err := storage.InsertMetricsBatch(metrics); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDBStoragePreparedStatements(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := NewDBStorage(db)
+
+	prep := mock.ExpectPrepare(`INSERT INTO metrics \(name, value, type\)`)
+	prep.ExpectExec().WithArgs("g", 1.5, "gauge").WillReturnResult(sqlmock.NewResult(0, 1))
+	prep.ExpectExec().WithArgs("g", 2.5, "gauge").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := storage.SetGauge("g", 1.5); err != nil {
+		t.Fatalf("SetGauge error: %v", err)
+	}
+	if err := storage.SetGauge("g", 2.5); err != nil {
+		t.Fatalf("SetGauge error: %v", err)
+	}
+
+	prep.WillBeClosed()
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInsertMetricsBatchCopyFallback(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := NewDBStorageWithOptions(db, DBOptions{ChunkSize: 2, CopyThreshold: 3})
+
+	delta := int64(1)
+	var metrics models.ListMetrics
+	for i := 0; i < 3; i++ {
+		metrics.List = append(metrics.List, models.Metrics{ID: fmt.Sprintf("c%d", i), MType: "counter", Delta: &delta})
+	}
+
+	// Соединение sqlmock не является соединением pgx, поэтому COPY недоступен
+	// и пакет записывается порциями INSERT.
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO metrics`).
+		WithArgs("c0", int64(1), "counter", nil, "c1", int64(1), "counter", nil).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO metrics`).
+		WithArgs("c2", int64(1), "counter", nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := storage.InsertMetricsBatch(metrics); err != nil {
+		t.Fatalf("InsertMetricsBatch error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// mutexMemStorage повторяет прежнюю реализацию MemStorage с одной блокировкой
+// на оба словаря и используется как база для сравнения в бенчмарках.
+type mutexMemStorage struct {
+	mu       *sync.Mutex
+	Gauges   map[string]Gauge
+	Counters map[string]Counter
+}
+
+func newMutexMemStorage() *mutexMemStorage {
+	return &mutexMemStorage{
+		mu:       &sync.Mutex{},
+		Gauges:   make(map[string]Gauge),
+		Counters: make(map[string]Counter),
+	}
+}
+
+func (m *mutexMemStorage) SetGauge(name string, value Gauge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Gauges[name] = value
+	return nil
+}
+
+func (m *mutexMemStorage) GetGauge(name string) (Gauge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	val, ok := m.Gauges[name]
+	if !ok {
+		return 0, errors.New("metric not found")
+	}
+	return val, nil
+}
+
+func (m *mutexMemStorage) SetCounter(name string, value Counter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Counters[name] += value
+	return nil
+}
+
+func (m *mutexMemStorage) GetAll() (*models.ListMetrics, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var list models.ListMetrics
+	for name, val := range m.Counters {
+		v := int64(val)
+		list.List = append(list.List, models.Metrics{ID: name, MType: "counter", Delta: &v})
+	}
+	for name, val := range m.Gauges {
+		v := float64(val)
+		list.List = append(list.List, models.Metrics{ID: name, MType: "gauge", Value: &v})
+	}
+	return &list, nil
+}
+
+type benchStorage interface {
+	SetGauge(name string, value Gauge) error
+	GetGauge(name string) (Gauge, error)
+	SetCounter(name string, value Counter) error
+	GetAll() (*models.ListMetrics, error)
+}
+
+const benchMetricCount = 1024
+
+var benchMetricNames = func() []string {
+	names := make([]string, benchMetricCount)
+	for i := range names {
+		names[i] = fmt.Sprintf("metric_%d", i)
+	}
+	return names
+}()
+
+var benchStorages = []struct {
+	name    string
+	factory func() benchStorage
+}{
+	{name: "mutex", factory: func() benchStorage { return newMutexMemStorage() }},
+	{name: "sharded", factory: func() benchStorage { return NewMemStorage() }},
+}
+
+func fillBenchStorage(s benchStorage) {
+	for i, name := range benchMetricNames {
+		s.SetGauge(name, Gauge(i))
+		s.SetCounter(name+"_count", Counter(i))
+	}
+}
+
+func TestMemStorageConcurrentAccess(t *testing.T) {
+	storage := NewMemStorage()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, name := range benchMetricNames {
+				storage.SetCounter(name, 1)
+				storage.SetGauge(name, 1)
+				storage.GetGauge(name)
+			}
+			storage.GetAll()
+		}()
+	}
+	wg.Wait()
+
+	list, err := storage.GetAll()
+	if err != nil {
+		t.Fatalf("GetAll error: %v", err)
+	}
+	if len(list.List) != 2*benchMetricCount {
+		t.Errorf("expected %d metrics, got %d", 2*benchMetricCount, len(list.List))
+	}
+
+	val, err := storage.GetCounter(benchMetricNames[0])
+	if err != nil || val != 8 {
+		t.Errorf("expected counter 8, got %d (err %v)", val, err)
+	}
+}
+
+// BenchmarkMemStorageIngest измеряет параллельную запись, как при обработке /updates.
+func BenchmarkMemStorageIngest(b *testing.B) {
+	for _, bs := range benchStorages {
+		b.Run(bs.name, func(b *testing.B) {
+			s := bs.factory()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					metric := benchMetricNames[i%benchMetricCount]
+					s.SetGauge(metric, Gauge(i))
+					s.SetCounter(metric, 1)
+					i++
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkMemStorageRead измеряет параллельное чтение отдельных метрик.
+func BenchmarkMemStorageRead(b *testing.B) {
+	for _, bs := range benchStorages {
+		b.Run(bs.name, func(b *testing.B) {
+			s := bs.factory()
+			fillBenchStorage(s)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					s.GetGauge(benchMetricNames[i%benchMetricCount])
+					i++
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkMemStorageMixed моделирует нагрузку, при которой запись через /updates
+// идет одновременно с опросом дашбордов через GetAll.
+func BenchmarkMemStorageMixed(b *testing.B) {
+	for _, bs := range benchStorages {
+		b.Run(bs.name, func(b *testing.B) {
+			s := bs.factory()
+			fillBenchStorage(s)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					metric := benchMetricNames[i%benchMetricCount]
+					switch i % 100 {
+					case 0:
+						s.GetAll()
+					default:
+						if i%2 == 0 {
+							s.SetCounter(metric, 1)
+						} else {
+							s.GetGauge(metric)
+						}
+					}
+					i++
+				}
+			})
+		})
+	}
+}
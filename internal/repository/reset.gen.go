@@ -4,12 +4,14 @@ package repository
 
 func (s *DBStorage) Reset() {
 	s.db = nil
+	s.opts = DBOptions{}
+	s.stmtMu = nil
+	s.stmts = nil
+	s.chunkQuery = ""
 
 }
 
 func (s *MemStorage) Reset() {
-	s.mu = nil
-	s.Gauges = nil
-	s.Counters = nil
+	s.shards = nil
 
 }
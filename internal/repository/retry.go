@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+// DefaultRetryDelays задает задержки между повторными попытками по умолчанию.
+// Количество задержек определяет максимальное число повторов.
+var DefaultRetryDelays = []time.Duration{1 * time.Second, 3 * time.Second, 5 * time.Second}
+
+// RetryOptions задает параметры RetryStorage.
+type RetryOptions struct {
+	// Delays — задержки перед каждой повторной попыткой. Пустой список отключает повторы.
+	Delays []time.Duration
+
+	// IsRetryable определяет, можно ли повторить идемпотентную операцию после ошибки.
+	IsRetryable func(error) bool
+
+	// IsSafeToRetry определяет, что операция гарантированно не была применена.
+	// Только после таких ошибок повторяются увеличения счетчиков.
+	IsSafeToRetry func(error) bool
+}
+
+// RetryStats содержит счетчики RetryStorage.
+type RetryStats struct {
+	// Calls — количество вызовов операций хранилища.
+	Calls uint64 `json:"calls"`
+
+	// Attempts — общее количество попыток, включая первую.
+	Attempts uint64 `json:"attempts"`
+
+	// Retries — количество повторных попыток.
+	Retries uint64 `json:"retries"`
+
+	// Exhausted — количество операций, завершившихся ошибкой после всех повторов.
+	Exhausted uint64 `json:"exhausted"`
+}
+
+type retryCounters struct {
+	calls     atomic.Uint64
+	attempts  atomic.Uint64
+	retries   atomic.Uint64
+	exhausted atomic.Uint64
+}
+
+// RetryStorage — декоратор Storage, повторяющий записи после временных ошибок
+// базы данных (переключение реплики, разрыв соединения, deadlock).
+//
+// Установка gauge, абсолютного значения и сброс счетчика идемпотентны и повторяются
+// после любой ошибки IsRetryable. Увеличение счетчика и пакет со счетчиками
+// повторяются, только если IsSafeToRetry подтверждает, что операция не была
+// применена: после разрыва соединения во время фиксации счетчик мог быть уже
+// увеличен. Чтения не повторяются.
+//
+// Ожидание перед повтором прерывается отменой контекста, см. WithContext.
+type RetryStorage struct {
+	inner         Storage
+	delays        []time.Duration
+	isRetryable   func(error) bool
+	isSafeToRetry func(error) bool
+
+	ctx   context.Context
+	stats *retryCounters
+}
+
+// NewRetryStorage создает декоратор, повторяющий операции inner согласно opts.
+// Если opts.IsRetryable или opts.IsSafeToRetry не заданы, соответствующие
+// операции не повторяются.
+func NewRetryStorage(inner Storage, opts RetryOptions) *RetryStorage {
+	never := func(error) bool { return false }
+
+	isRetryable := opts.IsRetryable
+	if isRetryable == nil {
+		isRetryable = never
+	}
+	isSafeToRetry := opts.IsSafeToRetry
+	if isSafeToRetry == nil {
+		isSafeToRetry = never
+	}
+
+	return &RetryStorage{
+		inner:         inner,
+		delays:        opts.Delays,
+		isRetryable:   isRetryable,
+		isSafeToRetry: isSafeToRetry,
+		ctx:           context.Background(),
+		stats:         &retryCounters{},
+	}
+}
+
+// WithContext возвращает хранилище с общими счетчиками, ожидание повторов в
+// котором прерывается при отмене ctx, например по истечении времени запроса
+// или при остановке сервера. После отмены возвращается последняя ошибка операции.
+func (r *RetryStorage) WithContext(ctx context.Context) Storage {
+	bound := *r
+	bound.ctx = ctx
+	return &bound
+}
+
+// Stats возвращает текущие значения счетчиков попыток.
+func (r *RetryStorage) Stats() RetryStats {
+	return RetryStats{
+		Calls:     r.stats.calls.Load(),
+		Attempts:  r.stats.attempts.Load(),
+		Retries:   r.stats.retries.Load(),
+		Exhausted: r.stats.exhausted.Load(),
+	}
+}
+
+// Health возвращает состояние обернутого хранилища, дополненное счетчиками повторов.
+func (r *RetryStorage) Health() Health {
+	h := Health{Status: "ok"}
+	if hs, ok := r.inner.(interface{ Health() Health }); ok {
+		h = hs.Health()
+	}
+
+	stats := r.Stats()
+	h.Retry = &stats
+	return h
+}
+
+func (r *RetryStorage) do(op string, retryable func(error) bool, fn func() error) error {
+	r.stats.calls.Add(1)
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		r.stats.attempts.Add(1)
+
+		err = fn()
+		if err == nil || !retryable(err) {
+			return err
+		}
+
+		if attempt >= len(r.delays) {
+			r.stats.exhausted.Add(1)
+			log.Printf("Storage %s failed after %d attempts: %v", op, attempt+1, err)
+			return err
+		}
+
+		log.Printf("Storage %s failed, retrying in %v (attempt %d): %v", op, r.delays[attempt], attempt+1, err)
+		r.stats.retries.Add(1)
+
+		timer := time.NewTimer(r.delays[attempt])
+		select {
+		case <-timer.C:
+		case <-r.ctx.Done():
+			timer.Stop()
+			r.stats.exhausted.Add(1)
+			log.Printf("Storage %s retry canceled: %v", op, r.ctx.Err())
+			return err
+		}
+	}
+}
+
+func (r *RetryStorage) SetGauge(name string, value Gauge) error {
+	return r.do("SetGauge", r.isRetryable, func() error {
+		return r.inner.SetGauge(name, value)
+	})
+}
+
+func (r *RetryStorage) GetGauge(name string) (Gauge, error) {
+	return r.inner.GetGauge(name)
+}
+
+func (r *RetryStorage) SetCounter(name string, value Counter) error {
+	return r.do("SetCounter", r.isSafeToRetry, func() error {
+		return r.inner.SetCounter(name, value)
+	})
+}
+
+func (r *RetryStorage) GetCounter(name string) (Counter, error) {
+	return r.inner.GetCounter(name)
+}
+
+func (r *RetryStorage) ResetCounter(name string) error {
+	return r.do("ResetCounter", r.isRetryable, func() error {
+		return r.inner.ResetCounter(name)
+	})
+}
+
+func (r *RetryStorage) SetCounterValue(name string, value Counter) error {
+	return r.do("SetCounterValue", r.isRetryable, func() error {
+		return r.inner.SetCounterValue(name, value)
+	})
+}
+
+func (r *RetryStorage) GetAll() (*models.ListMetrics, error) {
+	return r.inner.GetAll()
+}
+
+// InsertMetricsBatch повторяет пакет из одних gauge после любой временной ошибки,
+// а пакет со счетчиками — только если он гарантированно не был записан.
+func (r *RetryStorage) InsertMetricsBatch(metrics models.ListMetrics) error {
+	retryable := r.isRetryable
+	for _, m := range metrics.List {
+		if m.MType == models.Counter {
+			retryable = r.isSafeToRetry
+			break
+		}
+	}
+
+	return r.do("InsertMetricsBatch", retryable, func() error {
+		return r.inner.InsertMetricsBatch(metrics)
+	})
+}
+
+// Ping не повторяется: проверка доступности должна отражать текущее состояние базы.
+func (r *RetryStorage) Ping(ctx context.Context) error {
+	return r.inner.Ping(ctx)
+}
+
+// WithContext возвращает хранилище, операции которого ограничены контекстом ctx,
+// если storage это поддерживает, иначе сам storage.
+func WithContext(storage Storage, ctx context.Context) Storage {
+	if cs, ok := storage.(interface {
+		WithContext(context.Context) Storage
+	}); ok {
+		return cs.WithContext(ctx)
+	}
+	return storage
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/levinOo/go-metrics-project/internal/config/db"
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+func newRetryTestStorage(t *testing.T) (*RetryStorage, sqlmock.Sqlmock) {
+	t.Helper()
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	storage := NewRetryStorage(NewDBStorage(conn), RetryOptions{
+		Delays:        []time.Duration{time.Millisecond, time.Millisecond},
+		IsRetryable:   db.IsTransientError,
+		IsSafeToRetry: db.IsSafeToRetry,
+	})
+
+	return storage, mock
+}
+
+func TestRetryStorageRetriesTransientErrors(t *testing.T) {
+	storage, mock := newRetryTestStorage(t)
+
+	val := 1.5
+	metrics := models.ListMetrics{List: []models.Metrics{{ID: "g", MType: "gauge", Value: &val}}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO metrics`).WillReturnError(&pgconn.PgError{Code: "40P01"})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO metrics`).WillReturnError(&pgconn.PgError{Code: "57P01"})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO metrics`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := storage.InsertMetricsBatch(metrics); err != nil {
+		t.Fatalf("InsertMetricsBatch error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	want := RetryStats{Calls: 1, Attempts: 3, Retries: 2}
+	if got := storage.Stats(); got != want {
+		t.Errorf("unexpected stats: got %+v, want %+v", got, want)
+	}
+}
+
+func TestRetryStorageGivesUp(t *testing.T) {
+	storage, mock := newRetryTestStorage(t)
+
+	prep := mock.ExpectPrepare(`INSERT INTO metrics \(name, value, type\)`)
+	for i := 0; i < 3; i++ {
+		prep.ExpectExec().WillReturnError(errors.New("dial tcp 10.0.0.1:5432: connect: connection refused"))
+	}
+
+	if err := storage.SetGauge("g", 1); err == nil {
+		t.Fatal("expected error after retries")
+	}
+
+	want := RetryStats{Calls: 1, Attempts: 3, Retries: 2, Exhausted: 1}
+	if got := storage.Stats(); got != want {
+		t.Errorf("unexpected stats: got %+v, want %+v", got, want)
+	}
+}
+
+func TestRetryStorageCounterRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		attempts uint64
+	}{
+		{"rolled back", &pgconn.PgError{Code: "40P01"}, 2},
+		{"connection refused", errors.New("dial tcp 10.0.0.1:5432: connect: connection refused"), 2},
+		{"connection lost after send", &pgconn.PgError{Code: "57P01"}, 1},
+		{"connection reset", errors.New("read tcp 10.0.0.2:41234->10.0.0.1:5432: read: connection reset by peer"), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, mock := newRetryTestStorage(t)
+
+			prep := mock.ExpectPrepare(`INSERT INTO metrics \(name, delta, type\)`)
+			prep.ExpectExec().WillReturnError(tt.err)
+			if tt.attempts > 1 {
+				prep.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			err := storage.SetCounter("c", 1)
+			if tt.attempts > 1 && err != nil {
+				t.Fatalf("SetCounter error: %v", err)
+			}
+			if tt.attempts == 1 && err == nil {
+				t.Fatal("increment that may have been applied must not be retried")
+			}
+
+			if got := storage.Stats().Attempts; got != tt.attempts {
+				t.Errorf("expected %d attempts, got %d", tt.attempts, got)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRetryStorageDoesNotRetryReads(t *testing.T) {
+	storage, mock := newRetryTestStorage(t)
+
+	mock.ExpectPrepare(`SELECT delta FROM metrics`).
+		ExpectQuery().
+		WillReturnError(errors.New("dial tcp 10.0.0.1:5432: connect: connection refused"))
+
+	if _, err := storage.GetCounter("c"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if got := storage.Stats(); got.Attempts != 0 {
+		t.Errorf("reads must bypass retries: %+v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRetryStorageWithContext(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer conn.Close()
+
+	storage := NewRetryStorage(NewDBStorage(conn), RetryOptions{
+		Delays:      []time.Duration{time.Hour},
+		IsRetryable: db.IsTransientError,
+	})
+
+	mock.ExpectPrepare(`INSERT INTO metrics \(name, value, type\)`).
+		ExpectExec().
+		WillReturnError(&pgconn.PgError{Code: "57P01"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = WithContext(storage, ctx).SetGauge("g", 1)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("canceled context did not abort backoff, took %v", elapsed)
+	}
+
+	want := RetryStats{Calls: 1, Attempts: 1, Retries: 1, Exhausted: 1}
+	if got := storage.Stats(); got != want {
+		t.Errorf("unexpected stats: got %+v, want %+v", got, want)
+	}
+}
+
+func TestRetryStorageDoesNotRetryPermanentErrors(t *testing.T) {
+	storage, mock := newRetryTestStorage(t)
+
+	mock.ExpectPrepare(`INSERT INTO metrics \(name, delta, type\)`).
+		ExpectExec().
+		WillReturnError(&pgconn.PgError{Code: "23505"})
+
+	if err := storage.SetCounter("c", 1); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	if got := storage.Stats(); got.Attempts != 1 || got.Retries != 0 {
+		t.Errorf("permanent error must not be retried: %+v", got)
+	}
+}
@@ -5,8 +5,11 @@ package service
 func (s *ServerComponents) Reset() {
 	s.server = nil
 	s.store = nil
+	s.buffer = nil
 	s.logger = nil
 	s.dbConn = nil
+	s.dbStorage = nil
+	s.listeners = nil
 
 }
 
@@ -14,6 +17,7 @@ func (s *PeriodicSaver) Reset() {
 	s.store = nil
 	s.interval = 0
 	s.filePath = ""
+	s.keep = 0
 	s.logger = nil
 	s.stopCh = nil
 	s.done = nil
@@ -8,7 +8,9 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
@@ -19,13 +21,13 @@ import (
 	"github.com/levinOo/go-metrics-project/internal/config"
 	"github.com/levinOo/go-metrics-project/internal/config/db"
 	"github.com/levinOo/go-metrics-project/internal/handler"
+	"github.com/levinOo/go-metrics-project/internal/ingest"
+	"github.com/levinOo/go-metrics-project/internal/listener"
 	"github.com/levinOo/go-metrics-project/internal/logger"
 	"github.com/levinOo/go-metrics-project/internal/models"
 	"github.com/levinOo/go-metrics-project/internal/repository"
+	"github.com/levinOo/go-metrics-project/internal/validation"
 	"go.uber.org/zap"
-
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
 // ServerComponents содержит все компоненты, необходимые для работы сервера метрик.
@@ -35,8 +37,15 @@ import (
 type ServerComponents struct {
 	server *http.Server
 	store  repository.Storage
+	buffer *repository.BufferedStorage
 	logger *zap.SugaredLogger
 	dbConn *sql.DB
+
+	// dbStorage равен nil, если сервер работает без базы данных.
+	dbStorage *repository.DBStorage
+
+	// listeners равен nil, если приемники Graphite и StatsD отключены.
+	listeners *listener.Listeners
 }
 
 // PeriodicSaver управляет автоматическим периодическим сохранением метрик на диск.
@@ -47,6 +56,7 @@ type PeriodicSaver struct {
 	store    repository.Storage
 	interval time.Duration
 	filePath string
+	keep     int
 	logger   *zap.SugaredLogger
 	stopCh   chan struct{}
 	done     chan struct{}
@@ -54,58 +64,149 @@ type PeriodicSaver struct {
 
 // Serve инициализирует и запускает сервер метрик с указанной конфигурацией.
 // Настраивает хранилище (в памяти или база данных), запускает периодическое сохранение,
+// при недоступной базе данных стартует в деградированном режиме с буфером записи,
 // включает профилирование pprof и обрабатывает корректное завершение работы по SIGINT/SIGTERM.
 //
 // Возвращает ошибку, если запуск или завершение сервера завершились неудачей.
 func Serve(cfg config.Config) error {
 	sugar := logger.NewLogger()
-	server := setupServer(cfg, sugar)
+	server, err := setupServer(cfg, sugar)
+	if err != nil {
+		return err
+	}
 	saver := setupPeriodicSaver(cfg, server.store, sugar)
 
 	return runServerWithGracefulShutdown(server, saver, cfg)
 }
 
-func setupServer(cfg config.Config, sugar *zap.SugaredLogger) *ServerComponents {
+func setupServer(cfg config.Config, sugar *zap.SugaredLogger) (*ServerComponents, error) {
 	sugar.Infow("Starting server with config", "address", cfg.Addr, "storeInterval", cfg.StoreInterval, "fileStorage", cfg.FileStorage, "restore", cfg.Restore, "addressDB", cfg.AddrDB, "hash key", cfg.Key)
 
+	validator, err := validation.New(cfg.MetricNamePattern, cfg.MetricNameMaxLength)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metric validation config: %w", err)
+	}
+	validation.SetDefault(validator)
+
+	influxTypes, err := ingest.ParseTypeMapping(cfg.InfluxFieldTypes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid line protocol field types: %w", err)
+	}
+	if _, err := ingest.ParsePrecision(cfg.InfluxPrecision); err != nil {
+		return nil, fmt.Errorf("invalid line protocol precision: %w", err)
+	}
+	graphiteTypes, err := ingest.ParseTypeMapping(cfg.GraphiteTypes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Graphite types: %w", err)
+	}
+
 	var storage repository.Storage
 	var dbConn *sql.DB
+	var dbStorage *repository.DBStorage
+	var buffer *repository.BufferedStorage
 
 	if cfg.AddrDB != "" {
-		dbConn, err := db.ConnectDB(cfg.AddrDB, sugar)
+		dbConn, err = db.ConnectDB(cfg.AddrDB, sugar)
 		if err != nil {
-			sugar.Errorw("Failed to connect to DB", "error", err)
-			return nil
+			return nil, fmt.Errorf("failed to connect to DB: %w", err)
 		}
 
+		db.ConfigurePool(dbConn, db.PoolConfig{
+			MaxOpenConns:    cfg.DBMaxOpenConns,
+			MaxIdleConns:    cfg.DBMaxIdleConns,
+			ConnMaxLifetime: time.Duration(cfg.DBConnMaxLifetime) * time.Second,
+		})
+
+		dbStorage = repository.NewDBStorageWithOptions(dbConn, repository.DBOptions{
+			ChunkSize:     cfg.DBBatchChunkSize,
+			CopyThreshold: cfg.DBCopyThreshold,
+		})
+
+		// Буфер записи оборачивает хранилище без повторов, чтобы при недоступной базе
+		// сразу переходить в деградированный режим. Повторы выполняются поверх кеша
+		// и прерываются отменой контекста запроса.
+		migrated := false
+		buffer = repository.NewBufferedStorage(dbStorage, repository.BufferOptions{
+			Capacity:      cfg.DBBufferCapacity,
+			CheckInterval: time.Duration(cfg.DBCheckInterval) * time.Second,
+			IsUnavailable: db.IsUnavailableError,
+			IsSafeToRetry: db.IsSafeToRetry,
+			OnRecover: func(ctx context.Context) error {
+				if migrated {
+					return nil
+				}
+				if err := db.RunMigrations(cfg.AddrDB); err != nil {
+					return err
+				}
+				migrated = true
+				sugar.Infow("Migrations applied after database recovery")
+				return nil
+			},
+		})
+
 		if err := db.RunMigrations(cfg.AddrDB); err != nil {
-			sugar.Fatalw("Failed to run migrations", "error", err)
+			if !db.IsTransientError(err) {
+				return nil, fmt.Errorf("failed to run migrations: %w", err)
+			}
+			sugar.Warnw("Database is unavailable, starting in degraded mode", "error", err)
+			buffer.MarkDegraded()
+		} else {
+			migrated = true
 		}
 
-		storage = repository.NewDBStorage(dbConn)
+		buffer.Start()
+		storage = buffer
+
+		if cfg.DBCacheSize > 0 {
+			storage = repository.NewCachedStorage(storage, repository.CacheOptions{
+				Size: cfg.DBCacheSize,
+				TTL:  time.Duration(cfg.DBCacheTTL) * time.Second,
+			})
+		}
+
+		storage = repository.NewRetryStorage(storage, repository.RetryOptions{
+			Delays:        repository.DefaultRetryDelays,
+			IsRetryable:   db.IsTransientError,
+			IsSafeToRetry: db.IsSafeToRetry,
+		})
 	} else {
 		storage = repository.NewMemStorage()
 	}
 
 	if cfg.Restore {
-		if err := loadFromFile(storage, cfg.FileStorage, sugar); err != nil {
+		if err := loadFromFile(storage, cfg.FileStorage, cfg.SnapshotKeep, sugar); err != nil {
 			sugar.Errorw("Failed to load metrics from file", "error", err)
 		}
 	}
 
-	router := handler.NewRouter(storage, sugar, cfg)
+	router := handler.NewRouter(storage, sugar, cfg, influxTypes)
 
 	srv := &http.Server{
 		Addr:    cfg.Addr,
 		Handler: router,
 	}
 
-	return &ServerComponents{
-		server: srv,
-		store:  storage,
-		logger: sugar,
-		dbConn: dbConn,
+	var listeners *listener.Listeners
+	if cfg.GraphiteAddr != "" || cfg.StatsDAddr != "" {
+		listeners = listener.New(storage, listener.Options{
+			GraphiteAddr:  cfg.GraphiteAddr,
+			StatsDAddr:    cfg.StatsDAddr,
+			FlushInterval: time.Duration(cfg.ListenerFlushInterval) * time.Second,
+			GraphiteTypes: graphiteTypes,
+			AuditFile:     cfg.AuditFile,
+			AuditURL:      cfg.AuditURL,
+		})
 	}
+
+	return &ServerComponents{
+		server:    srv,
+		store:     storage,
+		buffer:    buffer,
+		logger:    sugar,
+		dbConn:    dbConn,
+		dbStorage: dbStorage,
+		listeners: listeners,
+	}, nil
 }
 
 func setupPeriodicSaver(cfg config.Config, storage repository.Storage, sugar *zap.SugaredLogger) *PeriodicSaver {
@@ -115,19 +216,22 @@ func setupPeriodicSaver(cfg config.Config, storage repository.Storage, sugar *za
 	}
 
 	saver := NewPeriodicSaver(storage, cfg.FileStorage, time.Duration(cfg.StoreInterval)*time.Second, sugar)
+	saver.keep = cfg.SnapshotKeep
 	saver.Start()
 
 	return saver
 }
 
 // NewPeriodicSaver создает новый экземпляр PeriodicSaver, который будет сохранять метрики
-// в указанный файл с заданным интервалом. Сохранение необходимо запустить методом Start
+// в указанный файл с заданным интервалом. Снимки пишутся атомарно, по умолчанию хранятся
+// три последних. Сохранение необходимо запустить методом Start
 // и остановить методом Stop когда оно больше не требуется.
 func NewPeriodicSaver(store repository.Storage, filePath string, interval time.Duration, logger *zap.SugaredLogger) *PeriodicSaver {
 	return &PeriodicSaver{
 		store:    store,
 		interval: interval,
 		filePath: filePath,
+		keep:     defaultSnapshotKeep,
 		logger:   logger,
 		stopCh:   make(chan struct{}),
 		done:     make(chan struct{}),
@@ -148,7 +252,7 @@ func (ps *PeriodicSaver) Start() {
 			select {
 			case <-ticker.C:
 				ps.logger.Debugw("Periodic save triggered")
-				if err := saveToFile(ps.store, ps.filePath, ps.logger); err != nil {
+				if err := saveToFile(ps.store, ps.filePath, ps.keep, ps.logger); err != nil {
 					ps.logger.Errorw("Failed to save metrics", "error", err)
 				} else {
 					ps.logger.Debugw("Metrics saved successfully", "file", ps.filePath)
@@ -183,6 +287,21 @@ func runServerWithGracefulShutdown(components *ServerComponents, saver *Periodic
 		}
 	}()
 
+	if components.listeners != nil {
+		if err := components.listeners.Start(); err != nil {
+			if saver != nil {
+				saver.Stop()
+			}
+			return err
+		}
+		sugar.Infow("Metric listeners started", "graphite", cfg.GraphiteAddr, "statsd", cfg.StatsDAddr)
+	}
+
+	// Контекст запросов отменяется при остановке, прерывая ожидание повторов записи.
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	server.BaseContext = func(net.Listener) context.Context { return baseCtx }
+
 	serverErr := make(chan error, 1)
 
 	go func() {
@@ -203,16 +322,37 @@ func runServerWithGracefulShutdown(components *ServerComponents, saver *Periodic
 			if saver != nil {
 				saver.Stop()
 			}
+			if components.listeners != nil {
+				components.listeners.Stop()
+			}
 			return fmt.Errorf("server error: %w", err)
 		}
 	case <-quit:
 		sugar.Infoln("Shutting down server...")
 	}
 
-	return gracefulShutdown(cfg, sugar, storage, server, saver, components.dbConn)
+	cancelRequests()
+
+	return gracefulShutdown(cfg, sugar, storage, server, saver, components.buffer, components.dbStorage, components.dbConn, components.listeners)
 }
 
-func gracefulShutdown(cfg config.Config, sugar *zap.SugaredLogger, store repository.Storage, srv *http.Server, saver *PeriodicSaver, dbConn *sql.DB) error {
+func gracefulShutdown(cfg config.Config, sugar *zap.SugaredLogger, store repository.Storage, srv *http.Server, saver *PeriodicSaver, buffer *repository.BufferedStorage, dbStorage *repository.DBStorage, dbConn *sql.DB, listeners *listener.Listeners) error {
+	if dbConn != nil {
+		// Соединение закрывается последним и при любом исходе финального сохранения.
+		defer func() {
+			if dbStorage != nil {
+				if err := dbStorage.Close(); err != nil {
+					sugar.Errorw("Error closing prepared statements", "error", err)
+				}
+			}
+
+			sugar.Infow("Closing database connection")
+			if err := dbConn.Close(); err != nil {
+				sugar.Errorw("Error closing database connection", "error", err)
+			}
+		}()
+	}
+
 	if saver != nil {
 		saver.Stop()
 	}
@@ -224,23 +364,26 @@ func gracefulShutdown(cfg config.Config, sugar *zap.SugaredLogger, store reposit
 		sugar.Errorw("Server shutdown error", "error", err)
 	}
 
-	sugar.Infow("Performing final save on shutdown", "file", cfg.FileStorage)
-	if err := saveToFile(store, cfg.FileStorage, sugar); err != nil {
-		return fmt.Errorf("failed to save metrics on shutdown: %w", err)
+	if listeners != nil {
+		sugar.Infow("Stopping metric listeners")
+		listeners.Stop()
 	}
 
-	if dbConn != nil {
-		sugar.Infow("Closing database connection")
-		if err := dbConn.Close(); err != nil {
-			sugar.Errorw("Error closing database connection", "error", err)
-		}
+	if buffer != nil {
+		sugar.Infow("Flushing buffered writes", "health", buffer.Health())
+		buffer.Stop()
+	}
+
+	sugar.Infow("Performing final save on shutdown", "file", cfg.FileStorage)
+	if err := saveToFile(store, cfg.FileStorage, cfg.SnapshotKeep, sugar); err != nil {
+		return fmt.Errorf("failed to save metrics on shutdown: %w", err)
 	}
 
 	sugar.Infoln("Metrics saved and server stopped gracefully")
 	return nil
 }
 
-func saveToFile(store repository.Storage, fileName string, sugar *zap.SugaredLogger) error {
+func saveToFile(store repository.Storage, fileName string, keep int, sugar *zap.SugaredLogger) error {
 	if fileName == "" {
 		sugar.Debugw("Save skipped - no filename specified")
 		return nil
@@ -249,6 +392,12 @@ func saveToFile(store repository.Storage, fileName string, sugar *zap.SugaredLog
 	sugar.Debugw("Starting save to file", "file", fileName)
 
 	allMetrics, err := store.GetAll()
+	if errors.Is(err, repository.ErrUnavailable) {
+		// Хранилище деградировало: последний записанный снимок остаётся актуальнее
+		// пустого или неполного, поэтому файл не перезаписывается.
+		sugar.Warnw("Storage unavailable, snapshot not updated", "file", fileName, "error", err)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get all metrics: %w", err)
 	}
@@ -259,7 +408,7 @@ func saveToFile(store repository.Storage, fileName string, sugar *zap.SugaredLog
 		return fmt.Errorf("failed to serialize metrics: %w", err)
 	}
 
-	if err := writeFile(fileName, data); err != nil {
+	if err := writeSnapshot(fileName, data, keep); err != nil {
 		return fmt.Errorf("failed to write file %s: %w", fileName, err)
 	}
 
@@ -267,26 +416,21 @@ func saveToFile(store repository.Storage, fileName string, sugar *zap.SugaredLog
 	return nil
 }
 
-func loadFromFile(store repository.Storage, fileName string, sugar *zap.SugaredLogger) error {
+func loadFromFile(store repository.Storage, fileName string, keep int, sugar *zap.SugaredLogger) error {
 	if fileName == "" {
 		return nil
 	}
 
-	data, err := readFile(fileName, sugar)
+	metrics, source, err := readSnapshot(fileName, keep, sugar)
 	if err != nil {
 		return err
 	}
 
-	if len(data) == 0 {
-		sugar.Infow("Metrics file is empty, starting with empty storage", "file", fileName)
+	if metrics == nil {
+		sugar.Infow("Metrics file does not exist or is empty, starting with empty storage", "file", fileName)
 		return nil
 	}
 
-	metrics, err := deserializeMetrics(data, fileName)
-	if err != nil {
-		return err
-	}
-
 	count := 0
 	for _, m := range metrics.List {
 		switch m.MType {
@@ -305,34 +449,52 @@ func loadFromFile(store repository.Storage, fileName string, sugar *zap.SugaredL
 		}
 	}
 
-	sugar.Infow("Metrics loaded successfully", "file", fileName, "count", count)
+	sugar.Infow("Metrics loaded successfully", "file", source, "count", count)
 	return nil
 }
 
-func readFile(fileName string, sugar *zap.SugaredLogger) ([]byte, error) {
-	data, err := os.ReadFile(fileName)
-	if err != nil {
-		if os.IsNotExist(err) {
-			sugar.Infow("Metrics file does not exist, starting with empty storage", "file", fileName)
-			return nil, nil
+// readSnapshot перебирает снимки от самого нового к самому старому и возвращает первый
+// корректный. Если нет ни одного непустого снимка, возвращает nil без ошибки.
+func readSnapshot(fileName string, keep int, sugar *zap.SugaredLogger) (*models.ListMetrics, string, error) {
+	found := false
+
+	for _, path := range snapshotPaths(fileName, keep) {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			sugar.Warnw("Failed to read snapshot, trying older one", "file", path, "error", err)
+			found = true
+			continue
 		}
-		return nil, fmt.Errorf("failed to read metrics file %s: %w", fileName, err)
-	}
-	return data, nil
-}
 
-func writeFile(fileName string, data []byte) error {
-	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+		payload, err := decodeSnapshot(raw)
+		if errors.Is(err, errSnapshotEmpty) {
+			// Пустой файл не содержит снимка и не считается поврежденным.
+			sugar.Infow("Metrics file is empty, trying older one", "file", path)
+			continue
+		}
+		found = true
+		if err != nil {
+			sugar.Warnw("Snapshot is corrupted, trying older one", "file", path, "error", err)
+			continue
+		}
+
+		metrics, err := deserializeMetrics(payload, path)
+		if err != nil {
+			sugar.Warnw("Snapshot is corrupted, trying older one", "file", path, "error", err)
+			continue
+		}
+
+		return metrics, path, nil
 	}
-	defer file.Close()
 
-	if _, err := file.Write(data); err != nil {
-		return fmt.Errorf("failed to write  %w", err)
+	if found {
+		return nil, "", fmt.Errorf("no valid snapshot found for %s", fileName)
 	}
 
-	return nil
+	return nil, "", nil
 }
 
 func deserializeMetrics(data []byte, fileName string) (*models.ListMetrics, error) {
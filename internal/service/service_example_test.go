@@ -31,7 +31,7 @@ func Example_updateGaugeMetric() {
 	}
 
 	// Создаем тестовый сервер
-	router := handler.NewRouter(storage, sugar, cfg)
+	router := handler.NewRouter(storage, sugar, cfg, nil)
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
@@ -68,7 +68,7 @@ func Example_updateCounterMetric() {
 		Key:           "",
 	}
 
-	router := handler.NewRouter(storage, sugar, cfg)
+	router := handler.NewRouter(storage, sugar, cfg, nil)
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
@@ -106,7 +106,7 @@ func Example_getMetricValue() {
 	// Предварительно добавляем метрику
 	storage.SetGauge("Temperature", 23.5)
 
-	router := handler.NewRouter(storage, sugar, cfg)
+	router := handler.NewRouter(storage, sugar, cfg, nil)
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
@@ -142,7 +142,7 @@ func Example_batchUpdateMetrics() {
 		Key:           "",
 	}
 
-	router := handler.NewRouter(storage, sugar, cfg)
+	router := handler.NewRouter(storage, sugar, cfg, nil)
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
@@ -213,7 +213,7 @@ func Example_getAllMetrics() {
 	storage.SetGauge("Memory", 78.2)
 	storage.SetCounter("Requests", 100)
 
-	router := handler.NewRouter(storage, sugar, cfg)
+	router := handler.NewRouter(storage, sugar, cfg, nil)
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
@@ -240,7 +240,7 @@ func Example_healthCheck() {
 		Key:           "",
 	}
 
-	router := handler.NewRouter(storage, sugar, cfg)
+	router := handler.NewRouter(storage, sugar, cfg, nil)
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
@@ -254,3 +254,53 @@ func Example_healthCheck() {
 	fmt.Printf("Status: %d\n", resp.StatusCode)
 	// Output: Status: 200
 }
+
+// Example_adminCounterReset демонстрирует обнуление и установку значения счетчика
+// через административные эндпоинты.
+func Example_adminCounterReset() {
+	storage := repository.NewMemStorage()
+	sugar := logger.NewLogger()
+	cfg := config.Config{
+		Addr:       "localhost:8080",
+		AdminToken: "secret",
+	}
+
+	storage.SetCounter("RequestCount", 42)
+
+	router := handler.NewRouter(storage, sugar, cfg, nil)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	post := func(path, token string) int {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			log.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	fmt.Printf("Without token: %d\n", post("/admin/counters/RequestCount/reset", ""))
+	fmt.Printf("Reset: %d\n", post("/admin/counters/RequestCount/reset", "secret"))
+
+	val, _ := storage.GetCounter("RequestCount")
+	fmt.Printf("After reset: %d\n", val)
+
+	fmt.Printf("Set: %d\n", post("/admin/counters/RequestCount/set/7", "secret"))
+
+	val, _ = storage.GetCounter("RequestCount")
+	fmt.Printf("After set: %d\n", val)
+
+	fmt.Printf("Reset missing: %d\n", post("/admin/counters/Unknown/reset", "secret"))
+	// Output:
+	// Without token: 401
+	// Reset: 200
+	// After reset: 0
+	// Set: 200
+	// After set: 7
+	// Reset missing: 404
+}
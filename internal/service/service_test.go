@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/levinOo/go-metrics-project/internal/config"
+	"github.com/levinOo/go-metrics-project/internal/logger"
+	"github.com/levinOo/go-metrics-project/internal/repository"
+)
+
+// downStorage имитирует недоступную базу данных.
+type downStorage struct {
+	*repository.MemStorage
+}
+
+func (downStorage) Ping(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+func TestGracefulShutdownWhileDegraded(t *testing.T) {
+	sugar := logger.NewLogger()
+	fileName := filepath.Join(t.TempDir(), "storage.json")
+
+	saved := repository.NewMemStorage()
+	saved.SetGauge("Alloc", 42)
+	if err := saveToFile(saved, fileName, 3, sugar); err != nil {
+		t.Fatalf("saveToFile error: %v", err)
+	}
+	before, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buffer := repository.NewBufferedStorage(downStorage{repository.NewMemStorage()}, repository.BufferOptions{})
+	buffer.MarkDegraded()
+	buffer.Start()
+
+	dbConn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectClose()
+
+	cfg := config.Config{FileStorage: fileName, SnapshotKeep: 3}
+	if err := gracefulShutdown(cfg, sugar, buffer, &http.Server{}, nil, buffer, nil, dbConn, nil); err != nil {
+		t.Fatalf("gracefulShutdown error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("database connection was not closed: %v", err)
+	}
+
+	after, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Error("snapshot must not be overwritten while storage is unavailable")
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Формат снимка метрик на диске:
+//
+//	GMSNAP/1 sha256:<hex> <length>\n
+//	<payload>
+//
+// Заголовок содержит версию формата, контрольную сумму SHA256 и длину полезной
+// нагрузки. Полезная нагрузка — сериализованный models.ListMetrics.
+const (
+	snapshotMagic   = "GMSNAP"
+	snapshotVersion = 1
+
+	// defaultSnapshotKeep задает количество хранимых снимков по умолчанию.
+	defaultSnapshotKeep = 3
+)
+
+var (
+	errSnapshotHeader   = errors.New("invalid snapshot header")
+	errSnapshotVersion  = errors.New("unsupported snapshot version")
+	errSnapshotLength   = errors.New("snapshot length mismatch")
+	errSnapshotChecksum = errors.New("snapshot checksum mismatch")
+	errSnapshotEmpty    = errors.New("snapshot is empty")
+)
+
+// encodeSnapshot добавляет к данным заголовок с версией формата и контрольной суммой.
+func encodeSnapshot(payload []byte) []byte {
+	sum := sha256.Sum256(payload)
+	header := fmt.Sprintf("%s/%d sha256:%s %d\n", snapshotMagic, snapshotVersion, hex.EncodeToString(sum[:]), len(payload))
+
+	buf := make([]byte, 0, len(header)+len(payload))
+	buf = append(buf, header...)
+	return append(buf, payload...)
+}
+
+// decodeSnapshot проверяет заголовок и контрольную сумму снимка и возвращает полезную нагрузку.
+// Файлы старого формата (JSON без заголовка) принимаются без проверки контрольной суммы.
+func decodeSnapshot(raw []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, errSnapshotEmpty
+	}
+
+	if !bytes.HasPrefix(raw, []byte(snapshotMagic+"/")) {
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+			return raw, nil
+		}
+		return nil, errSnapshotHeader
+	}
+
+	idx := bytes.IndexByte(raw, '\n')
+	if idx < 0 {
+		return nil, errSnapshotHeader
+	}
+	header, payload := string(raw[:idx]), raw[idx+1:]
+
+	fields := strings.Fields(header)
+	if len(fields) != 3 || !strings.HasPrefix(fields[1], "sha256:") {
+		return nil, errSnapshotHeader
+	}
+
+	version, err := strconv.Atoi(strings.TrimPrefix(fields[0], snapshotMagic+"/"))
+	if err != nil {
+		return nil, errSnapshotHeader
+	}
+	if version != snapshotVersion {
+		return nil, fmt.Errorf("%w: %d", errSnapshotVersion, version)
+	}
+
+	length, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, errSnapshotHeader
+	}
+	if length != len(payload) {
+		return nil, fmt.Errorf("%w: header %d, actual %d", errSnapshotLength, length, len(payload))
+	}
+
+	sum := sha256.Sum256(payload)
+	if hex.EncodeToString(sum[:]) != strings.TrimPrefix(fields[1], "sha256:") {
+		return nil, errSnapshotChecksum
+	}
+
+	return payload, nil
+}
+
+// snapshotPaths возвращает пути снимков от самого нового к самому старому:
+// fileName, fileName.1, ..., fileName.(keep-1).
+func snapshotPaths(fileName string, keep int) []string {
+	if keep < 1 {
+		keep = 1
+	}
+
+	paths := make([]string, 0, keep)
+	paths = append(paths, fileName)
+	for i := 1; i < keep; i++ {
+		paths = append(paths, fileName+"."+strconv.Itoa(i))
+	}
+	return paths
+}
+
+// writeSnapshot атомарно записывает снимок: данные пишутся во временный файл в той же
+// директории, синхронизируются на диск и переименовываются поверх fileName.
+// Предыдущие снимки сдвигаются (fileName -> fileName.1 -> ...), хранится не более keep штук.
+func writeSnapshot(fileName string, payload []byte, keep int) error {
+	dir := filepath.Dir(fileName)
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(fileName)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+
+	if err := writeAndSync(tmp, encodeSnapshot(payload)); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := rotateSnapshots(fileName, keep); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to rotate snapshots: %w", err)
+	}
+
+	if err := os.Rename(tmpName, fileName); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	return syncDir(dir)
+}
+
+func writeAndSync(file *os.File, data []byte) error {
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close: %w", err)
+	}
+
+	return nil
+}
+
+// rotateSnapshots сдвигает существующие снимки на одну позицию, удаляя самый старый.
+func rotateSnapshots(fileName string, keep int) error {
+	paths := snapshotPaths(fileName, keep)
+
+	for i := len(paths) - 1; i > 0; i-- {
+		err := os.Rename(paths[i-1], paths[i])
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open dir: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync dir: %w", err)
+	}
+
+	return nil
+}
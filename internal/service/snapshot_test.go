@@ -0,0 +1,110 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/levinOo/go-metrics-project/internal/logger"
+	"github.com/levinOo/go-metrics-project/internal/repository"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	payload := []byte(`{"List":[{"id":"Alloc","type":"gauge","value":1.5}]}`)
+
+	decoded, err := decodeSnapshot(encodeSnapshot(payload))
+	if err != nil {
+		t.Fatalf("decodeSnapshot error: %v", err)
+	}
+	if string(decoded) != string(payload) {
+		t.Errorf("payload mismatch: got %s, want %s", decoded, payload)
+	}
+
+	corrupted := encodeSnapshot(payload)
+	corrupted[len(corrupted)-3] = 'X'
+	if _, err := decodeSnapshot(corrupted); err == nil {
+		t.Error("expected checksum error for corrupted snapshot")
+	}
+
+	if _, err := decodeSnapshot(payload); err != nil {
+		t.Errorf("legacy snapshot without header should be accepted: %v", err)
+	}
+}
+
+func TestSnapshotRotationAndFallback(t *testing.T) {
+	sugar := logger.NewLogger()
+	fileName := filepath.Join(t.TempDir(), "storage.json")
+
+	storage := repository.NewMemStorage()
+	for i := 1; i <= 4; i++ {
+		storage.SetGauge("Alloc", repository.Gauge(i))
+		if err := saveToFile(storage, fileName, 3, sugar); err != nil {
+			t.Fatalf("saveToFile error: %v", err)
+		}
+	}
+
+	if _, err := os.Stat(fileName + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 3 snapshots, found %s.3", fileName)
+	}
+
+	// Имитируем сбой во время записи: текущий снимок обрезан.
+	if err := os.WriteFile(fileName, []byte("GMSNAP/1 sha256:00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := repository.NewMemStorage()
+	if err := loadFromFile(restored, fileName, 3, sugar); err != nil {
+		t.Fatalf("loadFromFile error: %v", err)
+	}
+
+	val, err := restored.GetGauge("Alloc")
+	if err != nil {
+		t.Fatalf("GetGauge error: %v", err)
+	}
+	if val != 3 {
+		t.Errorf("expected fallback to previous snapshot with value 3, got %v", val)
+	}
+}
+
+func TestLoadFromFileMissing(t *testing.T) {
+	sugar := logger.NewLogger()
+	fileName := filepath.Join(t.TempDir(), "storage.json")
+
+	if err := loadFromFile(repository.NewMemStorage(), fileName, 3, sugar); err != nil {
+		t.Errorf("missing snapshot should not be an error: %v", err)
+	}
+}
+
+func TestLoadFromFileEmpty(t *testing.T) {
+	sugar := logger.NewLogger()
+	fileName := filepath.Join(t.TempDir(), "storage.json")
+
+	if err := os.WriteFile(fileName, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadFromFile(repository.NewMemStorage(), fileName, 3, sugar); err != nil {
+		t.Fatalf("empty snapshot should not be an error: %v", err)
+	}
+
+	// Пустой текущий снимок пропускается в пользу предыдущего.
+	fileName = filepath.Join(t.TempDir(), "storage.json")
+	saved := repository.NewMemStorage()
+	saved.SetGauge("Alloc", 7)
+	if err := saveToFile(saved, fileName, 3, sugar); err != nil {
+		t.Fatalf("saveToFile error: %v", err)
+	}
+	if err := saveToFile(saved, fileName, 3, sugar); err != nil {
+		t.Fatalf("saveToFile error: %v", err)
+	}
+	if err := os.WriteFile(fileName, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := repository.NewMemStorage()
+	if err := loadFromFile(restored, fileName, 3, sugar); err != nil {
+		t.Fatalf("loadFromFile error: %v", err)
+	}
+	if val, err := restored.GetGauge("Alloc"); err != nil || val != 7 {
+		t.Errorf("expected fallback to older snapshot with value 7, got %v, %v", val, err)
+	}
+}
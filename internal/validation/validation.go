@@ -0,0 +1,154 @@
+// Package validation проверяет метрики перед записью в хранилище: имя по настраиваемому
+// регулярному выражению и ограничению длины, тип, наличие и конечность значения,
+// переполнение счетчика.
+//
+// Все ошибки правил сопоставляются с ErrInvalid через errors.Is, поэтому вызывающая
+// сторона может отличить некорректную метрику от ошибки хранилища:
+//
+//	if errors.Is(err, validation.ErrInvalid) {
+//		// 400 Bad Request
+//	}
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+const (
+	// DefaultNamePattern — шаблон имени метрики по умолчанию.
+	DefaultNamePattern = `[A-Za-z_][A-Za-z0-9_.:-]*`
+
+	// DefaultMaxNameLength — максимальная длина имени метрики по умолчанию.
+	DefaultMaxNameLength = 255
+)
+
+// ErrInvalid — общая ошибка, с которой сопоставляются все ошибки правил.
+var ErrInvalid = errors.New("invalid metric")
+
+// ruleError — ошибка нарушения отдельного правила.
+type ruleError string
+
+func (e ruleError) Error() string { return string(e) }
+
+// Is сопоставляет ошибку правила с ErrInvalid.
+func (e ruleError) Is(target error) bool { return target == ErrInvalid }
+
+// Ошибки правил проверки.
+var (
+	ErrEmptyName       error = ruleError("metric name is empty")
+	ErrNameTooLong     error = ruleError("metric name is too long")
+	ErrInvalidName     error = ruleError("metric name does not match allowed pattern")
+	ErrUnknownType     error = ruleError("unknown metric type")
+	ErrMissingValue    error = ruleError("missing value for gauge")
+	ErrMissingDelta    error = ruleError("missing delta for counter")
+	ErrNonFinite       error = ruleError("gauge value must be finite")
+	ErrCounterOverflow error = ruleError("counter overflows int64")
+)
+
+// Validator проверяет метрики по настроенным правилам. Безопасен для конкурентного использования.
+type Validator struct {
+	namePattern   *regexp.Regexp
+	maxNameLength int
+}
+
+// New создает Validator. Шаблон pattern должен совпадать с именем целиком;
+// пустой шаблон и неположительная длина заменяются значениями по умолчанию.
+func New(pattern string, maxNameLength int) (*Validator, error) {
+	if pattern == "" {
+		pattern = DefaultNamePattern
+	}
+	if maxNameLength <= 0 {
+		maxNameLength = DefaultMaxNameLength
+	}
+
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("compile metric name pattern: %w", err)
+	}
+
+	return &Validator{namePattern: re, maxNameLength: maxNameLength}, nil
+}
+
+var defaultValidator atomic.Pointer[Validator]
+
+func init() {
+	v, _ := New(DefaultNamePattern, DefaultMaxNameLength)
+	defaultValidator.Store(v)
+}
+
+// Default возвращает Validator, используемый обработчиками и хранилищами.
+func Default() *Validator {
+	return defaultValidator.Load()
+}
+
+// SetDefault заменяет Validator по умолчанию. Вызывается при запуске сервера.
+func SetDefault(v *Validator) {
+	defaultValidator.Store(v)
+}
+
+// Name проверяет имя метрики.
+func (v *Validator) Name(name string) error {
+	switch {
+	case name == "":
+		return ErrEmptyName
+	case len(name) > v.maxNameLength:
+		return fmt.Errorf("%w: %d bytes, limit %d", ErrNameTooLong, len(name), v.maxNameLength)
+	case !v.namePattern.MatchString(name):
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// Type проверяет тип метрики.
+func (v *Validator) Type(mtype string) error {
+	if mtype != models.Gauge && mtype != models.Counter {
+		return fmt.Errorf("%w: %q", ErrUnknownType, mtype)
+	}
+	return nil
+}
+
+// Gauge проверяет значение gauge: NaN и бесконечности не допускаются.
+func (v *Validator) Gauge(value float64) error {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("%w: %v", ErrNonFinite, value)
+	}
+	return nil
+}
+
+// Metric проверяет метрику целиком: имя, тип и значение, соответствующее типу.
+func (v *Validator) Metric(m models.Metrics) error {
+	if err := v.Name(m.ID); err != nil {
+		return err
+	}
+	if err := v.Type(m.MType); err != nil {
+		return err
+	}
+
+	if m.MType == models.Gauge {
+		if m.Value == nil {
+			return ErrMissingValue
+		}
+		return v.Gauge(*m.Value)
+	}
+
+	if m.Delta == nil {
+		return ErrMissingDelta
+	}
+	return nil
+}
+
+// AddCounter складывает значение счетчика с приращением и возвращает
+// ErrCounterOverflow, если сумма выходит за пределы int64.
+func AddCounter(value, delta int64) (int64, error) {
+	sum := value + delta
+	if (delta > 0 && sum < value) || (delta < 0 && sum > value) {
+		return value, fmt.Errorf("%w: %d + %d", ErrCounterOverflow, value, delta)
+	}
+	return sum, nil
+}
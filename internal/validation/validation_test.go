@@ -0,0 +1,76 @@
+package validation
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/levinOo/go-metrics-project/internal/models"
+)
+
+func TestValidatorMetric(t *testing.T) {
+	v, err := New(DefaultNamePattern, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	finite := 1.5
+	nan := math.NaN()
+	inf := math.Inf(1)
+	delta := int64(1)
+
+	tests := []struct {
+		name   string
+		metric models.Metrics
+		want   error
+	}{
+		{"valid gauge", models.Metrics{ID: "Alloc", MType: models.Gauge, Value: &finite}, nil},
+		{"valid counter", models.Metrics{ID: "http.requests:total", MType: models.Counter, Delta: &delta}, nil},
+		{"empty name", models.Metrics{MType: models.Gauge, Value: &finite}, ErrEmptyName},
+		{"long name", models.Metrics{ID: strings.Repeat("a", 25), MType: models.Gauge, Value: &finite}, ErrNameTooLong},
+		{"bad name", models.Metrics{ID: "rm -rf /", MType: models.Gauge, Value: &finite}, ErrInvalidName},
+		{"partial match", models.Metrics{ID: "Alloc\n", MType: models.Gauge, Value: &finite}, ErrInvalidName},
+		{"unknown type", models.Metrics{ID: "Alloc", MType: "histogram"}, ErrUnknownType},
+		{"missing value", models.Metrics{ID: "Alloc", MType: models.Gauge}, ErrMissingValue},
+		{"missing delta", models.Metrics{ID: "Alloc", MType: models.Counter}, ErrMissingDelta},
+		{"nan", models.Metrics{ID: "Alloc", MType: models.Gauge, Value: &nan}, ErrNonFinite},
+		{"inf", models.Metrics{ID: "Alloc", MType: models.Gauge, Value: &inf}, ErrNonFinite},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Metric(tt.metric)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("error %v must match ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestAddCounter(t *testing.T) {
+	if sum, err := AddCounter(1, 2); err != nil || sum != 3 {
+		t.Errorf("AddCounter(1, 2) = %d, %v", sum, err)
+	}
+	if _, err := AddCounter(math.MaxInt64, 1); !errors.Is(err, ErrCounterOverflow) {
+		t.Errorf("expected overflow, got %v", err)
+	}
+	if _, err := AddCounter(math.MinInt64, -1); !errors.Is(err, ErrCounterOverflow) {
+		t.Errorf("expected overflow, got %v", err)
+	}
+}
+
+func TestNewInvalidPattern(t *testing.T) {
+	if _, err := New("[", 10); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
@@ -0,0 +1,10 @@
+// Package migrations содержит SQL-миграции схемы базы данных.
+// Файлы встраиваются в бинарный файл, поэтому сервер не зависит от рабочей директории.
+package migrations
+
+import "embed"
+
+// FS содержит файлы миграций в формате {version}_{name}.up.sql и {version}_{name}.down.sql.
+//
+//go:embed *.sql
+var FS embed.FS